@@ -0,0 +1,470 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"PawTribalWars/game"
+	"PawTribalWars/handlers"
+	"PawTribalWars/jwks"
+	"PawTribalWars/mailer"
+	"PawTribalWars/ratelimit"
+	"PawTribalWars/repo"
+	"PawTribalWars/repo/memory"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testServer to całe API na repo/memory - bez bazy
+type testServer struct {
+	*httptest.Server
+	db   *memory.DB
+	keys *jwks.KeySet
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	keys := jwks.NewHMAC([]byte("test-secret"))
+	handlers.UseKeys(keys)
+	handlers.UseRateLimitStore(ratelimit.NewMemoryStore())
+	handlers.UseMailer(mailer.NewLog(io.Discard, "PAW <noreply@localhost>"))
+
+	m := memory.New()
+	store := m.Store()
+	svc := game.NewService(store, game.Config{Speed: 1, BeginnerPointsThreshold: 1000})
+	srv := httptest.NewServer(newRouter(routerDeps{
+		Game:     handlers.NewGame(svc),
+		Accounts: handlers.NewAccounts(svc, store),
+		Admin:    handlers.NewAdmin(svc, store),
+		Guard:    handlers.NewGuard(store),
+		Metrics:  http.NotFoundHandler(),
+	}))
+	t.Cleanup(srv.Close)
+	return &testServer{Server: srv, db: m, keys: keys}
+}
+
+const testPassword = "Tajne-haslo1"
+
+// register zakłada konto przez /register (bez potwierdzonego maila)
+func (s *testServer) register(t *testing.T, name string) repo.User {
+	t.Helper()
+	status, body := s.do(t, "POST", "/api/v1/register", "", map[string]string{
+		"username": name, "email": name + "@example.com", "password": testPassword,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("register %s: %d %v", name, status, body)
+	}
+	user, err := s.db.Store().Users.ByUsername(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// player rejestruje gracza z potwierdzonym mailem
+func (s *testServer) player(t *testing.T, name string) repo.User {
+	t.Helper()
+	user := s.register(t, name)
+	s.db.VerifyEmail(user.ID)
+	return user
+}
+
+// login loguje gracza przez /login i zwraca token dostępu oraz id sesji
+func (s *testServer) login(t *testing.T, user repo.User) (token, sessionID string) {
+	t.Helper()
+	status, body := s.do(t, "POST", "/api/v1/login", "", map[string]string{"username": user.Username, "password": testPassword})
+	if status != http.StatusOK {
+		t.Fatalf("login %s: %d %v", user.Username, status, body)
+	}
+	refresh, _ := body["refresh_token"].(string)
+	sessionID, _, _ = strings.Cut(refresh, ".")
+	return body["token"].(string), sessionID
+}
+
+// sitterToken podpisuje token zastępcy sitterID na koncie owner; logowanie
+// zastępcy (/sitting/login) czyta jeszcze zastępstwa prosto z Postgresa
+func (s *testServer) sitterToken(t *testing.T, owner repo.User, sitterID int) string {
+	t.Helper()
+	sessionID := s.db.AddSession(owner.ID, time.Now().Add(time.Hour))
+	token, err := s.keys.Sign(&handlers.Claims{
+		UserID:    owner.ID,
+		Username:  owner.Username,
+		Role:      owner.Role,
+		SessionID: sessionID,
+		SitterID:  sitterID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        fmt.Sprintf("jti-%s", sessionID),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// do wysyła żądanie (body jako JSON) i zwraca status oraz zdekodowaną odpowiedź
+func (s *testServer) do(t *testing.T, method, path, token string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+	req, err := http.NewRequest(method, s.URL+path, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	out := map[string]interface{}{}
+	json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+// startowa wioska gracza z zapasem surowców na rozbudowy
+func (s *testServer) village(t *testing.T, user repo.User) int {
+	t.Helper()
+	ctx := context.Background()
+	villages, err := s.db.Store().Villages.ListByUser(ctx, user.ID)
+	if err != nil || len(villages) != 1 {
+		t.Fatalf("starting village of %s: %v %v", user.Username, villages, err)
+	}
+	villageID := villages[0].ID
+	res, _ := s.db.Store().Resources.Get(ctx, villageID)
+	res.Wood, res.Clay, res.Iron = 100000, 100000, 100000
+	s.db.Store().Resources.Save(ctx, res)
+	return villageID
+}
+
+func expectError(t *testing.T, status int, body map[string]interface{}, wantStatus int, wantCode string) {
+	t.Helper()
+	if status != wantStatus || body["code"] != wantCode {
+		t.Fatalf("got %d %v, want %d %s", status, body, wantStatus, wantCode)
+	}
+}
+
+func TestRegisteredPlayerUpgradesStartingVillage(t *testing.T) {
+	s := newTestServer(t)
+	user := s.player(t, "anna")
+	token, _ := s.login(t, user)
+	villageID := s.village(t, user)
+
+	status, body := s.do(t, "POST", fmt.Sprintf("/api/v1/villages/%d/buildings/lumbermill/upgrade", villageID), token, nil)
+	if status != http.StatusOK || body["new_level"] != float64(2) {
+		t.Fatalf("upgrade: %d %v", status, body)
+	}
+	status, body = s.do(t, "GET", fmt.Sprintf("/api/v1/villages/%d/overview", villageID), token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("overview: %d %v", status, body)
+	}
+}
+
+func TestRegisterRejectsTakenUsername(t *testing.T) {
+	s := newTestServer(t)
+	s.register(t, "anna")
+
+	status, body := s.do(t, "POST", "/api/v1/register", "", map[string]string{
+		"username": "anna", "email": "inna@example.com", "password": testPassword,
+	})
+	expectError(t, status, body, http.StatusConflict, "USER_EXISTS")
+}
+
+func TestLoginLocksAccountAfterFailures(t *testing.T) {
+	s := newTestServer(t)
+	user := s.player(t, "anna")
+	wrong := map[string]string{"username": "anna", "password": "Zle-haslo1"}
+
+	for i := 0; i < 5; i++ {
+		status, body := s.do(t, "POST", "/api/v1/login", "", wrong)
+		expectError(t, status, body, http.StatusUnauthorized, "INVALID_CREDENTIALS")
+	}
+	// zablokowane konto nie wpuszcza nawet z dobrym hasłem
+	status, body := s.do(t, "POST", "/api/v1/login", "", map[string]string{"username": "anna", "password": testPassword})
+	expectError(t, status, body, http.StatusTooManyRequests, "ACCOUNT_LOCKED")
+
+	// nieistniejąca nazwa blokuje się tak samo (nowe wiadra, żeby nie trafić w limit per IP)
+	handlers.UseRateLimitStore(ratelimit.NewMemoryStore())
+	for i := 0; i < 5; i++ {
+		s.do(t, "POST", "/api/v1/login", "", map[string]string{"username": "nikt", "password": testPassword})
+	}
+	status, body = s.do(t, "POST", "/api/v1/login", "", map[string]string{"username": "nikt", "password": testPassword})
+	expectError(t, status, body, http.StatusTooManyRequests, "ACCOUNT_LOCKED")
+
+	s.db.Store().Auth.ResetLoginFailures(context.Background(), user.ID)
+	s.login(t, user)
+}
+
+func TestAdminEditsVillageWithAuditLog(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.player(t, "admin")
+	if _, err := s.db.Store().Admin.SetRole(context.Background(), admin.ID, handlers.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	token, _ := s.login(t, admin)
+	player := s.player(t, "anna")
+	villageID := s.village(t, player)
+	playerToken, _ := s.login(t, player)
+
+	status, body := s.do(t, "GET", "/api/v1/admin/users?search=ann", token, nil)
+	if results, _ := body["results"].([]interface{}); status != http.StatusOK || body["total"] != float64(1) || len(results) != 1 {
+		t.Fatalf("search: %d %v", status, body)
+	}
+
+	path := fmt.Sprintf("/api/v1/admin/villages/%d", villageID)
+	status, body = s.do(t, "PUT", path+"/resources", token, map[string]int{"wood": 1, "clay": 2, "iron": 3})
+	if status != http.StatusOK {
+		t.Fatalf("set resources: %d %v", status, body)
+	}
+	status, body = s.do(t, "PUT", path+"/buildings/townhall", token, map[string]int{"level": 10})
+	if status != http.StatusOK {
+		t.Fatalf("set building: %d %v", status, body)
+	}
+	status, body = s.do(t, "GET", path, token, nil)
+	if status != http.StatusOK || body["owner"] != "anna" || body["resources"].(map[string]interface{})["clay"] != float64(2) ||
+		body["buildings"].(map[string]interface{})["townhall"] != float64(10) {
+		t.Fatalf("village: %d %v", status, body)
+	}
+	// punkty wioski i gracza przelicza game.Service, jak po rozbudowie w grze
+	if updated, _ := s.db.Store().Users.ByID(context.Background(), player.ID); updated.Points <= player.Points {
+		t.Fatalf("player points %d -> %d", player.Points, updated.Points)
+	}
+
+	status, body = s.do(t, "PUT", "/api/v1/admin/villages/999/units/spearman", token, map[string]int{"count": 5})
+	expectError(t, status, body, http.StatusNotFound, "VILLAGE_NOT_FOUND")
+
+	status, body = s.do(t, "GET", "/api/v1/admin/audit", token, nil)
+	entries, _ := body["results"].([]interface{})
+	if status != http.StatusOK || len(entries) != 4 {
+		t.Fatalf("audit log: %d %v", status, body)
+	}
+	// od najnowszego: podgląd wioski, budynek, surowce, wyszukiwanie
+	actions := []string{"village.inspect", "village.set_building", "village.set_resources", "user.search"}
+	for i, want := range actions {
+		entry := entries[i].(map[string]interface{})
+		if entry["action"] != want || entry["actor"] != "admin" {
+			t.Fatalf("audit entry %d: %v, want %s", i, entry, want)
+		}
+	}
+	if inspect := entries[0].(map[string]interface{}); inspect["target_id"] != fmt.Sprint(villageID) {
+		t.Fatalf("inspect entry: %v", inspect)
+	}
+	if search := entries[3].(map[string]interface{}); search["details"].(map[string]interface{})["search"] != "ann" {
+		t.Fatalf("search entry: %v", search)
+	}
+
+	// gracz bez roli nie ma dostępu do panelu
+	status, body = s.do(t, "GET", "/api/v1/admin/audit", playerToken, nil)
+	if status != http.StatusForbidden {
+		t.Fatalf("player in admin panel: %d %v", status, body)
+	}
+}
+
+func TestMissingTokenIsRejected(t *testing.T) {
+	s := newTestServer(t)
+	status, body := s.do(t, "GET", "/api/v1/villages", "", nil)
+	expectError(t, status, body, http.StatusUnauthorized, "AUTH_REQUIRED")
+}
+
+func TestRevokedSessionIsRejected(t *testing.T) {
+	s := newTestServer(t)
+	token, sessionID := s.login(t, s.player(t, "anna"))
+	s.db.RevokeSession(sessionID)
+
+	status, body := s.do(t, "GET", "/api/v1/villages", token, nil)
+	expectError(t, status, body, http.StatusUnauthorized, "TOKEN_REVOKED")
+}
+
+func TestBanAppliesToIssuedTokens(t *testing.T) {
+	s := newTestServer(t)
+	user := s.player(t, "anna")
+	token, _ := s.login(t, user)
+	s.db.SetBan(user.ID, &repo.Ban{Reason: "multikonto"})
+
+	status, body := s.do(t, "GET", "/api/v1/villages", token, nil)
+	expectError(t, status, body, http.StatusForbidden, "ACCOUNT_BANNED")
+
+	expired := time.Now().Add(-time.Minute)
+	s.db.SetBan(user.ID, &repo.Ban{Reason: "multikonto", ExpiresAt: &expired})
+	if status, body := s.do(t, "GET", "/api/v1/villages", token, nil); status != http.StatusOK {
+		t.Fatalf("after ban expired: %d %v", status, body)
+	}
+}
+
+func TestUnverifiedEmailCannotFoundVillage(t *testing.T) {
+	s := newTestServer(t)
+	token, _ := s.login(t, s.register(t, "anna"))
+
+	status, body := s.do(t, "POST", "/api/v1/villages", token, map[string]string{"name": "Osada"})
+	expectError(t, status, body, http.StatusForbidden, "EMAIL_NOT_VERIFIED")
+}
+
+func TestVacationBlocksCommandsButNotReads(t *testing.T) {
+	s := newTestServer(t)
+	user := s.player(t, "anna")
+	token, _ := s.login(t, user)
+	villageID := s.village(t, user)
+	s.db.SetVacation(user.ID, &repo.Vacation{StartsAt: time.Now().Add(-time.Hour), EndsAt: time.Now().Add(time.Hour)})
+
+	status, body := s.do(t, "POST", fmt.Sprintf("/api/v1/villages/%d/buildings/lumbermill/upgrade", villageID), token, nil)
+	expectError(t, status, body, http.StatusForbidden, "ON_VACATION")
+	if status, body := s.do(t, "GET", fmt.Sprintf("/api/v1/villages/%d/resources", villageID), token, nil); status != http.StatusOK {
+		t.Fatalf("resources on vacation: %d %v", status, body)
+	}
+}
+
+func TestOtherPlayersVillageIsForbidden(t *testing.T) {
+	s := newTestServer(t)
+	villageID := s.village(t, s.player(t, "anna"))
+	other, _ := s.login(t, s.player(t, "bartek"))
+
+	status, body := s.do(t, "POST", fmt.Sprintf("/api/v1/villages/%d/buildings/lumbermill/upgrade", villageID), other, nil)
+	expectError(t, status, body, http.StatusForbidden, "VILLAGE_NOT_FOUND")
+}
+
+func TestSitterActionsAreLoggedUntilSittingEnds(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.player(t, "anna")
+	sitter := s.player(t, "bartek")
+	villageID := s.village(t, owner)
+	s.db.SetSitting(owner.ID, sitter.ID, true)
+	token := s.sitterToken(t, owner, sitter.ID)
+
+	path := fmt.Sprintf("/api/v1/villages/%d/buildings/lumbermill/upgrade", villageID)
+	if status, body := s.do(t, "POST", path, token, nil); status != http.StatusOK {
+		t.Fatalf("sitter upgrade: %d %v", status, body)
+	}
+	actions := s.db.SitterActions()
+	if len(actions) != 1 || actions[0].Path != path || actions[0].SitterID != sitter.ID || actions[0].Status != http.StatusOK {
+		t.Fatalf("sitter log: %+v", actions)
+	}
+
+	s.db.SetSitting(owner.ID, sitter.ID, false)
+	status, body := s.do(t, "GET", "/api/v1/villages", token, nil)
+	expectError(t, status, body, http.StatusUnauthorized, "SITTING_ENDED")
+}
+
+func TestSmithyResearch(t *testing.T) {
+	s := newTestServer(t)
+	user := s.player(t, "anna")
+	token, _ := s.login(t, user)
+	villageID := s.village(t, user)
+
+	status, body := s.do(t, "POST", fmt.Sprintf("/api/v1/villages/%d/smithy/swordsman/research", villageID), token, nil)
+	expectError(t, status, body, http.StatusForbidden, "SMITHY_LEVEL_TOO_LOW")
+	if details, _ := body["details"].(map[string]interface{}); details["required"] != float64(2) {
+		t.Fatalf("requirement not reported: %v", body)
+	}
+
+	status, body = s.do(t, "POST", fmt.Sprintf("/api/v1/villages/%d/smithy/spearman/research", villageID), token, nil)
+	expectError(t, status, body, http.StatusForbidden, "SMITHY_LEVEL_TOO_LOW")
+	if status, body := s.do(t, "POST", fmt.Sprintf("/api/v1/villages/%d/buildings/smithy/upgrade", villageID), token, nil); status != http.StatusOK {
+		t.Fatalf("smithy upgrade: %d %v", status, body)
+	}
+	status, body = s.do(t, "POST", fmt.Sprintf("/api/v1/villages/%d/smithy/spearman/research", villageID), token, nil)
+	if status != http.StatusOK || body["level"] != float64(2) {
+		t.Fatalf("research: %d %v", status, body)
+	}
+	status, body = s.do(t, "POST", fmt.Sprintf("/api/v1/villages/%d/smithy/archer/research", villageID), token, nil)
+	expectError(t, status, body, http.StatusConflict, "RESEARCH_IN_PROGRESS")
+
+	status, body = s.do(t, "GET", fmt.Sprintf("/api/v1/villages/%d/smithy", villageID), token, nil)
+	if queue, _ := body["queue"].([]interface{}); status != http.StatusOK || len(queue) != 1 || body["smithy_level"] != float64(2) {
+		t.Fatalf("smithy: %d %v", status, body)
+	}
+}
+
+func TestJSONTypesAreNotCoerced(t *testing.T) {
+	s := newTestServer(t)
+	user := s.player(t, "anna")
+	token, _ := s.login(t, user)
+	villageID := s.village(t, user)
+	path := fmt.Sprintf("/api/v1/villages/%d/units/spearman/recruit", villageID)
+
+	status, body := s.do(t, "POST", path, token, map[string]interface{}{"count": "5"})
+	expectError(t, status, body, http.StatusBadRequest, "VALIDATION_FAILED")
+	if fields, _ := body["details"].(map[string]interface{})["fields"].(map[string]interface{}); fields["count"] == nil {
+		t.Fatalf("count not reported: %v", body)
+	}
+
+	status, body = s.do(t, "POST", path, token, map[string]interface{}{"count": 5, "village_id": 1})
+	expectError(t, status, body, http.StatusBadRequest, "VALIDATION_FAILED")
+
+	status, body = s.do(t, "POST", path, token, []int{5})
+	expectError(t, status, body, http.StatusBadRequest, "INVALID_BODY")
+}
+
+func TestFormBodyIsRejectedOnAPI(t *testing.T) {
+	s := newTestServer(t)
+	token, _ := s.login(t, s.player(t, "anna"))
+
+	req, _ := http.NewRequest("POST", s.URL+"/api/v1/villages", strings.NewReader("name=Osada"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body := map[string]interface{}{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	expectError(t, resp.StatusCode, body, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE")
+}
+
+func TestLegacyRouteReadsJSONAsForm(t *testing.T) {
+	s := newTestServer(t)
+	user := s.player(t, "anna")
+	token, _ := s.login(t, user)
+	villageID := s.village(t, user)
+
+	status, body := s.do(t, "PUT", fmt.Sprintf("/villages/%d", villageID), token, map[string]string{"name": "Gród"})
+	if status != http.StatusOK {
+		t.Fatalf("legacy rename village: %d %v", status, body)
+	}
+	if village, _ := s.db.Store().Villages.ByID(context.Background(), villageID); village.Name != "Gród" {
+		t.Fatalf("name not read from JSON: %q", village.Name)
+	}
+}
+
+func TestMetricsRequireToken(t *testing.T) {
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("scheduler_lag_seconds 0\n"))
+	})
+	cases := []struct {
+		token, header string
+		dev           bool
+		want          int
+	}{
+		{"metrics-token", "", false, http.StatusUnauthorized},
+		{"metrics-token", "Bearer wrong", false, http.StatusUnauthorized},
+		{"metrics-token", "Bearer metrics-token", false, http.StatusOK},
+		{"", "", true, http.StatusOK},
+		{"", "", false, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		if c.header != "" {
+			req.Header.Set("Authorization", c.header)
+		}
+		rec := httptest.NewRecorder()
+		handlers.MetricsAuth(c.token, c.dev, metrics).ServeHTTP(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("token %q, header %q, dev %v: got %d, want %d", c.token, c.header, c.dev, rec.Code, c.want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package battle
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// maksymalny poziom badania jednostki w kuźni
+const MaxResearchLevel = 3
+
+// bazowe statystyki jednostek
+type UnitStats struct {
+	Attack  int `json:"attack"`
+	Defense int `json:"defense"`
+}
+
+var Units = map[string]UnitStats{
+	"spearman":  {Attack: 10, Defense: 15},
+	"swordsman": {Attack: 25, Defense: 50},
+	"archer":    {Attack: 15, Defense: 50},
+}
+
+// statystyki po uwzględnieniu poziomu badania (+10% za każdy poziom powyżej 1)
+func ResearchedStats(unitType string, level int) UnitStats {
+	base := Units[unitType]
+	if level <= 1 {
+		return base
+	}
+	bonus := 1 + 0.1*float64(level-1)
+	return UnitStats{
+		Attack:  int(float64(base.Attack) * bonus),
+		Defense: int(float64(base.Defense) * bonus),
+	}
+}
+
+const (
+	maxWallLevel = 20
+	maxLuck      = 25.0 // szczęście w procentach, od -25 do +25
+	// MaxUnitCount ogranicza liczebność jednej jednostki po każdej stronie,
+	// żeby liczba * siła nie wyszła poza zakres
+	MaxUnitCount = 10_000_000
+)
+
+// Army: typ jednostki -> liczba
+type Army map[string]int
+
+type Input struct {
+	Attacker         Army           `json:"attacker"`
+	Defender         Army           `json:"defender"`
+	AttackerResearch map[string]int `json:"attacker_research"`
+	DefenderResearch map[string]int `json:"defender_research"`
+	WallLevel        int            `json:"wall_level"`
+	Morale           *float64       `json:"morale"` // w procentach (0-100], nil = 100
+	Luck             *float64       `json:"luck"`   // nil = losowane
+	Seed             *int64         `json:"seed"`   // stałe ziarno daje powtarzalne losowanie szczęścia
+}
+
+type Result struct {
+	AttackerWon       bool    `json:"attacker_won"`
+	Luck              float64 `json:"luck"`
+	Morale            float64 `json:"morale"`
+	AttackStrength    float64 `json:"attack_strength"`
+	DefenseStrength   float64 `json:"defense_strength"`
+	AttackerLosses    Army    `json:"attacker_losses"`
+	DefenderLosses    Army    `json:"defender_losses"`
+	AttackerSurvivors Army    `json:"attacker_survivors"`
+	DefenderSurvivors Army    `json:"defender_survivors"`
+}
+
+// Validate sprawdza dane wejściowe i zwraca wszystkie znalezione błędy
+func (in Input) Validate() []string {
+	var problems []string
+	checkArmy := func(side string, army Army) {
+		for _, uType := range sortedTypes(army) {
+			if _, ok := Units[uType]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: unknown unit type %q", side, uType))
+			}
+			if army[uType] < 0 {
+				problems = append(problems, fmt.Sprintf("%s: negative count of %s", side, uType))
+			}
+			if army[uType] > MaxUnitCount {
+				problems = append(problems, fmt.Sprintf("%s: count of %s must be at most %d", side, uType, MaxUnitCount))
+			}
+		}
+	}
+	checkResearch := func(side string, research map[string]int) {
+		for _, uType := range sortedTypes(research) {
+			level := research[uType]
+			if _, ok := Units[uType]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: unknown unit type %q", side, uType))
+			}
+			if level < 0 || level > MaxResearchLevel {
+				problems = append(problems, fmt.Sprintf("%s: research level of %s must be 0-%d", side, uType, MaxResearchLevel))
+			}
+		}
+	}
+	checkArmy("attacker", in.Attacker)
+	checkArmy("defender", in.Defender)
+	checkResearch("attacker_research", in.AttackerResearch)
+	checkResearch("defender_research", in.DefenderResearch)
+	if in.WallLevel < 0 || in.WallLevel > maxWallLevel {
+		problems = append(problems, fmt.Sprintf("wall_level must be 0-%d", maxWallLevel))
+	}
+	if in.Morale != nil && (*in.Morale <= 0 || *in.Morale > 100) {
+		problems = append(problems, "morale must be greater than 0 and at most 100")
+	}
+	if in.Luck != nil && math.Abs(*in.Luck) > maxLuck {
+		problems = append(problems, fmt.Sprintf("luck must be between -%g and %g", maxLuck, maxLuck))
+	}
+	return problems
+}
+
+// Simulate rozgrywa bitwę dla symulatora (API i cmd/simulate) - gra nie ma
+// jeszcze ataków. Przy podanym Luck albo Seed wynik jest w pełni deterministyczny.
+func Simulate(in Input) (Result, error) {
+	if problems := in.Validate(); len(problems) > 0 {
+		return Result{}, fmt.Errorf("invalid battle input: %v", problems)
+	}
+
+	morale := 100.0
+	if in.Morale != nil {
+		morale = *in.Morale
+	}
+
+	var luck float64
+	switch {
+	case in.Luck != nil:
+		luck = *in.Luck
+	case in.Seed != nil:
+		luck = rollLuck(rand.New(rand.NewSource(*in.Seed)))
+	default:
+		luck = rollLuck(rand.New(rand.NewSource(rand.Int63())))
+	}
+
+	attack := 0.0
+	for _, uType := range sortedTypes(in.Attacker) {
+		attack += float64(in.Attacker[uType]) * float64(ResearchedStats(uType, in.AttackerResearch[uType]).Attack)
+	}
+	attack *= morale / 100 * (1 + luck/100)
+
+	// mur daje stałą obronę i mnożnik za każdy poziom
+	defense := 20 + 50*float64(in.WallLevel)
+	for _, uType := range sortedTypes(in.Defender) {
+		defense += float64(in.Defender[uType]) * float64(ResearchedStats(uType, in.DefenderResearch[uType]).Defense)
+	}
+	defense *= math.Pow(1.037, float64(in.WallLevel))
+
+	res := Result{
+		AttackerWon:     attack > defense,
+		Luck:            luck,
+		Morale:          morale,
+		AttackStrength:  math.Round(attack*100) / 100,
+		DefenseStrength: math.Round(defense*100) / 100,
+	}
+
+	// zwycięzca traci (siła przegranego / siła zwycięzcy)^1.5 armii, przegrany wszystko
+	var attackerRatio, defenderRatio float64
+	if res.AttackerWon {
+		attackerRatio = math.Pow(defense/attack, 1.5)
+		defenderRatio = 1
+	} else {
+		attackerRatio = 1
+		if defense > 0 {
+			defenderRatio = math.Pow(attack/defense, 1.5)
+		}
+	}
+	res.AttackerLosses, res.AttackerSurvivors = applyLosses(in.Attacker, attackerRatio)
+	res.DefenderLosses, res.DefenderSurvivors = applyLosses(in.Defender, defenderRatio)
+	return res, nil
+}
+
+func rollLuck(rng *rand.Rand) float64 {
+	return math.Round((rng.Float64()*2*maxLuck-maxLuck)*10) / 10
+}
+
+func applyLosses(army Army, ratio float64) (losses, survivors Army) {
+	losses, survivors = Army{}, Army{}
+	for uType, count := range army {
+		lost := int(math.Round(float64(count) * ratio))
+		if lost > count {
+			lost = count
+		}
+		losses[uType] = lost
+		survivors[uType] = count - lost
+	}
+	return losses, survivors
+}
+
+func sortedTypes(counts map[string]int) []string {
+	types := make([]string, 0, len(counts))
+	for uType := range counts {
+		types = append(types, uType)
+	}
+	sort.Strings(types)
+	return types
+}
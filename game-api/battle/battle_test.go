@@ -0,0 +1,72 @@
+package battle
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSimulateIsDeterministicWithSeed(t *testing.T) {
+	seed := int64(42)
+	in := Input{
+		Attacker:         Army{"swordsman": 100, "archer": 50},
+		Defender:         Army{"spearman": 200},
+		AttackerResearch: map[string]int{"swordsman": 2},
+		WallLevel:        5,
+		Seed:             &seed,
+	}
+	first, err := Simulate(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		again, err := Simulate(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(first, again) {
+			t.Fatalf("run %d differs:\n%+v\n%+v", i, first, again)
+		}
+	}
+	if first.Morale != 100 {
+		t.Fatalf("morale = %g, want default 100", first.Morale)
+	}
+}
+
+func TestSimulateWithFixedLuck(t *testing.T) {
+	luck := 10.0
+	morale := 50.0
+	res, err := Simulate(Input{
+		Attacker: Army{"swordsman": 10},
+		Defender: Army{},
+		Morale:   &morale,
+		Luck:     &luck,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10 * 25 ataku * 50% morale * 110% szczęścia; obrona to sam mur poziomu 0
+	if res.AttackStrength != 137.5 || res.DefenseStrength != 20 || !res.AttackerWon {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
+
+func TestValidateRejectsOutOfRangeInput(t *testing.T) {
+	zero := 0.0
+	cases := map[string]Input{
+		"zero morale":    {Attacker: Army{"spearman": 1}, Morale: &zero},
+		"too many units": {Attacker: Army{"spearman": MaxUnitCount + 1}},
+		"overflowing":    {Defender: Army{"swordsman": math.MaxInt64 / 10}},
+		"negative count": {Attacker: Army{"archer": -1}},
+		"unknown unit":   {Attacker: Army{"axeman": 1}},
+		"wall too high":  {WallLevel: maxWallLevel + 1},
+	}
+	for name, in := range cases {
+		if problems := in.Validate(); len(problems) == 0 {
+			t.Errorf("%s: accepted", name)
+		}
+		if _, err := Simulate(in); err == nil {
+			t.Errorf("%s: simulated", name)
+		}
+	}
+}
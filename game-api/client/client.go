@@ -0,0 +1,85 @@
+// Package client to typowany klient API gry, generowany z openapi/openapi.json.
+// Ręcznie pisany jest tylko ten plik; metody i typy są w client_gen.go.
+//
+//	c := client.New("http://localhost:8080")
+//	login, err := c.Login(ctx, client.LoginParams{Username: "gracz", Password: "..."})
+//	c.Token = login.Token
+//	villages, err := c.GetVillages(ctx)
+package client
+
+//go:generate go run ../cmd/genclient -spec ../openapi/openapi.json -out client_gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type Client struct {
+	BaseURL    string
+	Token      string // access token wysyłany jako Bearer; pusty dla tras publicznych
+	HTTPClient *http.Client
+}
+
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+// APIError to odpowiedź błędu serwera ({"code", "message", "details"})
+type APIError struct {
+	StatusCode int
+	Body       Error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Body.Code, e.Body.Message)
+}
+
+// wysyła żądanie z ciałem JSON i dekoduje odpowiedź do out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr.Body); err != nil {
+			apiErr.Body.Message = http.StatusText(resp.StatusCode)
+		}
+		return apiErr
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,1104 @@
+// Code generated by genclient from openapi.json. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Army: typ jednostki -> liczba
+type Army map[string]int
+
+type BuildingCost struct {
+	BuildingType string `json:"building_type"`
+	Cost         Cost   `json:"cost"`
+	CurrentLevel int    `json:"current_level"`
+	NextLevel    int    `json:"next_level"`
+}
+
+type BuildingLevel struct {
+	Level int    `json:"level"`
+	Type  string `json:"type"`
+}
+
+type BuildingUpgrade struct {
+	BuildingType string `json:"building_type"`
+	Cost         Cost   `json:"cost"`
+	Message      string `json:"message,omitempty"`
+	NewLevel     int    `json:"new_level"`
+}
+
+type Buildings struct {
+	Buildings []BuildingLevel `json:"buildings"`
+	VillageID int             `json:"village_id"`
+}
+
+type Cost struct {
+	Clay int `json:"clay"`
+	Iron int `json:"iron"`
+	Wood int `json:"wood"`
+}
+
+type Error struct {
+	Code    string                 `json:"code"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	Message string                 `json:"message"`
+}
+
+// LoginResult: Tokeny albo - przy włączonym 2FA - mfa_token do POST /login/mfa
+type LoginResult struct {
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+	MFARequired  bool      `json:"mfa_required,omitempty"`
+	MFAToken     string    `json:"mfa_token,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Token        string    `json:"token,omitempty"`
+}
+
+type Me struct {
+	AvatarURL               string     `json:"avatar_url,omitempty"`
+	BeginnerProtectionUntil *time.Time `json:"beginner_protection_until,omitempty"`
+	CreatedAt               time.Time  `json:"created_at,omitempty"`
+	DeletionScheduledAt     *time.Time `json:"deletion_scheduled_at,omitempty"`
+	Email                   string     `json:"email"`
+	EmailVerified           bool       `json:"email_verified,omitempty"`
+	ID                      int        `json:"id"`
+	PendingEmail            string     `json:"pending_email,omitempty"`
+	Points                  int        `json:"points,omitempty"`
+	ProfileText             string     `json:"profile_text,omitempty"`
+	Role                    string     `json:"role"`
+	TwoFactorEnabled        bool       `json:"two_factor_enabled,omitempty"`
+	Username                string     `json:"username"`
+}
+
+type Message struct {
+	Message string `json:"message"`
+}
+
+// Object: Obiekt JSON bez ustalonego schematu
+type Object map[string]interface{}
+
+type OverviewBuilding struct {
+	Affordable bool   `json:"affordable"`
+	Level      int    `json:"level"`
+	NextCost   Cost   `json:"next_cost"`
+	NextLevel  int    `json:"next_level"`
+	Type       string `json:"type"`
+}
+
+type OverviewResources struct {
+	Capacity         int  `json:"capacity"`
+	Clay             int  `json:"clay"`
+	Iron             int  `json:"iron"`
+	PerHour          Cost `json:"per_hour"`
+	ProductionPaused bool `json:"production_paused"`
+	Wood             int  `json:"wood"`
+}
+
+type OverviewUnit struct {
+	Abroad int    `json:"abroad"`
+	Home   int    `json:"home"`
+	Total  int    `json:"total"`
+	Type   string `json:"type"`
+}
+
+type QueuedResearch struct {
+	FinishesAt time.Time `json:"finishes_at"`
+	Level      int       `json:"level"`
+	UnitType   string    `json:"unit_type"`
+}
+
+type Queues struct {
+	Research []QueuedResearch `json:"research"`
+}
+
+type Recruitment struct {
+	Cost    Cost   `json:"cost"`
+	Count   int    `json:"count"`
+	Message string `json:"message,omitempty"`
+	Type    string `json:"type"`
+}
+
+type Resources struct {
+	Clay      int `json:"clay"`
+	Iron      int `json:"iron"`
+	VillageID int `json:"village_id"`
+	Wood      int `json:"wood"`
+}
+
+type Session struct {
+	CreatedAt  time.Time `json:"created_at,omitempty"`
+	Current    bool      `json:"current"`
+	ExpiresAt  time.Time `json:"expires_at,omitempty"`
+	ID         string    `json:"id"`
+	IP         string    `json:"ip,omitempty"`
+	LastUsedAt time.Time `json:"last_used_at,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+}
+
+type SimulationInput struct {
+	Attacker         Army           `json:"attacker"`
+	AttackerResearch map[string]int `json:"attacker_research,omitempty"`
+	Defender         Army           `json:"defender"`
+	DefenderResearch map[string]int `json:"defender_research,omitempty"`
+	Luck             *float64       `json:"luck,omitempty"`
+	Morale           *float64       `json:"morale,omitempty"`
+	Seed             *int64         `json:"seed,omitempty"`
+	WallLevel        int            `json:"wall_level,omitempty"`
+}
+
+type SimulationResult struct {
+	AttackStrength    float64 `json:"attack_strength"`
+	AttackerLosses    Army    `json:"attacker_losses"`
+	AttackerSurvivors Army    `json:"attacker_survivors"`
+	AttackerWon       bool    `json:"attacker_won"`
+	DefenderLosses    Army    `json:"defender_losses"`
+	DefenderSurvivors Army    `json:"defender_survivors"`
+	DefenseStrength   float64 `json:"defense_strength"`
+	Luck              float64 `json:"luck"`
+	Morale            float64 `json:"morale"`
+}
+
+type Tokens struct {
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"refresh_token"`
+	Token        string    `json:"token"`
+}
+
+type UnitCount struct {
+	Count int    `json:"count"`
+	Type  string `json:"type"`
+}
+
+type Units struct {
+	Units     []UnitCount `json:"units"`
+	VillageID int         `json:"village_id"`
+}
+
+type Village struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Points    int       `json:"points"`
+}
+
+type VillageCreated struct {
+	Current    int    `json:"current,omitempty"`
+	MaxAllowed int    `json:"max_allowed,omitempty"`
+	Message    string `json:"message,omitempty"`
+	VillageID  int    `json:"village_id"`
+}
+
+type VillageOverview struct {
+	Buildings []OverviewBuilding `json:"buildings"`
+	Incomings []Object           `json:"incomings"`
+	Queues    Queues             `json:"queues"`
+	Resources OverviewResources  `json:"resources"`
+	Units     []OverviewUnit     `json:"units"`
+	Village   Village            `json:"village"`
+}
+
+// GetJWKS: Klucze publiczne do weryfikacji tokenów
+//
+//	GET /.well-known/jwks.json
+func (c *Client) GetJWKS(ctx context.Context) (Object, error) {
+	var out Object
+	err := c.do(ctx, "GET", "/.well-known/jwks.json", nil, nil, &out)
+	return out, err
+}
+
+// ConfirmTOTPParams: parametry ConfirmTOTP.
+type ConfirmTOTPParams struct {
+	Code string `json:"code"`
+}
+
+// ConfirmTOTP: Włączenie 2FA pierwszym kodem
+//
+//	POST /api/v1/2fa/confirm
+func (c *Client) ConfirmTOTP(ctx context.Context, params ConfirmTOTPParams) (Object, error) {
+	var out Object
+	err := c.do(ctx, "POST", "/api/v1/2fa/confirm", nil, params, &out)
+	return out, err
+}
+
+// DisableTOTPParams: parametry DisableTOTP.
+type DisableTOTPParams struct {
+	Code         string `json:"code,omitempty"`
+	Password     string `json:"password"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+// DisableTOTP: Wyłączenie 2FA
+//
+//	POST /api/v1/2fa/disable
+func (c *Client) DisableTOTP(ctx context.Context, params DisableTOTPParams) (Message, error) {
+	var out Message
+	err := c.do(ctx, "POST", "/api/v1/2fa/disable", nil, params, &out)
+	return out, err
+}
+
+// EnrollTOTP: Nowy sekret TOTP
+//
+//	POST /api/v1/2fa/enroll
+func (c *Client) EnrollTOTP(ctx context.Context) (Object, error) {
+	var out Object
+	err := c.do(ctx, "POST", "/api/v1/2fa/enroll", nil, nil, &out)
+	return out, err
+}
+
+// RegenerateRecoveryCodesParams: parametry RegenerateRecoveryCodes.
+type RegenerateRecoveryCodesParams struct {
+	Code string `json:"code"`
+}
+
+// RegenerateRecoveryCodes: Nowe kody odzyskiwania
+//
+//	POST /api/v1/2fa/recovery-codes
+func (c *Client) RegenerateRecoveryCodes(ctx context.Context, params RegenerateRecoveryCodesParams) (Object, error) {
+	var out Object
+	err := c.do(ctx, "POST", "/api/v1/2fa/recovery-codes", nil, params, &out)
+	return out, err
+}
+
+// AdminAuditLogParams: parametry AdminAuditLog.
+type AdminAuditLogParams struct {
+	Page    int `json:"-"`
+	PerPage int `json:"-"`
+}
+
+// AdminAuditLog: Dziennik akcji administracyjnych
+//
+//	GET /api/v1/admin/audit
+func (c *Client) AdminAuditLog(ctx context.Context, params AdminAuditLogParams) (Object, error) {
+	var out Object
+	q := url.Values{}
+	if params.Page != 0 {
+		q.Set("page", strconv.Itoa(params.Page))
+	}
+	if params.PerPage != 0 {
+		q.Set("per_page", strconv.Itoa(params.PerPage))
+	}
+	err := c.do(ctx, "GET", "/api/v1/admin/audit", q, nil, &out)
+	return out, err
+}
+
+// RotateKeys: Rotacja kluczy JWT
+//
+//	POST /api/v1/admin/keys/rotate
+func (c *Client) RotateKeys(ctx context.Context) (Object, error) {
+	var out Object
+	err := c.do(ctx, "POST", "/api/v1/admin/keys/rotate", nil, nil, &out)
+	return out, err
+}
+
+// ListReportsParams: parametry ListReports.
+type ListReportsParams struct {
+	Status string `json:"-"`
+	Page   int    `json:"-"`
+}
+
+// ListReports: Zgłoszenia
+//
+//	GET /api/v1/admin/reports
+func (c *Client) ListReports(ctx context.Context, params ListReportsParams) (Object, error) {
+	var out Object
+	q := url.Values{}
+	if params.Status != "" {
+		q.Set("status", params.Status)
+	}
+	if params.Page != 0 {
+		q.Set("page", strconv.Itoa(params.Page))
+	}
+	err := c.do(ctx, "GET", "/api/v1/admin/reports", q, nil, &out)
+	return out, err
+}
+
+// ResolveReportParams: parametry ResolveReport.
+type ResolveReportParams struct {
+	Action string `json:"action"`
+	Note   string `json:"note,omitempty"`
+}
+
+// ResolveReport: Rozpatrzenie zgłoszenia
+//
+//	POST /api/v1/admin/reports/{id}/resolve
+func (c *Client) ResolveReport(ctx context.Context, id int, params ResolveReportParams) (Message, error) {
+	var out Message
+	err := c.do(ctx, "POST", "/api/v1/admin/reports/"+strconv.Itoa(id)+"/resolve", nil, params, &out)
+	return out, err
+}
+
+// AdminSearchUsersParams: parametry AdminSearchUsers.
+type AdminSearchUsersParams struct {
+	Search  string `json:"-"`
+	Page    int    `json:"-"`
+	PerPage int    `json:"-"`
+}
+
+// AdminSearchUsers: Wyszukiwanie graczy
+//
+//	GET /api/v1/admin/users
+func (c *Client) AdminSearchUsers(ctx context.Context, params AdminSearchUsersParams) (Object, error) {
+	var out Object
+	q := url.Values{}
+	if params.Search != "" {
+		q.Set("search", params.Search)
+	}
+	if params.Page != 0 {
+		q.Set("page", strconv.Itoa(params.Page))
+	}
+	if params.PerPage != 0 {
+		q.Set("per_page", strconv.Itoa(params.PerPage))
+	}
+	err := c.do(ctx, "GET", "/api/v1/admin/users", q, nil, &out)
+	return out, err
+}
+
+// BanUserParams: parametry BanUser.
+type BanUserParams struct {
+	Duration string `json:"duration,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+// BanUser: Ban (bez duration na zawsze)
+//
+//	POST /api/v1/admin/users/{id}/ban
+func (c *Client) BanUser(ctx context.Context, id int, params BanUserParams) (Object, error) {
+	var out Object
+	err := c.do(ctx, "POST", "/api/v1/admin/users/"+strconv.Itoa(id)+"/ban", nil, params, &out)
+	return out, err
+}
+
+// BanHistory: Historia banów
+//
+//	GET /api/v1/admin/users/{id}/bans
+func (c *Client) BanHistory(ctx context.Context, id int) (Object, error) {
+	var out Object
+	err := c.do(ctx, "GET", "/api/v1/admin/users/"+strconv.Itoa(id)+"/bans", nil, nil, &out)
+	return out, err
+}
+
+// AdminChangeRoleParams: parametry AdminChangeRole.
+type AdminChangeRoleParams struct {
+	Role string `json:"role"`
+}
+
+// AdminChangeRole: Zmiana roli
+//
+//	PUT /api/v1/admin/users/{id}/role
+func (c *Client) AdminChangeRole(ctx context.Context, id int, params AdminChangeRoleParams) (Message, error) {
+	var out Message
+	err := c.do(ctx, "PUT", "/api/v1/admin/users/"+strconv.Itoa(id)+"/role", nil, params, &out)
+	return out, err
+}
+
+// UnbanUser: Zdjęcie bana
+//
+//	POST /api/v1/admin/users/{id}/unban
+func (c *Client) UnbanUser(ctx context.Context, id int) (Message, error) {
+	var out Message
+	err := c.do(ctx, "POST", "/api/v1/admin/users/"+strconv.Itoa(id)+"/unban", nil, nil, &out)
+	return out, err
+}
+
+// AdminGetVillage: Podgląd wioski
+//
+//	GET /api/v1/admin/villages/{id}
+func (c *Client) AdminGetVillage(ctx context.Context, id int) (Object, error) {
+	var out Object
+	err := c.do(ctx, "GET", "/api/v1/admin/villages/"+strconv.Itoa(id), nil, nil, &out)
+	return out, err
+}
+
+// AdminSetBuildingParams: parametry AdminSetBuilding.
+type AdminSetBuildingParams struct {
+	Level int `json:"level"`
+}
+
+// AdminSetBuilding: Ustawienie poziomu budynku
+//
+//	PUT /api/v1/admin/villages/{id}/buildings/{type}
+func (c *Client) AdminSetBuilding(ctx context.Context, id int, typeName string, params AdminSetBuildingParams) (Message, error) {
+	var out Message
+	err := c.do(ctx, "PUT", "/api/v1/admin/villages/"+strconv.Itoa(id)+"/buildings/"+url.PathEscape(typeName), nil, params, &out)
+	return out, err
+}
+
+// AdminSetResourcesParams: parametry AdminSetResources.
+type AdminSetResourcesParams struct {
+	Clay int `json:"clay"`
+	Iron int `json:"iron"`
+	Wood int `json:"wood"`
+}
+
+// AdminSetResources: Ustawienie surowców
+//
+//	PUT /api/v1/admin/villages/{id}/resources
+func (c *Client) AdminSetResources(ctx context.Context, id int, params AdminSetResourcesParams) (Message, error) {
+	var out Message
+	err := c.do(ctx, "PUT", "/api/v1/admin/villages/"+strconv.Itoa(id)+"/resources", nil, params, &out)
+	return out, err
+}
+
+// AdminSetUnitsParams: parametry AdminSetUnits.
+type AdminSetUnitsParams struct {
+	Count int `json:"count"`
+}
+
+// AdminSetUnits: Ustawienie liczby jednostek
+//
+//	PUT /api/v1/admin/villages/{id}/units/{type}
+func (c *Client) AdminSetUnits(ctx context.Context, id int, typeName string, params AdminSetUnitsParams) (Message, error) {
+	var out Message
+	err := c.do(ctx, "PUT", "/api/v1/admin/villages/"+strconv.Itoa(id)+"/units/"+url.PathEscape(typeName), nil, params, &out)
+	return out, err
+}
+
+// ResendVerification: Ponowna wysyłka maila weryfikacyjnego
+//
+//	POST /api/v1/email/resend
+func (c *Client) ResendVerification(ctx context.Context) (Message, error) {
+	var out Message
+	err := c.do(ctx, "POST", "/api/v1/email/resend", nil, nil, &out)
+	return out, err
+}
+
+// VerifyEmailParams: parametry VerifyEmail.
+type VerifyEmailParams struct {
+	Token string `json:"token"`
+}
+
+// VerifyEmail: Potwierdzenie adresu email
+//
+//	POST /api/v1/email/verify
+func (c *Client) VerifyEmail(ctx context.Context, params VerifyEmailParams) (Message, error) {
+	var out Message
+	err := c.do(ctx, "POST", "/api/v1/email/verify", nil, params, &out)
+	return out, err
+}
+
+// LoginParams: parametry Login.
+type LoginParams struct {
+	Password string `json:"password"`
+	Username string `json:"username"`
+}
+
+// Login: Logowanie
+//
+//	POST /api/v1/login
+func (c *Client) Login(ctx context.Context, params LoginParams) (LoginResult, error) {
+	var out LoginResult
+	err := c.do(ctx, "POST", "/api/v1/login", nil, params, &out)
+	return out, err
+}
+
+// LoginMFAParams: parametry LoginMFA.
+type LoginMFAParams struct {
+	Code         string `json:"code,omitempty"`
+	MFAToken     string `json:"mfa_token"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+// LoginMFA: Drugi krok logowania z kodem 2FA
+//
+//	POST /api/v1/login/mfa
+func (c *Client) LoginMFA(ctx context.Context, params LoginMFAParams) (Tokens, error) {
+	var out Tokens
+	err := c.do(ctx, "POST", "/api/v1/login/mfa", nil, params, &out)
+	return out, err
+}
+
+// Logout: Wylogowanie bieżącej sesji
+//
+//	POST /api/v1/logout
+func (c *Client) Logout(ctx context.Context) (Message, error) {
+	var out Message
+	err := c.do(ctx, "POST", "/api/v1/logout", nil, nil, &out)
+	return out, err
+}
+
+// LogoutAll: Wylogowanie wszystkich sesji
+//
+//	POST /api/v1/logout-all
+func (c *Client) LogoutAll(ctx context.Context) (Object, error) {
+	var out Object
+	err := c.do(ctx, "POST", "/api/v1/logout-all", nil, nil, &out)
+	return out, err
+}
+
+// GetMapParams: parametry GetMap.
+type GetMapParams struct {
+	Page    int `json:"-"`
+	PerPage int `json:"-"`
+}
+
+// GetMap: Mapa świata: wioski z właścicielem i jego ochroną (urlop, ochrona początkujących)
+//
+//	GET /api/v1/map
+func (c *Client) GetMap(ctx context.Context, params GetMapParams) (Object, error) {
+	var out Object
+	q := url.Values{}
+	if params.Page != 0 {
+		q.Set("page", strconv.Itoa(params.Page))
+	}
+	if params.PerPage != 0 {
+		q.Set("per_page", strconv.Itoa(params.PerPage))
+	}
+	err := c.do(ctx, "GET", "/api/v1/map", q, nil, &out)
+	return out, err
+}
+
+// GetMe: Moje konto
+//
+//	GET /api/v1/me
+func (c *Client) GetMe(ctx context.Context) (Me, error) {
+	var out Me
+	err := c.do(ctx, "GET", "/api/v1/me", nil, nil, &out)
+	return out, err
+}
+
+// UpdateMeParams: parametry UpdateMe.
+type UpdateMeParams struct {
+	AvatarURL   string `json:"avatar_url,omitempty"`
+	Email       string `json:"email,omitempty"`
+	ProfileText string `json:"profile_text,omitempty"`
+}
+
+// UpdateMe: Zmiana profilu; nowy email wymaga potwierdzenia
+//
+//	PUT /api/v1/me
+func (c *Client) UpdateMe(ctx context.Context, params UpdateMeParams) (Message, error) {
+	var out Message
+	err := c.do(ctx, "PUT", "/api/v1/me", nil, params, &out)
+	return out, err
+}
+
+// DeleteMeParams: parametry DeleteMe.
+type DeleteMeParams struct {
+	Code         string `json:"code,omitempty"`
+	Password     string `json:"password"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+// DeleteMe: Zaplanowanie usunięcia konta
+//
+//	DELETE /api/v1/me
+func (c *Client) DeleteMe(ctx context.Context, params DeleteMeParams) (Object, error) {
+	var out Object
+	err := c.do(ctx, "DELETE", "/api/v1/me", nil, params, &out)
+	return out, err
+}
+
+// ConfirmEmailChangeParams: parametry ConfirmEmailChange.
+type ConfirmEmailChangeParams struct {
+	Token string `json:"token"`
+}
+
+// ConfirmEmailChange: Potwierdzenie zmiany emaila
+//
+//	POST /api/v1/me/email/confirm
+func (c *Client) ConfirmEmailChange(ctx context.Context, params ConfirmEmailChangeParams) (Message, error) {
+	var out Message
+	err := c.do(ctx, "POST", "/api/v1/me/email/confirm", nil, params, &out)
+	return out, err
+}
+
+// ChangePasswordParams: parametry ChangePassword.
+type ChangePasswordParams struct {
+	NewPassword string `json:"new_password"`
+	OldPassword string `json:"old_password"`
+}
+
+// ChangePassword: Zmiana hasła
+//
+//	POST /api/v1/me/password
+func (c *Client) ChangePassword(ctx context.Context, params ChangePasswordParams) (Object, error) {
+	var out Object
+	err := c.do(ctx, "POST", "/api/v1/me/password", nil, params, &out)
+	return out, err
+}
+
+// RestoreMe: Anulowanie usunięcia konta
+//
+//	POST /api/v1/me/restore
+func (c *Client) RestoreMe(ctx context.Context) (Message, error) {
+	var out Message
+	err := c.do(ctx, "POST", "/api/v1/me/restore", nil, nil, &out)
+	return out, err
+}
+
+// ForgotPasswordParams: parametry ForgotPassword.
+type ForgotPasswordParams struct {
+	Email string `json:"email"`
+}
+
+// ForgotPassword: Mail z linkiem do resetu hasła
+//
+//	POST /api/v1/password/forgot
+func (c *Client) ForgotPassword(ctx context.Context, params ForgotPasswordParams) (Message, error) {
+	var out Message
+	err := c.do(ctx, "POST", "/api/v1/password/forgot", nil, params, &out)
+	return out, err
+}
+
+// ResetPasswordParams: parametry ResetPassword.
+type ResetPasswordParams struct {
+	Password string `json:"password"`
+	Token    string `json:"token"`
+}
+
+// ResetPassword: Ustawienie nowego hasła tokenem z maila
+//
+//	POST /api/v1/password/reset
+func (c *Client) ResetPassword(ctx context.Context, params ResetPasswordParams) (Message, error) {
+	var out Message
+	err := c.do(ctx, "POST", "/api/v1/password/reset", nil, params, &out)
+	return out, err
+}
+
+// GetPlayerProfile: Publiczny profil gracza
+//
+//	GET /api/v1/players/{id}
+func (c *Client) GetPlayerProfile(ctx context.Context, id int) (Object, error) {
+	var out Object
+	err := c.do(ctx, "GET", "/api/v1/players/"+strconv.Itoa(id), nil, nil, &out)
+	return out, err
+}
+
+// GetODARankingParams: parametry GetODARanking.
+type GetODARankingParams struct {
+	Page    int    `json:"-"`
+	PerPage int    `json:"-"`
+	Search  string `json:"-"`
+}
+
+// GetODARanking: Ranking graczy: pokonani w ataku
+//
+//	GET /api/v1/rankings/oda
+func (c *Client) GetODARanking(ctx context.Context, params GetODARankingParams) (Object, error) {
+	var out Object
+	q := url.Values{}
+	if params.Page != 0 {
+		q.Set("page", strconv.Itoa(params.Page))
+	}
+	if params.PerPage != 0 {
+		q.Set("per_page", strconv.Itoa(params.PerPage))
+	}
+	if params.Search != "" {
+		q.Set("search", params.Search)
+	}
+	err := c.do(ctx, "GET", "/api/v1/rankings/oda", q, nil, &out)
+	return out, err
+}
+
+// GetODDRankingParams: parametry GetODDRanking.
+type GetODDRankingParams struct {
+	Page    int    `json:"-"`
+	PerPage int    `json:"-"`
+	Search  string `json:"-"`
+}
+
+// GetODDRanking: Ranking graczy: pokonani w obronie
+//
+//	GET /api/v1/rankings/odd
+func (c *Client) GetODDRanking(ctx context.Context, params GetODDRankingParams) (Object, error) {
+	var out Object
+	q := url.Values{}
+	if params.Page != 0 {
+		q.Set("page", strconv.Itoa(params.Page))
+	}
+	if params.PerPage != 0 {
+		q.Set("per_page", strconv.Itoa(params.PerPage))
+	}
+	if params.Search != "" {
+		q.Set("search", params.Search)
+	}
+	err := c.do(ctx, "GET", "/api/v1/rankings/odd", q, nil, &out)
+	return out, err
+}
+
+// GetPlayerRankingParams: parametry GetPlayerRanking.
+type GetPlayerRankingParams struct {
+	Page    int    `json:"-"`
+	PerPage int    `json:"-"`
+	Search  string `json:"-"`
+}
+
+// GetPlayerRanking: Ranking graczy: punkty
+//
+//	GET /api/v1/rankings/players
+func (c *Client) GetPlayerRanking(ctx context.Context, params GetPlayerRankingParams) (Object, error) {
+	var out Object
+	q := url.Values{}
+	if params.Page != 0 {
+		q.Set("page", strconv.Itoa(params.Page))
+	}
+	if params.PerPage != 0 {
+		q.Set("per_page", strconv.Itoa(params.PerPage))
+	}
+	if params.Search != "" {
+		q.Set("search", params.Search)
+	}
+	err := c.do(ctx, "GET", "/api/v1/rankings/players", q, nil, &out)
+	return out, err
+}
+
+// GetTribeRankingParams: parametry GetTribeRanking.
+type GetTribeRankingParams struct {
+	Page    int    `json:"-"`
+	PerPage int    `json:"-"`
+	Search  string `json:"-"`
+}
+
+// GetTribeRanking: Ranking plemion: suma punktów członków
+//
+//	GET /api/v1/rankings/tribes
+func (c *Client) GetTribeRanking(ctx context.Context, params GetTribeRankingParams) (Object, error) {
+	var out Object
+	q := url.Values{}
+	if params.Page != 0 {
+		q.Set("page", strconv.Itoa(params.Page))
+	}
+	if params.PerPage != 0 {
+		q.Set("per_page", strconv.Itoa(params.PerPage))
+	}
+	if params.Search != "" {
+		q.Set("search", params.Search)
+	}
+	err := c.do(ctx, "GET", "/api/v1/rankings/tribes", q, nil, &out)
+	return out, err
+}
+
+// GetVillageRankingParams: parametry GetVillageRanking.
+type GetVillageRankingParams struct {
+	Page    int    `json:"-"`
+	PerPage int    `json:"-"`
+	Search  string `json:"-"`
+}
+
+// GetVillageRanking: Ranking wiosek: punkty
+//
+//	GET /api/v1/rankings/villages
+func (c *Client) GetVillageRanking(ctx context.Context, params GetVillageRankingParams) (Object, error) {
+	var out Object
+	q := url.Values{}
+	if params.Page != 0 {
+		q.Set("page", strconv.Itoa(params.Page))
+	}
+	if params.PerPage != 0 {
+		q.Set("per_page", strconv.Itoa(params.PerPage))
+	}
+	if params.Search != "" {
+		q.Set("search", params.Search)
+	}
+	err := c.do(ctx, "GET", "/api/v1/rankings/villages", q, nil, &out)
+	return out, err
+}
+
+// RefreshParams: parametry Refresh.
+type RefreshParams struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh: Nowa para tokenów z refresh tokenu
+//
+//	POST /api/v1/refresh
+func (c *Client) Refresh(ctx context.Context, params RefreshParams) (Tokens, error) {
+	var out Tokens
+	err := c.do(ctx, "POST", "/api/v1/refresh", nil, params, &out)
+	return out, err
+}
+
+// RegisterParams: parametry Register.
+type RegisterParams struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Username string `json:"username"`
+}
+
+// Register: Rejestracja z wioską startową
+//
+//	POST /api/v1/register
+func (c *Client) Register(ctx context.Context, params RegisterParams) (Message, error) {
+	var out Message
+	err := c.do(ctx, "POST", "/api/v1/register", nil, params, &out)
+	return out, err
+}
+
+// CreateReportParams: parametry CreateReport.
+type CreateReportParams struct {
+	Reason     string `json:"reason"`
+	TargetID   int    `json:"target_id"`
+	TargetType string `json:"target_type"`
+}
+
+// CreateReport: Zgłoszenie do moderacji
+//
+//	POST /api/v1/reports
+func (c *Client) CreateReport(ctx context.Context, params CreateReportParams) (Object, error) {
+	var out Object
+	err := c.do(ctx, "POST", "/api/v1/reports", nil, params, &out)
+	return out, err
+}
+
+// GetSessions: Aktywne sesje
+//
+//	GET /api/v1/sessions
+func (c *Client) GetSessions(ctx context.Context) ([]Session, error) {
+	var out []Session
+	err := c.do(ctx, "GET", "/api/v1/sessions", nil, nil, &out)
+	return out, err
+}
+
+// RevokeSession: Zamknięcie sesji
+//
+//	DELETE /api/v1/sessions/{id}
+func (c *Client) RevokeSession(ctx context.Context, id string) (Message, error) {
+	var out Message
+	err := c.do(ctx, "DELETE", "/api/v1/sessions/"+url.PathEscape(id), nil, nil, &out)
+	return out, err
+}
+
+// Simulate: Symulacja bitwy
+//
+//	POST /api/v1/simulator
+func (c *Client) Simulate(ctx context.Context, body SimulationInput) (SimulationResult, error) {
+	var out SimulationResult
+	err := c.do(ctx, "POST", "/api/v1/simulator", nil, body, &out)
+	return out, err
+}
+
+// GetSitters: Moi zastępcy i konta, które mogę zastępować
+//
+//	GET /api/v1/sitters
+func (c *Client) GetSitters(ctx context.Context) (Object, error) {
+	var out Object
+	err := c.do(ctx, "GET", "/api/v1/sitters", nil, nil, &out)
+	return out, err
+}
+
+// AddSitterParams: parametry AddSitter.
+type AddSitterParams struct {
+	Hours  int    `json:"hours"`
+	Sitter string `json:"sitter"`
+}
+
+// AddSitter: Dodanie zastępcy
+//
+//	POST /api/v1/sitters
+func (c *Client) AddSitter(ctx context.Context, params AddSitterParams) (Object, error) {
+	var out Object
+	err := c.do(ctx, "POST", "/api/v1/sitters", nil, params, &out)
+	return out, err
+}
+
+// SitterLogParams: parametry SitterLog.
+type SitterLogParams struct {
+	Page int `json:"-"`
+}
+
+// SitterLog: Co zastępcy robili na moim koncie
+//
+//	GET /api/v1/sitters/log
+func (c *Client) SitterLog(ctx context.Context, params SitterLogParams) (Object, error) {
+	var out Object
+	q := url.Values{}
+	if params.Page != 0 {
+		q.Set("page", strconv.Itoa(params.Page))
+	}
+	err := c.do(ctx, "GET", "/api/v1/sitters/log", q, nil, &out)
+	return out, err
+}
+
+// RemoveSitter: Koniec zastępstwa
+//
+//	DELETE /api/v1/sitters/{id}
+func (c *Client) RemoveSitter(ctx context.Context, id int) (Message, error) {
+	var out Message
+	err := c.do(ctx, "DELETE", "/api/v1/sitters/"+strconv.Itoa(id), nil, nil, &out)
+	return out, err
+}
+
+// SitterLogin: Token zastępcy do konta gracza
+//
+//	POST /api/v1/sitting/{id}/login
+func (c *Client) SitterLogin(ctx context.Context, id int) (Object, error) {
+	var out Object
+	err := c.do(ctx, "POST", "/api/v1/sitting/"+strconv.Itoa(id)+"/login", nil, nil, &out)
+	return out, err
+}
+
+// GetVacation: Stan urlopu
+//
+//	GET /api/v1/vacation
+func (c *Client) GetVacation(ctx context.Context) (Object, error) {
+	var out Object
+	err := c.do(ctx, "GET", "/api/v1/vacation", nil, nil, &out)
+	return out, err
+}
+
+// StartVacationParams: parametry StartVacation.
+type StartVacationParams struct {
+	Days int `json:"days"`
+}
+
+// StartVacation: Zaplanowanie urlopu
+//
+//	POST /api/v1/vacation
+func (c *Client) StartVacation(ctx context.Context, params StartVacationParams) (Object, error) {
+	var out Object
+	err := c.do(ctx, "POST", "/api/v1/vacation", nil, params, &out)
+	return out, err
+}
+
+// EndVacation: Anulowanie albo zakończenie urlopu
+//
+//	DELETE /api/v1/vacation
+func (c *Client) EndVacation(ctx context.Context) (Message, error) {
+	var out Message
+	err := c.do(ctx, "DELETE", "/api/v1/vacation", nil, nil, &out)
+	return out, err
+}
+
+// GetVillages: Moje wioski
+//
+//	GET /api/v1/villages
+func (c *Client) GetVillages(ctx context.Context) ([]Village, error) {
+	var out []Village
+	err := c.do(ctx, "GET", "/api/v1/villages", nil, nil, &out)
+	return out, err
+}
+
+// CreateVillageParams: parametry CreateVillage.
+type CreateVillageParams struct {
+	Name string `json:"name"`
+}
+
+// CreateVillage: Założenie wioski
+//
+//	POST /api/v1/villages
+func (c *Client) CreateVillage(ctx context.Context, params CreateVillageParams) (VillageCreated, error) {
+	var out VillageCreated
+	err := c.do(ctx, "POST", "/api/v1/villages", nil, params, &out)
+	return out, err
+}
+
+// UpdateVillageParams: parametry UpdateVillage.
+type UpdateVillageParams struct {
+	Name string `json:"name"`
+}
+
+// UpdateVillage: Zmiana nazwy wioski
+//
+//	PUT /api/v1/villages/{id}
+func (c *Client) UpdateVillage(ctx context.Context, id int, params UpdateVillageParams) (Message, error) {
+	var out Message
+	err := c.do(ctx, "PUT", "/api/v1/villages/"+strconv.Itoa(id), nil, params, &out)
+	return out, err
+}
+
+// DeleteVillage: Porzucenie wioski
+//
+//	DELETE /api/v1/villages/{id}
+func (c *Client) DeleteVillage(ctx context.Context, id int) (Message, error) {
+	var out Message
+	err := c.do(ctx, "DELETE", "/api/v1/villages/"+strconv.Itoa(id), nil, nil, &out)
+	return out, err
+}
+
+// GetBuildings: Budynki wioski
+//
+//	GET /api/v1/villages/{id}/buildings
+func (c *Client) GetBuildings(ctx context.Context, id int) (Buildings, error) {
+	var out Buildings
+	err := c.do(ctx, "GET", "/api/v1/villages/"+strconv.Itoa(id)+"/buildings", nil, nil, &out)
+	return out, err
+}
+
+// GetBuildingCost: Koszt następnego poziomu
+//
+//	GET /api/v1/villages/{id}/buildings/{type}/cost
+func (c *Client) GetBuildingCost(ctx context.Context, id int, typeName string) (BuildingCost, error) {
+	var out BuildingCost
+	err := c.do(ctx, "GET", "/api/v1/villages/"+strconv.Itoa(id)+"/buildings/"+url.PathEscape(typeName)+"/cost", nil, nil, &out)
+	return out, err
+}
+
+// UpgradeBuilding: Rozbudowa budynku
+//
+//	POST /api/v1/villages/{id}/buildings/{type}/upgrade
+func (c *Client) UpgradeBuilding(ctx context.Context, id int, typeName string) (BuildingUpgrade, error) {
+	var out BuildingUpgrade
+	err := c.do(ctx, "POST", "/api/v1/villages/"+strconv.Itoa(id)+"/buildings/"+url.PathEscape(typeName)+"/upgrade", nil, nil, &out)
+	return out, err
+}
+
+// GetVillageOverview: Podgląd wioski: surowce z produkcją, budynki, jednostki i kolejki w jednej odpowiedzi
+//
+//	GET /api/v1/villages/{id}/overview
+func (c *Client) GetVillageOverview(ctx context.Context, id int) (VillageOverview, error) {
+	var out VillageOverview
+	err := c.do(ctx, "GET", "/api/v1/villages/"+strconv.Itoa(id)+"/overview", nil, nil, &out)
+	return out, err
+}
+
+// GetResources: Surowce wioski
+//
+//	GET /api/v1/villages/{id}/resources
+func (c *Client) GetResources(ctx context.Context, id int) (Resources, error) {
+	var out Resources
+	err := c.do(ctx, "GET", "/api/v1/villages/"+strconv.Itoa(id)+"/resources", nil, nil, &out)
+	return out, err
+}
+
+// GetSmithy: Badania w kuźni
+//
+//	GET /api/v1/villages/{id}/smithy
+func (c *Client) GetSmithy(ctx context.Context, id int) (Object, error) {
+	var out Object
+	err := c.do(ctx, "GET", "/api/v1/villages/"+strconv.Itoa(id)+"/smithy", nil, nil, &out)
+	return out, err
+}
+
+// StartResearch: Rozpoczęcie badania
+//
+//	POST /api/v1/villages/{id}/smithy/{type}/research
+func (c *Client) StartResearch(ctx context.Context, id int, typeName string) (Object, error) {
+	var out Object
+	err := c.do(ctx, "POST", "/api/v1/villages/"+strconv.Itoa(id)+"/smithy/"+url.PathEscape(typeName)+"/research", nil, nil, &out)
+	return out, err
+}
+
+// GetUnits: Jednostki w wiosce
+//
+//	GET /api/v1/villages/{id}/units
+func (c *Client) GetUnits(ctx context.Context, id int) (Units, error) {
+	var out Units
+	err := c.do(ctx, "GET", "/api/v1/villages/"+strconv.Itoa(id)+"/units", nil, nil, &out)
+	return out, err
+}
+
+// RecruitUnitsParams: parametry RecruitUnits.
+type RecruitUnitsParams struct {
+	Count int `json:"count"`
+}
+
+// RecruitUnits: Rekrutacja jednostek
+//
+//	POST /api/v1/villages/{id}/units/{type}/recruit
+func (c *Client) RecruitUnits(ctx context.Context, id int, typeName string, params RecruitUnitsParams) (Recruitment, error) {
+	var out Recruitment
+	err := c.do(ctx, "POST", "/api/v1/villages/"+strconv.Itoa(id)+"/units/"+url.PathEscape(typeName)+"/recruit", nil, params, &out)
+	return out, err
+}
+
+// GetOpenAPI: Ta specyfikacja
+//
+//	GET /openapi.json
+func (c *Client) GetOpenAPI(ctx context.Context) (Object, error) {
+	var out Object
+	err := c.do(ctx, "GET", "/openapi.json", nil, nil, &out)
+	return out, err
+}
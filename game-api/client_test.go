@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"PawTribalWars/client"
+	"PawTribalWars/repo"
+)
+
+// klient wygenerowany z openapi.json rozmawia z prawdziwym routerem, więc
+// rozjazd specyfikacji z odpowiedziami wychodzi w tych testach
+
+func newTestClient(t *testing.T, s *testServer, name string) (*client.Client, repo.User) {
+	t.Helper()
+	user := s.player(t, name)
+	c := client.New(s.URL)
+	c.Token, _ = s.login(t, user)
+	return c, user
+}
+
+func expectAPIError(t *testing.T, err error, wantStatus int, wantCode string) {
+	t.Helper()
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != wantStatus || apiErr.Body.Code != wantCode {
+		t.Fatalf("got %v, want %d %s", err, wantStatus, wantCode)
+	}
+}
+
+func TestClientVillageLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	c, _ := newTestClient(t, s, "anna")
+	ctx := context.Background()
+
+	// rejestracja zakłada wioskę startową, druga wymaga wyższego ratusza
+	starting, err := c.GetVillages(ctx)
+	if err != nil || len(starting) != 1 {
+		t.Fatalf("starting villages: %+v %v", starting, err)
+	}
+	villageID := starting[0].ID
+	_, err = c.CreateVillage(ctx, client.CreateVillageParams{Name: "Osada"})
+	expectAPIError(t, err, http.StatusForbidden, "VILLAGE_LIMIT_REACHED")
+
+	if _, err := c.UpdateVillage(ctx, villageID, client.UpdateVillageParams{Name: "Gród"}); err != nil {
+		t.Fatal(err)
+	}
+	villages, err := c.GetVillages(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(villages) != 1 || villages[0].ID != villageID || villages[0].Name != "Gród" {
+		t.Fatalf("villages: %+v", villages)
+	}
+
+	if _, err := c.DeleteVillage(ctx, villageID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.UpgradeBuilding(ctx, villageID, "lumbermill")
+	expectAPIError(t, err, http.StatusForbidden, "VILLAGE_NOT_FOUND")
+}
+
+func TestClientUpgradePaysQuotedCost(t *testing.T) {
+	s := newTestServer(t)
+	c, user := newTestClient(t, s, "anna")
+	ctx := context.Background()
+	villageID := s.village(t, user)
+
+	before, err := c.GetResources(ctx, villageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	quote, err := c.GetBuildingCost(ctx, villageID, "lumbermill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upgrade, err := c.UpgradeBuilding(ctx, villageID, "lumbermill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upgrade.NewLevel != quote.NextLevel || upgrade.Cost != quote.Cost {
+		t.Fatalf("upgrade %+v, quoted %+v", upgrade, quote)
+	}
+
+	after, err := c.GetResources(ctx, villageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// w trakcie testu może dojść co najwyżej minuta produkcji
+	if after.Wood > before.Wood-quote.Cost.Wood+100 || after.Wood < before.Wood-quote.Cost.Wood {
+		t.Fatalf("wood %d -> %d after paying %d", before.Wood, after.Wood, quote.Cost.Wood)
+	}
+
+	buildings, err := c.GetBuildings(ctx, villageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range buildings.Buildings {
+		if b.Type == "lumbermill" && b.Level != upgrade.NewLevel {
+			t.Fatalf("lumbermill level %d, want %d", b.Level, upgrade.NewLevel)
+		}
+	}
+}
+
+func TestClientRecruitment(t *testing.T) {
+	s := newTestServer(t)
+	c, user := newTestClient(t, s, "anna")
+	ctx := context.Background()
+	villageID := s.village(t, user)
+
+	recruited, err := c.RecruitUnits(ctx, villageID, "spearman", client.RecruitUnitsParams{Count: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recruited.Count != 5 || recruited.Type != "spearman" {
+		t.Fatalf("recruitment: %+v", recruited)
+	}
+	overview, err := c.GetVillageOverview(ctx, villageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, u := range overview.Units {
+		if u.Type == "spearman" {
+			found = u.Home >= 5
+		}
+	}
+	if !found {
+		t.Fatalf("overview units: %+v", overview.Units)
+	}
+
+	_, err = c.RecruitUnits(ctx, villageID, "spearman", client.RecruitUnitsParams{Count: 0})
+	expectAPIError(t, err, http.StatusBadRequest, "VALIDATION_FAILED")
+}
+
+func TestClientSimulator(t *testing.T) {
+	s := newTestServer(t)
+	c, _ := newTestClient(t, s, "anna")
+	seed := int64(7)
+	input := client.SimulationInput{
+		Attacker: client.Army{"swordsman": 100},
+		Defender: client.Army{"spearman": 20},
+		Seed:     &seed,
+	}
+
+	first, err := c.Simulate(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.Simulate(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Luck != second.Luck || first.AttackerWon != second.AttackerWon {
+		t.Fatalf("same seed, different battles: %+v vs %+v", first, second)
+	}
+}
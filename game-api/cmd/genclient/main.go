@@ -0,0 +1,422 @@
+// Command genclient generuje typowanego klienta Go z openapi/openapi.json.
+//
+//	go run ./cmd/genclient -spec openapi/openapi.json -out client/client_gen.go
+//
+// Zwykle uruchamiany przez go generate ./client. Obsługuje tylko tę część
+// OpenAPI, której używa nasza specyfikacja: $ref do components/schemas,
+// obiekty, tablice, mapy (additionalProperties) i typy proste. Pomijane są
+// operacje przestarzałe (stare trasy bez /api/v1) i bez odpowiedzi JSON
+// (WebSocket, SSE, metryki, Swagger UI).
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"sort"
+	"strings"
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Nullable             bool               `json:"nullable"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *Schema            `json:"items"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type Operation struct {
+	OperationID string      `json:"operationId"`
+	Summary     string      `json:"summary"`
+	Deprecated  bool        `json:"deprecated"`
+	Parameters  []Parameter `json:"parameters"`
+	RequestBody *struct {
+		Required bool                 `json:"required"`
+		Content  map[string]MediaType `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]struct {
+		Content map[string]MediaType `json:"content"`
+	} `json:"responses"`
+}
+
+type Spec struct {
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas"`
+	} `json:"components"`
+}
+
+var methodOrder = []string{"get", "post", "put", "patch", "delete"}
+
+func main() {
+	specPath := flag.String("spec", "openapi/openapi.json", "OpenAPI document")
+	out := flag.String("out", "client/client_gen.go", "output Go file")
+	pkg := flag.String("package", "client", "package name of the generated file")
+	flag.Parse()
+
+	raw, err := os.ReadFile(*specPath)
+	if err != nil {
+		fail(err)
+	}
+	var spec Spec
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		fail(fmt.Errorf("%s: %w", *specPath, err))
+	}
+
+	g := &generator{spec: &spec, imports: map[string]bool{"context": true}}
+	code, err := g.generate(*pkg)
+	if err != nil {
+		fail(err)
+	}
+	if err := os.WriteFile(*out, code, 0o644); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "genclient:", err)
+	os.Exit(1)
+}
+
+type generator struct {
+	spec    *Spec
+	imports map[string]bool
+	buf     bytes.Buffer
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) generate(pkg string) ([]byte, error) {
+	// typy najpierw, importy znamy dopiero po wygenerowaniu całości
+	g.types()
+	if err := g.operations(); err != nil {
+		return nil, err
+	}
+
+	var head bytes.Buffer
+	fmt.Fprintf(&head, "// Code generated by genclient from openapi.json. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	imports := make([]string, 0, len(g.imports))
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	for _, imp := range imports {
+		fmt.Fprintf(&head, "\t%q\n", imp)
+	}
+	head.WriteString(")\n")
+	head.Write(g.buf.Bytes())
+
+	code, err := format.Source(head.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not compile: %w", err)
+	}
+	return code, nil
+}
+
+// ===== Typy z components/schemas =====
+
+func (g *generator) types() {
+	names := make([]string, 0, len(g.spec.Components.Schemas))
+	for name := range g.spec.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		s := g.spec.Components.Schemas[name]
+		g.printf("\n")
+		if s.Description != "" {
+			g.printf("// %s: %s\n", name, s.Description)
+		}
+		if len(s.Properties) == 0 {
+			g.printf("type %s %s\n", name, g.goType(s))
+			continue
+		}
+		g.printf("type %s struct {\n", name)
+		g.fields(s)
+		g.printf("}\n")
+	}
+}
+
+// pola struktury z tagami json; niewymagane z omitempty
+func (g *generator) fields(s *Schema) {
+	required := map[string]bool{}
+	for _, r := range s.Required {
+		required[r] = true
+	}
+	for _, prop := range sortedKeys(s.Properties) {
+		tag := prop
+		if !required[prop] {
+			tag += ",omitempty"
+		}
+		g.printf("\t%s %s `json:%q`\n", goName(prop), g.goType(s.Properties[prop]), tag)
+	}
+}
+
+func (g *generator) goType(s *Schema) string {
+	if s.Ref != "" {
+		return strings.TrimPrefix(s.Ref, "#/components/schemas/")
+	}
+	var t string
+	switch s.Type {
+	case "integer":
+		t = "int"
+		if s.Format == "int64" {
+			t = "int64"
+		}
+	case "number":
+		t = "float64"
+	case "boolean":
+		t = "bool"
+	case "string":
+		t = "string"
+		if s.Format == "date-time" {
+			g.imports["time"] = true
+			t = "time.Time"
+		}
+	case "array":
+		return "[]" + g.goType(s.Items)
+	default:
+		var elem Schema
+		if len(s.AdditionalProperties) > 0 && json.Unmarshal(s.AdditionalProperties, &elem) == nil && (elem.Type != "" || elem.Ref != "") {
+			return "map[string]" + g.goType(&elem)
+		}
+		return "map[string]interface{}"
+	}
+	if s.Nullable {
+		return "*" + t
+	}
+	return t
+}
+
+// ===== Operacje =====
+
+func (g *generator) operations() error {
+	for _, path := range sortedKeys(g.spec.Paths) {
+		for _, method := range methodOrder {
+			op := g.spec.Paths[path][method]
+			if op == nil {
+				continue
+			}
+			if op.OperationID == "" {
+				return fmt.Errorf("%s %s: missing operationId", strings.ToUpper(method), path)
+			}
+			if err := g.operation(strings.ToUpper(method), path, op); err != nil {
+				return fmt.Errorf("%s: %w", op.OperationID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (g *generator) operation(method, path string, op *Operation) error {
+	result := g.result(op)
+	if op.Deprecated || result == "" {
+		return nil
+	}
+	name := goName(op.OperationID)
+
+	// parametry ścieżki jako argumenty, query i pola ciała w strukturze XParams
+	var pathParams, queryParams []Parameter
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "path":
+			pathParams = append(pathParams, p)
+		case "query":
+			queryParams = append(queryParams, p)
+		}
+	}
+
+	var bodySchema *Schema
+	if op.RequestBody != nil {
+		media, ok := op.RequestBody.Content["application/json"]
+		if !ok {
+			return fmt.Errorf("request body without application/json")
+		}
+		bodySchema = media.Schema
+	}
+
+	args := []string{"ctx context.Context"}
+	for _, p := range pathParams {
+		args = append(args, lowerFirst(goName(p.Name))+" "+g.goType(p.Schema))
+	}
+	paramsType := ""
+	if len(queryParams) > 0 || (bodySchema != nil && bodySchema.Ref == "") {
+		paramsType = name + "Params"
+		args = append(args, "params "+paramsType)
+	}
+	if bodySchema != nil && bodySchema.Ref != "" {
+		args = append(args, "body "+g.goType(bodySchema))
+	}
+
+	if paramsType != "" {
+		g.printf("\n// %s: parametry %s.\ntype %s struct {\n", paramsType, name, paramsType)
+		for _, p := range queryParams {
+			g.printf("\t%s %s `json:\"-\"`\n", goName(p.Name), g.goType(p.Schema))
+		}
+		if bodySchema != nil && bodySchema.Ref == "" {
+			g.fields(bodySchema)
+		}
+		g.printf("}\n")
+	}
+
+	g.printf("\n// %s: %s\n//\n//\t%s %s\n", name, op.Summary, method, path)
+	g.printf("func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), result)
+	g.printf("\tvar out %s\n", result)
+
+	query := "nil"
+	if len(queryParams) > 0 {
+		g.imports["net/url"] = true
+		query = "q"
+		g.printf("\tq := url.Values{}\n")
+		for _, p := range queryParams {
+			g.queryParam(p)
+		}
+	}
+
+	body := "nil"
+	switch {
+	case bodySchema == nil:
+	case bodySchema.Ref != "":
+		body = "body"
+	default:
+		body = "params"
+	}
+
+	g.printf("\terr := c.do(ctx, %q, %s, %s, %s, &out)\n", method, g.pathExpr(path, pathParams), query, body)
+	g.printf("\treturn out, err\n}\n")
+	return nil
+}
+
+// typ odpowiedzi z pierwszego kodu 2xx; "" gdy nie jest JSON-em
+func (g *generator) result(op *Operation) string {
+	codes := sortedKeys(op.Responses)
+	for _, code := range codes {
+		if !strings.HasPrefix(code, "2") {
+			continue
+		}
+		media, ok := op.Responses[code].Content["application/json"]
+		if !ok || media.Schema == nil {
+			return ""
+		}
+		return g.goType(media.Schema)
+	}
+	return ""
+}
+
+func (g *generator) queryParam(p Parameter) {
+	field := "params." + goName(p.Name)
+	var value, zero string
+	switch g.goType(p.Schema) {
+	case "int":
+		g.imports["strconv"] = true
+		value, zero = "strconv.Itoa("+field+")", "0"
+	case "bool":
+		g.imports["strconv"] = true
+		value, zero = "strconv.FormatBool("+field+")", "false"
+	default:
+		value, zero = field, `""`
+	}
+	if p.Required {
+		g.printf("\tq.Set(%q, %s)\n", p.Name, value)
+		return
+	}
+	g.printf("\tif %s != %s {\n\t\tq.Set(%q, %s)\n\t}\n", field, zero, p.Name, value)
+}
+
+// "/villages/{id}" -> "/villages/" + strconv.Itoa(id)
+func (g *generator) pathExpr(path string, params []Parameter) string {
+	expr := fmt.Sprintf("%q", path)
+	for _, p := range params {
+		arg := lowerFirst(goName(p.Name))
+		if g.goType(p.Schema) == "int" {
+			g.imports["strconv"] = true
+			arg = "strconv.Itoa(" + arg + ")"
+		} else {
+			g.imports["net/url"] = true
+			arg = "url.PathEscape(" + arg + ")"
+		}
+		expr = strings.Replace(expr, "{"+p.Name+"}", `" + `+arg+` + "`, 1)
+	}
+	return strings.TrimSuffix(expr, ` + ""`)
+}
+
+// ===== Nazwy =====
+
+// skróty pisane w Go wielkimi literami
+var initialisms = map[string]bool{
+	"id": true, "ip": true, "url": true, "api": true, "jwks": true,
+	"mfa": true, "totp": true, "oda": true, "odd": true, "json": true,
+}
+
+// village_id -> VillageID, getJWKS -> GetJWKS, loginMFA -> LoginMFA
+func goName(s string) string {
+	var words []string
+	for _, part := range strings.Split(s, "_") {
+		start := 0
+		for i := 1; i < len(part); i++ {
+			if part[i] >= 'A' && part[i] <= 'Z' && part[i-1] >= 'a' && part[i-1] <= 'z' {
+				words = append(words, part[start:i])
+				start = i
+			}
+		}
+		words = append(words, part[start:])
+	}
+	var b strings.Builder
+	for _, w := range words {
+		if w == "" {
+			continue
+		}
+		lower := strings.ToLower(w)
+		if initialisms[lower] {
+			b.WriteString(strings.ToUpper(lower))
+			continue
+		}
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	return b.String()
+}
+
+// nazwa argumentu: VillageID -> villageID, Type -> typeName (słowo kluczowe)
+func lowerFirst(s string) string {
+	name := strings.ToLower(s[:1]) + s[1:]
+	for k := range initialisms {
+		if strings.HasPrefix(s, strings.ToUpper(k)) && (len(s) == len(k) || s[len(k)] < 'a') {
+			name = k + s[len(k):]
+		}
+	}
+	if token.IsKeyword(name) {
+		name += "Name"
+	}
+	return name
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
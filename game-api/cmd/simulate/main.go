@@ -0,0 +1,125 @@
+// Command simulate rozgrywa bitwę tym samym kodem co symulator w API gry.
+//
+//	go run ./cmd/simulate -attacker swordsman=100,archer=50 -defender spearman=200 -wall 5 -seed 42
+//	go run ./cmd/simulate -input battle.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"PawTribalWars/battle"
+)
+
+func main() {
+	input := flag.String("input", "", "JSON file with simulation input (\"-\" for stdin)")
+	attacker := flag.String("attacker", "", "attacking army, e.g. swordsman=100,archer=50")
+	defender := flag.String("defender", "", "defending army, e.g. spearman=200")
+	attResearch := flag.String("attacker-research", "", "attacker research levels, e.g. swordsman=2")
+	defResearch := flag.String("defender-research", "", "defender research levels, e.g. spearman=3")
+	wall := flag.Int("wall", 0, "wall level")
+	morale := flag.Float64("morale", 100, "attacker morale in percent")
+	luck := flag.String("luck", "", "fixed luck in percent (-25..25); random if empty")
+	seed := flag.String("seed", "", "random seed for reproducible luck")
+	flag.Parse()
+
+	var in battle.Input
+	var err error
+	if *input != "" {
+		in, err = readInput(*input)
+	} else {
+		in, err = inputFromFlags(*attacker, *defender, *attResearch, *defResearch, *wall, *morale, *luck, *seed)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "simulate:", err)
+		os.Exit(2)
+	}
+
+	if problems := in.Validate(); len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Fprintln(os.Stderr, "simulate:", p)
+		}
+		os.Exit(2)
+	}
+
+	result, err := battle.Simulate(in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "simulate:", err)
+		os.Exit(1)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(result)
+}
+
+func readInput(path string) (battle.Input, error) {
+	var in battle.Input
+	f := os.Stdin
+	if path != "-" {
+		var err error
+		f, err = os.Open(path)
+		if err != nil {
+			return in, err
+		}
+		defer f.Close()
+	}
+	err := json.NewDecoder(f).Decode(&in)
+	return in, err
+}
+
+func inputFromFlags(attacker, defender, attResearch, defResearch string, wall int, morale float64, luck, seed string) (battle.Input, error) {
+	in := battle.Input{WallLevel: wall, Morale: &morale}
+	var err error
+	if in.Attacker, err = parseCounts(attacker); err != nil {
+		return in, fmt.Errorf("-attacker: %w", err)
+	}
+	if in.Defender, err = parseCounts(defender); err != nil {
+		return in, fmt.Errorf("-defender: %w", err)
+	}
+	if in.AttackerResearch, err = parseCounts(attResearch); err != nil {
+		return in, fmt.Errorf("-attacker-research: %w", err)
+	}
+	if in.DefenderResearch, err = parseCounts(defResearch); err != nil {
+		return in, fmt.Errorf("-defender-research: %w", err)
+	}
+	if luck != "" {
+		l, err := strconv.ParseFloat(luck, 64)
+		if err != nil {
+			return in, fmt.Errorf("-luck: %w", err)
+		}
+		in.Luck = &l
+	}
+	if seed != "" {
+		s, err := strconv.ParseInt(seed, 10, 64)
+		if err != nil {
+			return in, fmt.Errorf("-seed: %w", err)
+		}
+		in.Seed = &s
+	}
+	return in, nil
+}
+
+// "swordsman=100,archer=50" -> {"swordsman": 100, "archer": 50}
+func parseCounts(s string) (battle.Army, error) {
+	army := battle.Army{}
+	if s == "" {
+		return army, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("expected type=count, got %q", part)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("bad count for %s: %w", name, err)
+		}
+		army[name] = n
+	}
+	return army, nil
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// load uruchamia Load z podanymi zmiennymi PAW_* i opcjonalnym plikiem
+func load(t *testing.T, env map[string]string, file string) (*Config, error) {
+	t.Helper()
+	t.Setenv("PAW_CONFIG_FILE", "")
+	if file != "" {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("PAW_CONFIG_FILE", path)
+	}
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	return Load()
+}
+
+// poza dev potrzebne są prawdziwe sekrety i SMTP
+var stagingEnv = map[string]string{
+	"PAW_ENV":            EnvStaging,
+	"PAW_DB_DSN":         "postgres://paw:secret@db:5432/paw",
+	"PAW_JWT_ALGORITHM":  "HS256",
+	"PAW_JWT_SECRET":     strings.Repeat("s", 32),
+	"PAW_MAIL_DRIVER":    "smtp",
+	"PAW_MAIL_SMTP_HOST": "smtp.example.com",
+}
+
+func with(base map[string]string, overrides map[string]string) map[string]string {
+	env := map[string]string{}
+	for k, v := range base {
+		env[k] = v
+	}
+	for k, v := range overrides {
+		env[k] = v
+	}
+	return env
+}
+
+func TestLoadOverrides(t *testing.T) {
+	file := `{
+		"listen_addr": ":9000",
+		"world": {"speed": 2, "beginner_protection": "72h"},
+		"rate_limit": {"limits": {"login": {"per_minute": 1, "burst": 2}}}
+	}`
+	cases := []struct {
+		name  string
+		env   map[string]string
+		file  string
+		check func(*Config) bool
+	}{
+		{"defaults", nil, "", func(c *Config) bool {
+			return c.Env == EnvDev && c.ListenAddr == ":8080" && c.JWT.Algorithm == "RS256"
+		}},
+		{"file", nil, file, func(c *Config) bool {
+			return c.ListenAddr == ":9000" && c.World.Speed == 2 && c.World.BeginnerProtection.Duration == 72*time.Hour
+		}},
+		{"file replaces only given limits", nil, file, func(c *Config) bool {
+			return c.RateLimit.Limits["login"] == LimitConfig{PerMinute: 1, Burst: 2} &&
+				c.RateLimit.Limits["write"] == Default().RateLimit.Limits["write"]
+		}},
+		{"env over file", map[string]string{"PAW_WORLD_SPEED": "3", "PAW_LISTEN_ADDR": " :7000 "}, file, func(c *Config) bool {
+			return c.World.Speed == 3 && c.ListenAddr == ":7000" && c.World.BeginnerProtection.Duration == 72*time.Hour
+		}},
+		{"env types", map[string]string{
+			"PAW_DB_AUTO_MIGRATE":        "false",
+			"PAW_DB_MAX_OPEN_CONNS":      "50",
+			"PAW_JWT_ACCESS_TTL":         "5m",
+			"PAW_TRUSTED_PROXIES":        "10.0.0.0/8, 127.0.0.1,",
+			"PAW_RATE_LIMIT_ENABLED":     "0",
+			"PAW_ACCOUNT_VACATION_DELAY": "0s",
+		}, "", func(c *Config) bool {
+			return !c.DB.AutoMigrate && c.DB.MaxOpenConns == 50 && c.JWT.AccessTTL.Duration == 5*time.Minute &&
+				len(c.TrustedProxies) == 2 && c.TrustedProxies[1] == "127.0.0.1" &&
+				!c.RateLimit.Enabled && c.Account.VacationDelay.Duration == 0
+		}},
+		{"staging with real secrets", stagingEnv, "", func(c *Config) bool {
+			return c.Env == EnvStaging && !c.IsDev()
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg, err := load(t, c.env, c.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !c.check(cfg) {
+				t.Errorf("unexpected config: %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	cases := []struct {
+		name string
+		env  map[string]string
+		file string
+		want []string // fragmenty, które muszą być w błędzie
+	}{
+		{"not a number", map[string]string{"PAW_WORLD_SPEED": "fast"}, "",
+			[]string{`PAW_WORLD_SPEED: not a number: "fast"`}},
+		{"not an integer", map[string]string{"PAW_DB_MAX_OPEN_CONNS": "lots"}, "",
+			[]string{"PAW_DB_MAX_OPEN_CONNS: not an integer"}},
+		{"not a boolean", map[string]string{"PAW_RATE_LIMIT_ENABLED": "maybe"}, "",
+			[]string{"PAW_RATE_LIMIT_ENABLED: not a boolean"}},
+		{"duration without unit", map[string]string{"PAW_JWT_ACCESS_TTL": "15"}, "",
+			[]string{"PAW_JWT_ACCESS_TTL: not a duration"}},
+		{"all errors at once", map[string]string{"PAW_ENV": "prod", "PAW_MAIL_DRIVER": "pigeon", "PAW_WORLD_SPEED": "0"}, "",
+			[]string{"env must be one of", "mail.driver must be log, file or smtp", "world.speed must be positive"}},
+		{"refresh shorter than access", map[string]string{"PAW_JWT_ACCESS_TTL": "2h", "PAW_JWT_REFRESH_TTL": "1h"}, "",
+			[]string{"jwt.refresh_ttl must be longer"}},
+		{"bad proxy", map[string]string{"PAW_TRUSTED_PROXIES": "10.0.0.0/8,proxy.local"}, "",
+			[]string{`trusted_proxies: not an IP address or CIDR: "proxy.local"`}},
+		{"missing policy limit", nil, `{"rate_limit": {"limits": {"login": {"per_minute": 0, "burst": 1}}}}`,
+			[]string{"rate_limit.limits.login needs positive per_minute and burst"}},
+		{"unknown field in file", nil, `{"wrold": {"speed": 2}}`,
+			[]string{`unknown field "wrold"`}},
+		{"bad duration in file", nil, `{"jwt": {"access_ttl": 900}}`,
+			[]string{"duration must be a string"}},
+		{"production without smtp", with(stagingEnv, map[string]string{"PAW_ENV": EnvProduction, "PAW_MAIL_DRIVER": "log"}), "",
+			[]string{"mail.driver must be smtp in production"}},
+		{"default dsn outside dev", with(stagingEnv, map[string]string{"PAW_DB_DSN": DefaultDSN}), "",
+			[]string{"default db.dsn"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := load(t, c.env, c.file)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range c.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadRequiresSigningKey(t *testing.T) {
+	cases := []struct {
+		name string
+		env  map[string]string
+		want string // pusty = konfiguracja poprawna
+	}{
+		{"HS256 without secret", map[string]string{"PAW_JWT_ALGORITHM": "HS256", "PAW_JWT_SECRET": ""},
+			"jwt.secret is required for HS256"},
+		{"HS256 default secret outside dev", with(stagingEnv, map[string]string{"PAW_JWT_SECRET": DefaultJWTSecret}),
+			"refusing to start in staging with the default jwt.secret"},
+		{"HS256 short secret outside dev", with(stagingEnv, map[string]string{"PAW_JWT_SECRET": "short"}),
+			"jwt.secret must be at least 32 characters"},
+		{"HS256 default secret in dev", map[string]string{"PAW_JWT_ALGORITHM": "HS256"}, ""},
+		{"unknown algorithm", map[string]string{"PAW_JWT_ALGORITHM": "none"},
+			`jwt.algorithm must be HS256, RS256 or EdDSA (got "none")`},
+		// klucze RS256/EdDSA są w bazie, sekret nie jest potrzebny
+		{"RS256 without secret", with(stagingEnv, map[string]string{"PAW_JWT_ALGORITHM": "RS256", "PAW_JWT_SECRET": ""}), ""},
+		{"retention shorter than access ttl", map[string]string{"PAW_JWT_ALGORITHM": "EdDSA", "PAW_JWT_KEY_RETENTION": "1m"},
+			"jwt.key_retention must be at least jwt.access_ttl"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := load(t, c.env, "")
+			switch {
+			case c.want == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case c.want != "" && err == nil:
+				t.Fatalf("expected %q", c.want)
+			case c.want != "" && !strings.Contains(err.Error(), c.want):
+				t.Errorf("error %q does not mention %q", err, c.want)
+			}
+		})
+	}
+}
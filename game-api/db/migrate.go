@@ -0,0 +1,282 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// migracje wkompilowane w binarkę: NNNN_nazwa.up.sql + opcjonalne NNNN_nazwa.down.sql
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// wspólny klucz pg_advisory_lock, żeby kilka instancji nie migrowało naraz
+const migrationLockID = 727274
+
+var migrationName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string // pusty = migracji nie da się cofnąć
+	Checksum string // sha256 pliku up
+}
+
+// stan migracji w bazie (AppliedAt nil = oczekuje)
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// wczytuje i sortuje migracje z katalogu migrations
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		m := migrationName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migration %q: name must look like 0001_name.up.sql", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		content, err := fs.ReadFile(fsys, path.Join("migrations", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			sum := sha256.Sum256(content)
+			mig.Up = string(content)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// bierze blokadę na osobnym połączeniu i zakłada schema_migrations; cała migracja
+// idzie tym połączeniem, bo blokada sesyjna należy do niego
+func lockMigrations(conn *sql.DB) (*sql.Conn, func(), error) {
+	ctx := context.Background()
+	c, err := conn.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := c.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		c.Close()
+		return nil, nil, err
+	}
+	unlock := func() {
+		_, _ = c.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockID)
+		c.Close()
+	}
+
+	_, err = c.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`)
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	return c, unlock, nil
+}
+
+// wersje zastosowane w bazie razem z sumami kontrolnymi
+func appliedMigrations(c *sql.Conn) (map[int]string, map[int]time.Time, error) {
+	rows, err := c.QueryContext(context.Background(), "SELECT version, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	checksums := map[int]string{}
+	appliedAt := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var checksum string
+		var at time.Time
+		if err := rows.Scan(&version, &checksum, &at); err != nil {
+			return nil, nil, err
+		}
+		checksums[version] = checksum
+		appliedAt[version] = at
+	}
+	return checksums, appliedAt, rows.Err()
+}
+
+// zastosowana migracja nie może się zmienić ani zniknąć z binarki
+func verifyChecksums(migrations []Migration, applied map[int]string) error {
+	known := map[int]bool{}
+	for _, m := range migrations {
+		known[m.Version] = true
+		if sum, ok := applied[m.Version]; ok && sum != m.Checksum {
+			return fmt.Errorf("migration %d_%s was modified after being applied (checksum mismatch)", m.Version, m.Name)
+		}
+	}
+	for version := range applied {
+		if !known[version] {
+			return fmt.Errorf("database has migration %d applied, which this binary does not know - deploy a newer build", version)
+		}
+	}
+	return nil
+}
+
+// Migrate stosuje wszystkie oczekujące migracje, każdą w osobnej transakcji
+func Migrate(conn *sql.DB) ([]Migration, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+	c, unlock, err := lockMigrations(conn)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, _, err := appliedMigrations(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyChecksums(migrations, applied); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		err := inTx(c, m.Up, "INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+			m.Version, m.Name, m.Checksum)
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		log.Printf("migrate: applied %d_%s", m.Version, m.Name)
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrateDown cofa steps ostatnio zastosowanych migracji
+func MigrateDown(conn *sql.DB, steps int) ([]Migration, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+	c, unlock, err := lockMigrations(conn)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, _, err := appliedMigrations(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyChecksums(migrations, applied); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.Down == "" {
+			return done, fmt.Errorf("migration %d_%s cannot be reverted (no down file)", m.Version, m.Name)
+		}
+		if err := inTx(c, m.Down, "DELETE FROM schema_migrations WHERE version=$1", m.Version); err != nil {
+			return done, fmt.Errorf("revert %d_%s: %w", m.Version, m.Name, err)
+		}
+		log.Printf("migrate: reverted %d_%s", m.Version, m.Name)
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Migrations zwraca wszystkie znane migracje ze stanem w bazie
+func Migrations(conn *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+	c, unlock, err := lockMigrations(conn)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, appliedAt, err := appliedMigrations(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyChecksums(migrations, applied); err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		s := MigrationStatus{Migration: m}
+		if at, ok := appliedAt[m.Version]; ok {
+			s.AppliedAt = &at
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+// wykonuje skrypt migracji i zapis w schema_migrations atomowo
+func inTx(c *sql.Conn, script, record string, args ...interface{}) error {
+	tx, err := c.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// bez argumentów pq wysyła cały plik jednym zapytaniem (wiele poleceń, bloki DO)
+	if _, err := tx.Exec(script); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
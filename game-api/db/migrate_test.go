@@ -0,0 +1,148 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Fatalf("migration %d_%s: versions must be consecutive from 1", m.Version, m.Name)
+		}
+		if m.Down == "" {
+			t.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_second.up.sql":     {Data: []byte("SELECT 2;")},
+		"migrations/0001_first.up.sql":      {Data: []byte("SELECT 1;")},
+		"migrations/0001_first.down.sql":    {Data: []byte("SELECT -1;")},
+		"migrations/0010_after_nine.up.sql": {Data: []byte("SELECT 10;")},
+	}
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	for _, m := range migrations {
+		got = append(got, m.Version)
+	}
+	if len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 10 {
+		t.Fatalf("order = %v, want [1 2 10]", got)
+	}
+	first := migrations[0]
+	if first.Name != "first" || first.Up != "SELECT 1;" || first.Down != "SELECT -1;" || len(first.Checksum) != 64 {
+		t.Fatalf("first = %+v", first)
+	}
+	if migrations[1].Down != "" {
+		t.Fatalf("second should have no down file: %+v", migrations[1])
+	}
+	if first.Checksum == migrations[1].Checksum {
+		t.Fatal("different files share a checksum")
+	}
+}
+
+func TestLoadMigrationsRejectsBadFiles(t *testing.T) {
+	cases := map[string]struct {
+		files fstest.MapFS
+		want  string
+	}{
+		"bad name": {fstest.MapFS{
+			"migrations/first.up.sql": {Data: []byte("SELECT 1;")},
+		}, "name must look like"},
+		"two names": {fstest.MapFS{
+			"migrations/0001_first.up.sql":   {Data: []byte("SELECT 1;")},
+			"migrations/0001_other.down.sql": {Data: []byte("SELECT -1;")},
+		}, "two names"},
+		"only down": {fstest.MapFS{
+			"migrations/0001_first.down.sql": {Data: []byte("SELECT -1;")},
+		}, "no up file"},
+	}
+	for name, c := range cases {
+		_, err := loadMigrations(c.files)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: got %v, want error containing %q", name, err, c.want)
+		}
+	}
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "first", Checksum: "aaa"},
+		{Version: 2, Name: "second", Checksum: "bbb"},
+	}
+	cases := map[string]struct {
+		applied map[int]string
+		want    string // "" = bez błędu
+	}{
+		"nothing applied":     {map[int]string{}, ""},
+		"partly applied":      {map[int]string{1: "aaa"}, ""},
+		"all applied":         {map[int]string{1: "aaa", 2: "bbb"}, ""},
+		"modified":            {map[int]string{1: "aaa", 2: "changed"}, "2_second was modified"},
+		"unknown in database": {map[int]string{1: "aaa", 3: "ccc"}, "migration 3 applied"},
+	}
+	for name, c := range cases {
+		err := verifyChecksums(migrations, c.applied)
+		switch {
+		case c.want == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", name, err)
+		case c.want != "" && (err == nil || !strings.Contains(err.Error(), c.want)):
+			t.Errorf("%s: got %v, want error containing %q", name, err, c.want)
+		}
+	}
+}
+
+// kolumny tabel, które istniały już w dawnym init.sql
+var initSQLColumns = map[string][]string{
+	"users":    {"id", "username", "email", "password_hash", "role", "created_at"},
+	"villages": {"id", "user_id", "name", "created_at"},
+}
+
+// baza z init.sql ma stare tabele, więc każda późniejsza kolumna potrzebuje ADD COLUMN
+func TestBaselineAdoptsInitSQLTables(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline := migrations[0].Up
+	for table, old := range initSQLColumns {
+		start := strings.Index(baseline, "CREATE TABLE IF NOT EXISTS "+table+" (")
+		if start < 0 {
+			t.Fatalf("no CREATE TABLE for %s", table)
+		}
+		body := baseline[start:]
+		body = body[strings.Index(body, "(")+1 : strings.Index(body, "\n);")]
+		for _, line := range strings.Split(body, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 || strings.HasPrefix(fields[0], "--") {
+				continue
+			}
+			column := fields[0]
+			if contains(old, column) {
+				continue
+			}
+			if !strings.Contains(baseline, "ADD COLUMN IF NOT EXISTS "+column+" ") &&
+				!strings.Contains(baseline, "ADD COLUMN "+column+" ") {
+				t.Errorf("%s.%s is missing from the init.sql adoption section", table, column)
+			}
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+-- Cofnięcie schematu bazowego usuwa cały świat
+DROP TABLE IF EXISTS vacations;
+DROP TABLE IF EXISTS sitter_actions;
+DROP TABLE IF EXISTS sitters;
+DROP TABLE IF EXISTS rate_limits;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS email_tokens;
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS bans;
+DROP TABLE IF EXISTS signing_keys;
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS scheduled_events;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS research_queue;
+DROP TABLE IF EXISTS research;
+DROP TABLE IF EXISTS units;
+DROP TABLE IF EXISTS unit_types;
+DROP TABLE IF EXISTS buildings;
+DROP TABLE IF EXISTS resources;
+DROP TABLE IF EXISTS villages;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS tribes;
@@ -0,0 +1,405 @@
+-- Schemat bazowy świata (odpowiednik dawnego db/init.sql).
+-- Wszystkie polecenia są idempotentne, więc migracja przechodzi także na bazie
+-- założonej wcześniej przez init.sql: CREATE TABLE IF NOT EXISTS pomija stare
+-- tabele, a sekcja "Adopcja bazy z init.sql" dopina ich brakujące kolumny,
+-- klucze, wiersze kuźni i badań oraz ograniczenia i indeksy (koniec pliku).
+
+-- ===========================
+-- Tabela plemion
+-- ===========================
+CREATE TABLE IF NOT EXISTS tribes (
+                        id SERIAL PRIMARY KEY,
+                        name VARCHAR(100) UNIQUE NOT NULL,
+                        tag VARCHAR(10) UNIQUE NOT NULL,
+                        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ===========================
+-- Tabela użytkowników
+-- ===========================
+CREATE TABLE IF NOT EXISTS users (
+                       id SERIAL PRIMARY KEY,
+                       username VARCHAR(50) UNIQUE NOT NULL,
+                       email VARCHAR(100) UNIQUE NOT NULL,
+                       password_hash VARCHAR(255) NOT NULL,
+                       role VARCHAR(20) DEFAULT 'player',
+                       tribe_id INT REFERENCES tribes(id) ON DELETE SET NULL,
+                       points INT DEFAULT 0,          -- suma punktów wszystkich wiosek
+                       kills_att BIGINT DEFAULT 0,    -- pokonani przeciwnicy jako agresor (ODA)
+                       kills_def BIGINT DEFAULT 0,    -- pokonani przeciwnicy jako obrońca (ODD)
+                       email_verified_at TIMESTAMPTZ, -- NULL = email niepotwierdzony
+                       totp_secret VARCHAR(64),       -- sekret 2FA (base32), ustawiany przy /2fa/enroll
+                       totp_enabled_at TIMESTAMPTZ,   -- NULL = 2FA wyłączone lub niepotwierdzone
+                       totp_last_step BIGINT DEFAULT 0, -- ostatnie użyte okno TOTP (ochrona przed powtórką)
+                       failed_logins INT DEFAULT 0,   -- nieudane logowania od ostatniego udanego
+                       locked_until TIMESTAMPTZ,      -- blokada po zbyt wielu nieudanych logowaniach
+                       pending_email VARCHAR(100),    -- nowy adres czekający na potwierdzenie
+                       profile_text TEXT,
+                       avatar_url VARCHAR(500),
+                       deletion_scheduled_at TIMESTAMPTZ, -- konto zostanie usunięte o tej porze (DELETE /me)
+                       deleted_at TIMESTAMPTZ,        -- konto zanonimizowane, wioski oddane barbarzyńcom
+                       protected_until TIMESTAMPTZ,   -- ochrona początkujących (NULL = brak)
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ===========================
+-- Tabela wiosek
+-- ===========================
+CREATE TABLE IF NOT EXISTS villages (
+                          id SERIAL PRIMARY KEY,
+                          user_id INT REFERENCES users(id) ON DELETE SET NULL, -- NULL = wioska barbarzyńska
+                          name VARCHAR(100) NOT NULL,
+                          points INT DEFAULT 0,
+                          created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ===========================
+-- Tabela zasobów
+-- ===========================
+CREATE TABLE IF NOT EXISTS resources (
+                           id SERIAL PRIMARY KEY,
+                           village_id INT NOT NULL REFERENCES villages(id) ON DELETE CASCADE,
+                           wood INT DEFAULT 100,
+                           clay INT DEFAULT 100,
+                           iron INT DEFAULT 100,
+                           updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ===========================
+-- Tabela budynków
+-- ===========================
+CREATE TABLE IF NOT EXISTS buildings (
+                           id SERIAL PRIMARY KEY,
+                           village_id INT NOT NULL REFERENCES villages(id) ON DELETE CASCADE,
+                           type VARCHAR(50) NOT NULL,   -- np. 'townhall', 'lumbermill', 'claypit', 'ironmine', 'warehouse', 'barracks', 'smithy'
+                           level INT DEFAULT 1,
+                           created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ===========================
+-- Tabela jednostek w wiosce
+-- ===========================
+CREATE TABLE IF NOT EXISTS units (
+                       id SERIAL PRIMARY KEY,
+                       village_id INT NOT NULL REFERENCES villages(id) ON DELETE CASCADE,
+                       type VARCHAR(50) NOT NULL,  -- np. 'spearman', 'swordsman', 'cavalry'
+                       count INT DEFAULT 0,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ===========================
+-- Tabela typów jednostek (koszty, czas szkolenia)
+-- ===========================
+CREATE TABLE IF NOT EXISTS unit_types (
+                            type VARCHAR(50) PRIMARY KEY,
+                            wood INT,
+                            clay INT,
+                            iron INT,
+                            training_time INT -- w sekundach
+);
+
+-- ===========================
+-- Tabela badań w kuźni (poziom 0 = niezbadana)
+-- ===========================
+CREATE TABLE IF NOT EXISTS research (
+                          village_id INT NOT NULL REFERENCES villages(id) ON DELETE CASCADE,
+                          unit_type VARCHAR(50) NOT NULL,
+                          level INT DEFAULT 0,
+                          PRIMARY KEY (village_id, unit_type)
+);
+
+-- ===========================
+-- Kolejka badań w kuźni
+-- ===========================
+CREATE TABLE IF NOT EXISTS research_queue (
+                                id SERIAL PRIMARY KEY,
+                                village_id INT NOT NULL REFERENCES villages(id) ON DELETE CASCADE,
+                                unit_type VARCHAR(50) NOT NULL,
+                                level INT NOT NULL,
+                                finishes_at TIMESTAMP NOT NULL,
+                                created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ===========================
+-- Dziennik powiadomień gracza (replay dla WebSocket/SSE)
+-- ===========================
+CREATE TABLE IF NOT EXISTS notifications (
+                               id BIGSERIAL PRIMARY KEY,
+                               user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                               type VARCHAR(50) NOT NULL,
+                               payload JSONB NOT NULL,
+                               created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS notifications_user_id_idx ON notifications (user_id, id);
+
+-- Domyślne typy jednostek
+INSERT INTO unit_types (type, wood, clay, iron, training_time) VALUES
+                                                                   ('spearman', 50, 30, 20, 30),
+                                                                   ('swordsman', 100, 50, 50, 60),
+                                                                   ('cavalry', 200, 100, 150, 120)
+ON CONFLICT (type) DO NOTHING;
+
+
+-- ===========================
+-- Zdarzenia czasowe wykonywane przez scheduler
+-- ===========================
+CREATE TABLE IF NOT EXISTS scheduled_events (
+                                  id BIGSERIAL PRIMARY KEY,
+                                  kind VARCHAR(50) NOT NULL,
+                                  payload JSONB NOT NULL DEFAULT '{}',
+                                  due_at TIMESTAMPTZ NOT NULL,
+                                  status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending / done / failed
+                                  attempts INT NOT NULL DEFAULT 0,
+                                  last_error TEXT,
+                                  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+                                  processed_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS scheduled_events_due_idx ON scheduled_events (due_at, id) WHERE status = 'pending';
+
+-- ===========================
+-- Sesje urządzeń (rotowane refresh tokeny)
+-- ===========================
+CREATE TABLE IF NOT EXISTS sessions (
+                          id VARCHAR(64) PRIMARY KEY,
+                          user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                          sitter_id INT REFERENCES users(id) ON DELETE CASCADE, -- sesja zastępcy na koncie user_id
+                          refresh_hash VARCHAR(64) NOT NULL,  -- sha256 aktualnego refresh tokenu
+                          user_agent TEXT,
+                          ip VARCHAR(64),
+                          created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+                          last_used_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+                          expires_at TIMESTAMPTZ NOT NULL,
+                          revoked_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
+
+-- ===========================
+-- Unieważnione access tokeny (jti) do czasu ich wygaśnięcia
+-- ===========================
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+                                jti VARCHAR(64) PRIMARY KEY,
+                                expires_at TIMESTAMPTZ NOT NULL
+);
+
+-- ===========================
+-- Klucze podpisujące JWT (RS256/EdDSA), wspólne dla wszystkich instancji
+-- ===========================
+CREATE TABLE IF NOT EXISTS signing_keys (
+                              kid VARCHAR(32) PRIMARY KEY,
+                              alg VARCHAR(10) NOT NULL,
+                              private_key TEXT NOT NULL,   -- PKCS#8 PEM
+                              created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+                              retired_at TIMESTAMPTZ       -- po rotacji klucz tylko weryfikuje
+);
+
+-- ===========================
+-- Blokady kont (expires_at NULL = na zawsze)
+-- ===========================
+CREATE TABLE IF NOT EXISTS bans (
+                      id SERIAL PRIMARY KEY,
+                      user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                      reason TEXT NOT NULL,
+                      banned_by INT REFERENCES users(id) ON DELETE SET NULL,
+                      created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+                      expires_at TIMESTAMPTZ,
+                      lifted_at TIMESTAMPTZ,
+                      lifted_by INT REFERENCES users(id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS bans_user_id_idx ON bans (user_id);
+
+-- ===========================
+-- Dziennik akcji administracyjnych
+-- ===========================
+CREATE TABLE IF NOT EXISTS audit_log (
+                           id BIGSERIAL PRIMARY KEY,
+                           actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+                           action VARCHAR(50) NOT NULL,
+                           target_type VARCHAR(30) NOT NULL,
+                           target_id VARCHAR(64) NOT NULL,
+                           details JSONB NOT NULL DEFAULT '{}',
+                           ip VARCHAR(64),
+                           created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ===========================
+-- Zgłoszenia graczy (kolejka moderacji)
+-- ===========================
+CREATE TABLE IF NOT EXISTS reports (
+                         id SERIAL PRIMARY KEY,
+                         reporter_id INT REFERENCES users(id) ON DELETE SET NULL,
+                         target_type VARCHAR(20) NOT NULL, -- village, message, user
+                         target_id INT NOT NULL,
+                         target_snapshot TEXT NOT NULL DEFAULT '', -- treść w chwili zgłoszenia
+                         reason TEXT NOT NULL,
+                         status VARCHAR(20) NOT NULL DEFAULT 'open', -- open, resolved, dismissed
+                         handled_by INT REFERENCES users(id) ON DELETE SET NULL,
+                         resolution_note TEXT,
+                         created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+                         handled_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS reports_status_idx ON reports (status, created_at);
+
+-- ===========================
+-- Jednorazowe tokeny wysyłane mailem (weryfikacja, reset hasła)
+-- ===========================
+CREATE TABLE IF NOT EXISTS email_tokens (
+                              token_hash CHAR(64) PRIMARY KEY, -- sha256, sam token jest tylko w mailu
+                              user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                              purpose VARCHAR(20) NOT NULL, -- verify_email, reset_password
+                              created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+                              expires_at TIMESTAMPTZ NOT NULL,
+                              used_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS email_tokens_user_idx ON email_tokens (user_id, purpose);
+
+-- ===========================
+-- Kody zapasowe 2FA (tylko skróty)
+-- ===========================
+CREATE TABLE IF NOT EXISTS recovery_codes (
+                                id SERIAL PRIMARY KEY,
+                                user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                code_hash CHAR(64) NOT NULL,
+                                used_at TIMESTAMPTZ,
+                                UNIQUE (user_id, code_hash)
+);
+
+-- ===========================
+-- Wiadra limitów żądań (store postgres, wspólny dla instancji)
+-- ===========================
+CREATE TABLE IF NOT EXISTS rate_limits (
+                             key VARCHAR(200) PRIMARY KEY, -- polityka:ip:... albo polityka:user:...
+                             tokens DOUBLE PRECISION NOT NULL,
+                             updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- ===========================
+-- Zastępstwa kont (sitterzy)
+-- ===========================
+CREATE TABLE IF NOT EXISTS sitters (
+                         id SERIAL PRIMARY KEY,
+                         owner_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                         sitter_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                         starts_at TIMESTAMPTZ NOT NULL,
+                         ends_at TIMESTAMPTZ NOT NULL,
+                         created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+                         revoked_at TIMESTAMPTZ,
+                         CHECK (owner_id <> sitter_id)
+);
+CREATE INDEX IF NOT EXISTS sitters_owner_idx ON sitters (owner_id);
+CREATE INDEX IF NOT EXISTS sitters_sitter_idx ON sitters (sitter_id);
+
+-- Dziennik akcji zastępców widoczny dla właściciela konta
+CREATE TABLE IF NOT EXISTS sitter_actions (
+                                id SERIAL PRIMARY KEY,
+                                owner_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                sitter_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                method VARCHAR(10) NOT NULL,
+                                path TEXT NOT NULL,
+                                status INT NOT NULL,
+                                ip VARCHAR(64),
+                                created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS sitter_actions_owner_idx ON sitter_actions (owner_id, id);
+
+-- ===========================
+-- Urlopy graczy
+-- ===========================
+CREATE TABLE IF NOT EXISTS vacations (
+                           id SERIAL PRIMARY KEY,
+                           user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                           requested_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+                           starts_at TIMESTAMPTZ NOT NULL,
+                           ends_at TIMESTAMPTZ NOT NULL,   -- planowany koniec
+                           ended_at TIMESTAMPTZ,           -- wcześniejsze zakończenie / anulowanie
+                           settled_at TIMESTAMPTZ          -- produkcja i kolejki rozliczone po urlopie
+);
+CREATE INDEX IF NOT EXISTS vacations_user_idx ON vacations (user_id, starts_at);
+
+-- ===========================
+-- Adopcja bazy z init.sql
+-- ===========================
+
+-- init.sql zakładał users i villages bez kolumn dodanych później; na nowej bazie
+-- te polecenia nic nie robią
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS tribe_id INT REFERENCES tribes(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS points INT DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS kills_att BIGINT DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS kills_def BIGINT DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS totp_last_step BIGINT DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS failed_logins INT DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS pending_email VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS profile_text TEXT,
+    ADD COLUMN IF NOT EXISTS avatar_url VARCHAR(500),
+    ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS protected_until TIMESTAMPTZ;
+
+-- konta sprzed weryfikacji maili uznajemy za potwierdzone, inaczej stracą dostęp do gry
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_schema = current_schema() AND table_name = 'users' AND column_name = 'email_verified_at') THEN
+        ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+        UPDATE users SET email_verified_at = COALESCE(created_at, NOW());
+    END IF;
+END $$;
+
+-- wioski usuniętych kont przechodzą do barbarzyńców zamiast znikać kaskadowo
+ALTER TABLE villages ADD COLUMN IF NOT EXISTS points INT DEFAULT 0;
+ALTER TABLE villages ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE villages DROP CONSTRAINT IF EXISTS villages_user_id_fkey;
+ALTER TABLE villages ADD CONSTRAINT villages_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+-- kuźnia i zbadany pikinier należą do zestawu startowego; bez nich stare wioski
+-- nie zrekrutowałyby żadnej jednostki (UNIT_NOT_RESEARCHED)
+INSERT INTO buildings (village_id, type, level)
+SELECT v.id, 'smithy', 1 FROM villages v
+WHERE NOT EXISTS (SELECT 1 FROM buildings b WHERE b.village_id = v.id AND b.type = 'smithy');
+
+INSERT INTO research (village_id, unit_type, level)
+SELECT v.id, 'spearman', 1 FROM villages v
+ON CONFLICT (village_id, unit_type) DO NOTHING;
+
+-- ===========================
+-- Ograniczenia i indeksy
+-- ===========================
+
+-- init.sql nie pilnował unikalności, więc stare światy mogą mieć duplikaty:
+-- budynek zostaje z najwyższym poziomem, jednostki się sumują, surowce - najstarszy wiersz
+DELETE FROM buildings a USING buildings b
+WHERE a.village_id = b.village_id AND a.type = b.type
+  AND (a.level < b.level OR (a.level = b.level AND a.id > b.id));
+
+UPDATE units u SET count = d.total
+FROM (SELECT MIN(id) AS id, SUM(count) AS total FROM units GROUP BY village_id, type HAVING COUNT(*) > 1) d
+WHERE u.id = d.id;
+DELETE FROM units a USING units b
+WHERE a.village_id = b.village_id AND a.type = b.type AND a.id > b.id;
+
+DELETE FROM resources a USING resources b
+WHERE a.village_id = b.village_id AND a.id > b.id;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'buildings_village_id_type_key') THEN
+        ALTER TABLE buildings ADD CONSTRAINT buildings_village_id_type_key UNIQUE (village_id, type);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'units_village_id_type_key') THEN
+        ALTER TABLE units ADD CONSTRAINT units_village_id_type_key UNIQUE (village_id, type);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'resources_village_id_key') THEN
+        ALTER TABLE resources ADD CONSTRAINT resources_village_id_key UNIQUE (village_id);
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS villages_user_id_idx ON villages (user_id);
+CREATE INDEX IF NOT EXISTS users_tribe_id_idx ON users (tribe_id);
+CREATE INDEX IF NOT EXISTS research_queue_village_idx ON research_queue (village_id, finishes_at);
+CREATE INDEX IF NOT EXISTS revoked_tokens_expires_idx ON revoked_tokens (expires_at);
+CREATE INDEX IF NOT EXISTS rate_limits_updated_idx ON rate_limits (updated_at);
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor_id);
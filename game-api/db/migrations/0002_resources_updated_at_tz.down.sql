@@ -0,0 +1 @@
+ALTER TABLE resources ALTER COLUMN updated_at TYPE TIMESTAMP;
@@ -0,0 +1,4 @@
+-- resources.updated_at jest zapisywany z aplikacji (time.Time) i porównywany
+-- z NOW(); bez strefy czasowej produkcja rozjeżdżała się, gdy aplikacja i baza
+-- mają różne strefy. Istniejące wartości są czytane w strefie sesji (jak NOW()).
+ALTER TABLE resources ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
@@ -0,0 +1 @@
+ALTER TABLE research_queue ALTER COLUMN finishes_at TYPE TIMESTAMP;
@@ -0,0 +1,4 @@
+-- research_queue.finishes_at jest zapisywany z aplikacji (time.Time) i porównywany
+-- z NOW() przez completeResearch i scheduler; bez strefy czasowej badania kończyły
+-- się za wcześnie albo za późno, gdy aplikacja i baza mają różne strefy.
+ALTER TABLE research_queue ALTER COLUMN finishes_at TYPE TIMESTAMPTZ;
@@ -0,0 +1,11 @@
+-- nieudane logowania na nieistniejące nazwy gracza: blokują się tak samo jak
+-- konta w users, żeby 429 nie zdradzało, które konta istnieją.
+-- Klucz to sha256 nazwy, więc tabela nie zbiera dowolnie długich napisów.
+CREATE TABLE IF NOT EXISTS login_failures (
+    username_hash VARCHAR(64) PRIMARY KEY,
+    failed_logins INT NOT NULL DEFAULT 0,
+    locked_until  TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS login_failures_updated_idx ON login_failures (updated_at);
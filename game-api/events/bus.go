@@ -0,0 +1,163 @@
+package events
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// typy zdarzeń wysyłanych do graczy
+const (
+	BuildingFinished = "building_finished"
+	TroopsTrained    = "troops_trained"
+	IncomingAttack   = "incoming_attack"
+	ReportReceived   = "report_received"
+	NewMessage       = "new_message"
+	ResourceSnapshot = "resource_snapshot"
+)
+
+// ile ostatnich zdarzeń trzymamy na gracza do odtworzenia po reconnect
+const historySize = 200
+
+// bufor kanału subskrybenta; przepełniony kanał jest zamykany
+const subscriberBuffer = 64
+
+// liczba blokad publikacji; gracze dzielą je według user_id
+const publishStripes = 64
+
+type Event struct {
+	ID        int64       `json:"id"`
+	Type      string      `json:"type"`
+	Payload   interface{} `json:"payload"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// Store trwale zapisuje zdarzenia i nadaje im ID
+type Store interface {
+	Append(userID int, eventType string, payload json.RawMessage) (Event, error)
+	Since(userID int, lastID int64, limit int) ([]Event, error)
+}
+
+// Bus rozsyła zdarzenia do subskrybentów danego gracza
+type Bus struct {
+	store Store
+
+	// zapis i rozesłanie zdarzenia gracza są pod jedną blokadą, więc jego
+	// subskrybenci dostają zdarzenia w kolejności ID (odbiorcy pomijają ID
+	// nie większe niż ostatnio wysłane)
+	publishMu [publishStripes]sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	history map[int][]Event
+	subs    map[int]map[chan Event]struct{}
+}
+
+// NewBus tworzy szynę; bez store historia trzymana jest tylko w pamięci
+func NewBus(store Store) *Bus {
+	return &Bus{
+		store:   store,
+		history: map[int][]Event{},
+		subs:    map[int]map[chan Event]struct{}{},
+	}
+}
+
+// domyślna szyna używana przez serwer
+var Default = NewBus(nil)
+
+func Publish(userID int, eventType string, payload interface{}) Event {
+	return Default.Publish(userID, eventType, payload)
+}
+
+func Subscribe(userID int, lastID int64) (<-chan Event, []Event, func()) {
+	return Default.Subscribe(userID, lastID)
+}
+
+// Publish zapisuje zdarzenie w historii gracza i wysyła je do jego połączeń
+func (b *Bus) Publish(userID int, eventType string, payload interface{}) Event {
+	lock := &b.publishMu[uint(userID)%publishStripes]
+	lock.Lock()
+	defer lock.Unlock()
+
+	ev := Event{Type: eventType, Payload: payload, CreatedAt: time.Now()}
+	if b.store != nil {
+		raw, err := json.Marshal(payload)
+		if err == nil {
+			ev, err = b.store.Append(userID, eventType, raw)
+		}
+		if err != nil {
+			// zdarzenie i tak idzie na żywo, tylko nie da się go odtworzyć
+			log.Println("events: cannot store notification:", err)
+			ev = Event{Type: eventType, Payload: payload, CreatedAt: time.Now()}
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.store == nil {
+		b.nextID++
+		ev.ID = b.nextID
+		history := append(b.history[userID], ev)
+		if len(history) > historySize {
+			history = history[len(history)-historySize:]
+		}
+		b.history[userID] = history
+	}
+
+	for ch := range b.subs[userID] {
+		select {
+		case ch <- ev:
+		default:
+			// klient nie nadąża - rozłączamy, dogoni przez last-event ID
+			delete(b.subs[userID], ch)
+			close(ch)
+		}
+	}
+	return ev
+}
+
+// Subscribe zwraca kanał nowych zdarzeń, zdarzenia pominięte od lastID
+// oraz funkcję kończącą subskrypcję. Zdarzenie może trafić zarówno do
+// pominiętych jak i do kanału - odbiorca pomija ID, które już wysłał.
+func (b *Bus) Subscribe(userID int, lastID int64) (<-chan Event, []Event, func()) {
+	b.mu.Lock()
+	ch := make(chan Event, subscriberBuffer)
+	if b.subs[userID] == nil {
+		b.subs[userID] = map[chan Event]struct{}{}
+	}
+	b.subs[userID][ch] = struct{}{}
+
+	var missed []Event
+	if b.store == nil {
+		for _, ev := range b.history[userID] {
+			if ev.ID > lastID {
+				missed = append(missed, ev)
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	// kanał jest już zarejestrowany, więc nic nie wpadnie między odczytem a subskrypcją
+	if b.store != nil && lastID > 0 {
+		var err error
+		missed, err = b.store.Since(userID, lastID, historySize)
+		if err != nil {
+			log.Println("events: cannot replay notifications:", err)
+		}
+	}
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[userID][ch]; ok {
+			delete(b.subs[userID], ch)
+			close(ch)
+		}
+		if len(b.subs[userID]) == 0 {
+			delete(b.subs, userID)
+		}
+	}
+	return ch, missed, cancel
+}
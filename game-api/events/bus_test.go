@@ -0,0 +1,55 @@
+package events
+
+import (
+	"encoding/json"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowStore nadaje ID po kolei, ale oddaje je z losowym opóźnieniem - jak
+// baza, w której INSERT-y kończą się w innej kolejności niż zaczęły
+type slowStore struct {
+	mu     sync.Mutex
+	nextID int64
+}
+
+func (s *slowStore) Append(userID int, eventType string, payload json.RawMessage) (Event, error) {
+	s.mu.Lock()
+	s.nextID++
+	ev := Event{ID: s.nextID, Type: eventType, Payload: payload, CreatedAt: time.Now()}
+	s.mu.Unlock()
+	time.Sleep(time.Duration(rand.Intn(200)) * time.Microsecond)
+	return ev, nil
+}
+
+func (s *slowStore) Since(userID int, lastID int64, limit int) ([]Event, error) {
+	return nil, nil
+}
+
+func TestConcurrentPublishesArriveInIDOrder(t *testing.T) {
+	bus := NewBus(&slowStore{})
+	ch, _, cancel := bus.Subscribe(1, 0)
+	defer cancel()
+
+	const publishes = subscriberBuffer
+	var wg sync.WaitGroup
+	for i := 0; i < publishes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bus.Publish(1, NewMessage, nil)
+		}()
+	}
+	wg.Wait()
+
+	var last int64
+	for i := 0; i < publishes; i++ {
+		ev := <-ch
+		if ev.ID <= last {
+			t.Fatalf("event %d after %d", ev.ID, last)
+		}
+		last = ev.ID
+	}
+}
@@ -0,0 +1,61 @@
+package events
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+// PostgresStore trzyma powiadomienia w tabeli notifications
+type PostgresStore struct {
+	DB *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{DB: db}
+}
+
+// Append przy okazji usuwa zdarzenia gracza starsze niż ostatnie historySize -
+// dalej i tak nie sięga odtwarzanie po reconnect
+func (s *PostgresStore) Append(userID int, eventType string, payload json.RawMessage) (Event, error) {
+	ev := Event{Type: eventType, Payload: payload}
+	err := s.DB.QueryRow(`
+		WITH inserted AS (
+			INSERT INTO notifications (user_id, type, payload) VALUES ($1, $2, $3)
+			RETURNING id, created_at
+		), pruned AS (
+			DELETE FROM notifications
+			WHERE user_id=$1 AND id <= (
+				SELECT id FROM notifications WHERE user_id=$1
+				ORDER BY id DESC
+				OFFSET $4 LIMIT 1
+			)
+		)
+		SELECT id, created_at FROM inserted
+	`, userID, eventType, []byte(payload), historySize-1).Scan(&ev.ID, &ev.CreatedAt)
+	return ev, err
+}
+
+func (s *PostgresStore) Since(userID int, lastID int64, limit int) ([]Event, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, type, payload, created_at FROM notifications
+		WHERE user_id=$1 AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`, userID, lastID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Event
+	for rows.Next() {
+		var ev Event
+		var payload []byte
+		if err := rows.Scan(&ev.ID, &ev.Type, &payload, &ev.CreatedAt); err != nil {
+			return nil, err
+		}
+		ev.Payload = json.RawMessage(payload)
+		out = append(out, ev)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,72 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+)
+
+// błędy domenowe; transport tłumaczy je na własne kody odpowiedzi
+var (
+	ErrVillageNotFound  = errors.New("village not found or not yours")
+	ErrBuildingNotFound = errors.New("building not found")
+	ErrUnknownUnit      = errors.New("invalid unit type")
+	ErrNotResearched    = errors.New("unit not researched in smithy")
+	ErrMissingName      = errors.New("missing village name")
+	ErrInvalidCount     = errors.New("count must be a positive number")
+	// równoległe polecenie zmieniło wioskę w trakcie; można ponowić
+	ErrConcurrentUpdate = errors.New("village changed by a concurrent command")
+	ErrPlayerExists     = errors.New("username or email already taken")
+	// w kuźni trwa już badanie (jedno naraz)
+	ErrResearchInProgress = errors.New("smithy is already researching")
+	ErrResearchMaxLevel   = errors.New("research already at max level")
+	ErrTargetNotFound     = errors.New("target village not found")
+	// wioski gracza na urlopie są nietykalne
+	ErrTargetOnVacation = errors.New("target player is in vacation mode")
+	// gracz pod ochroną początkujących nie może zostać zaatakowany
+	ErrTargetProtected = errors.New("target player is under beginner protection")
+)
+
+// NotEnoughResourcesError niesie koszt i stan magazynu w chwili próby
+type NotEnoughResourcesError struct {
+	Cost      Cost
+	Available Cost
+}
+
+func (e *NotEnoughResourcesError) Error() string {
+	return "not enough resources"
+}
+
+// Missing zwraca brakującą ilość każdego surowca
+func (e *NotEnoughResourcesError) Missing() Cost {
+	missing := func(need, have int) int {
+		if need > have {
+			return need - have
+		}
+		return 0
+	}
+	return Cost{
+		Wood: missing(e.Cost.Wood, e.Available.Wood),
+		Clay: missing(e.Cost.Clay, e.Available.Clay),
+		Iron: missing(e.Cost.Iron, e.Available.Iron),
+	}
+}
+
+// VillageLimitError: gracz ma już tyle wiosek, ile pozwala jego ratusz
+type VillageLimitError struct {
+	Max     int
+	Current int
+}
+
+func (e *VillageLimitError) Error() string {
+	return fmt.Sprintf("You need higher Townhall level to create more villages (current max: %d)", e.Max)
+}
+
+// SmithyLevelError: badanie wymaga wyższego poziomu kuźni
+type SmithyLevelError struct {
+	Required int
+	Current  int
+}
+
+func (e *SmithyLevelError) Error() string {
+	return fmt.Sprintf("smithy level %d required (current: %d)", e.Required, e.Current)
+}
@@ -0,0 +1,105 @@
+package game
+
+import (
+	"context"
+	"time"
+
+	"PawTribalWars/repo"
+)
+
+// Overview to stan wioski na ekran główny, policzony z jednego odczytu
+type Overview struct {
+	Village   repo.Village
+	Resources OverviewResources
+	Buildings []OverviewBuilding
+	Units     []OverviewUnit
+	Research  []repo.QueuedResearch
+}
+
+// OverviewResources to surowce doliczone do chwili odczytu
+type OverviewResources struct {
+	Wood, Clay, Iron int
+	Capacity         int  // pojemność magazynu, informacyjnie (produkcja jej nie respektuje)
+	PerHour          Cost // produkcja na godzinę, zero na urlopie
+	Paused           bool // urlop wstrzymuje produkcję
+}
+
+// OverviewBuilding to budynek z kosztem następnego poziomu
+type OverviewBuilding struct {
+	Type       string
+	Level      int
+	NextLevel  int
+	NextCost   Cost
+	Affordable bool // surowce w magazynie starczają na rozbudowę
+}
+
+// OverviewUnit to jednostki wioski: w domu i poza nią
+type OverviewUnit struct {
+	Type   string
+	Home   int
+	Abroad int
+}
+
+// Overview zbiera podgląd wioski gracza. Produkcja jest tylko doliczana do
+// odpowiedzi, bez zapisu - podgląd niczego w bazie nie zmienia.
+func (s *Service) Overview(ctx context.Context, playerID, villageID int, now time.Time) (Overview, error) {
+	snap, err := s.store.Villages.Snapshot(ctx, villageID)
+	if err == repo.ErrNotFound || (err == nil && snap.Village.UserID != playerID) {
+		return Overview{}, ErrVillageNotFound
+	}
+	if err != nil {
+		return Overview{}, err
+	}
+
+	levels := map[string]int{}
+	for _, b := range snap.Buildings {
+		levels[b.Type] = b.Level
+	}
+
+	// na urlopie produkcja stoi, a czas urlopu nie jest doliczany
+	paused := snap.Vacation.Active(now)
+	res := OverviewResources{
+		Wood:     snap.Resources.Wood,
+		Clay:     snap.Resources.Clay,
+		Iron:     snap.Resources.Iron,
+		Capacity: StorageCapacity(levels["warehouse"]),
+		Paused:   paused,
+	}
+	productive, _ := productiveTime(snap.Resources.UpdatedAt, now, snap.Vacation)
+	if elapsedMinutes := int(productive.Minutes()); elapsedMinutes > 0 {
+		res.Wood += Production(levels["lumbermill"], elapsedMinutes, s.cfg.Speed)
+		res.Clay += Production(levels["claypit"], elapsedMinutes, s.cfg.Speed)
+		res.Iron += Production(levels["ironmine"], elapsedMinutes, s.cfg.Speed)
+	}
+	if !paused {
+		res.PerHour = Cost{
+			Wood: Production(levels["lumbermill"], 60, s.cfg.Speed),
+			Clay: Production(levels["claypit"], 60, s.cfg.Speed),
+			Iron: Production(levels["ironmine"], 60, s.cfg.Speed),
+		}
+	}
+
+	overview := Overview{
+		Village:   snap.Village,
+		Resources: res,
+		Buildings: []OverviewBuilding{},
+		Units:     []OverviewUnit{},
+		Research:  []repo.QueuedResearch{},
+	}
+	for _, b := range snap.Buildings {
+		cost := UpgradeCost(b.Type, b.Level+1)
+		overview.Buildings = append(overview.Buildings, OverviewBuilding{
+			Type:       b.Type,
+			Level:      b.Level,
+			NextLevel:  b.Level + 1,
+			NextCost:   cost,
+			Affordable: res.Wood >= cost.Wood && res.Clay >= cost.Clay && res.Iron >= cost.Iron,
+		})
+	}
+	// nie ma jeszcze ruchów wojsk, więc wszystkie jednostki są w domu
+	for _, u := range snap.Units {
+		overview.Units = append(overview.Units, OverviewUnit{Type: u.Type, Home: u.Count})
+	}
+	overview.Research = append(overview.Research, snap.Research...)
+	return overview, nil
+}
@@ -0,0 +1,51 @@
+package game
+
+import "math"
+
+// MaxBuildingLevel to maksymalny poziom budynku uwzględniany w tabelach punktów
+const MaxBuildingLevel = 30
+
+// punkty za poziom 1 każdego budynku
+var buildingBasePoints = map[string]int{
+	"townhall":   10,
+	"lumbermill": 6,
+	"claypit":    6,
+	"ironmine":   6,
+	"warehouse":  6,
+	"barracks":   16,
+	"smithy":     19,
+}
+
+// buildingPoints[typ][poziom] = łączne punkty budynku na danym poziomie
+var buildingPoints = buildPointTables()
+
+// każdy kolejny poziom daje 20% więcej punktów niż poprzedni
+func buildPointTables() map[string][]int {
+	tables := map[string][]int{}
+	for bType, base := range buildingBasePoints {
+		table := make([]int, MaxBuildingLevel+1)
+		for level := 1; level <= MaxBuildingLevel; level++ {
+			table[level] = table[level-1] + int(math.Round(float64(base)*math.Pow(1.2, float64(level-1))))
+		}
+		tables[bType] = table
+	}
+	return tables
+}
+
+// IsBuilding mówi, czy typ budynku istnieje w grze
+func IsBuilding(buildingType string) bool {
+	_, ok := buildingBasePoints[buildingType]
+	return ok
+}
+
+// BuildingPoints to punkty budynku na danym poziomie
+func BuildingPoints(buildingType string, level int) int {
+	table, ok := buildingPoints[buildingType]
+	if !ok || level <= 0 {
+		return 0
+	}
+	if level > MaxBuildingLevel {
+		level = MaxBuildingLevel
+	}
+	return table[level]
+}
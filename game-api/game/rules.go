@@ -0,0 +1,157 @@
+// Package game zawiera reguły gry niezależne od transportu: koszty, badania, produkcję,
+// limity wiosek i zestawy startowe oraz Service, który wykonuje na nich
+// polecenia graczy. Z tego samego kodu korzystają HTTP, CLI i scheduler.
+package game
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"PawTribalWars/repo"
+)
+
+// koszt w surowcach (budynki, jednostki, badania)
+type Cost struct {
+	Wood int `json:"wood"`
+	Clay int `json:"clay"`
+	Iron int `json:"iron"`
+}
+
+// Times zwraca koszt n sztuk
+func (c Cost) Times(n int) Cost {
+	return Cost{Wood: c.Wood * n, Clay: c.Clay * n, Iron: c.Iron * n}
+}
+
+// bazowe koszty budynków (dla level 1)
+var baseCosts = map[string]Cost{
+	"lumbermill": {Wood: 50, Clay: 50, Iron: 20},
+	"claypit":    {Wood: 50, Clay: 50, Iron: 20},
+	"ironmine":   {Wood: 50, Clay: 50, Iron: 20},
+	"warehouse":  {Wood: 100, Clay: 60, Iron: 40},
+	"barracks":   {Wood: 120, Clay: 100, Iron: 80},
+	"smithy":     {Wood: 220, Clay: 180, Iron: 240},
+}
+
+// UpgradeCost to koszt rozbudowy budynku do poziomu nextLevel (x2.5 za poziom)
+func UpgradeCost(buildingType string, nextLevel int) Cost {
+	base := baseCosts[buildingType]
+	multiplier := math.Pow(2.5, float64(nextLevel-1))
+	return Cost{
+		Wood: int(float64(base.Wood) * multiplier),
+		Clay: int(float64(base.Clay) * multiplier),
+		Iron: int(float64(base.Iron) * multiplier),
+	}
+}
+
+// koszty rekrutacji jednej jednostki
+var unitCosts = map[string]Cost{
+	"spearman":  {Wood: 50, Clay: 30, Iron: 20},
+	"swordsman": {Wood: 30, Clay: 50, Iron: 40},
+	"archer":    {Wood: 40, Clay: 40, Iron: 30},
+}
+
+// UnitCost zwraca koszt jednej jednostki; ok=false dla nieznanego typu
+func UnitCost(unitType string) (cost Cost, ok bool) {
+	cost, ok = unitCosts[unitType]
+	return cost, ok
+}
+
+// ResearchRule to wymagania i koszty badania jednostki w kuźni (dla poziomu 1)
+type ResearchRule struct {
+	SmithyLevel int  // wymagany poziom kuźni
+	Cost        Cost // koszt badania
+	Duration    int  // czas badania w sekundach
+}
+
+var researchRules = map[string]ResearchRule{
+	"spearman":  {SmithyLevel: 1, Cost: Cost{Wood: 80, Clay: 60, Iron: 40}, Duration: 120},
+	"swordsman": {SmithyLevel: 2, Cost: Cost{Wood: 150, Clay: 120, Iron: 180}, Duration: 300},
+	"archer":    {SmithyLevel: 3, Cost: Cost{Wood: 200, Clay: 160, Iron: 160}, Duration: 420},
+}
+
+// ResearchTypes zwraca jednostki badane w kuźni, alfabetycznie
+func ResearchTypes() []string {
+	types := make([]string, 0, len(researchRules))
+	for unitType := range researchRules {
+		types = append(types, unitType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// RequiredSmithyLevel to poziom kuźni potrzebny do badania danego poziomu:
+// każdy kolejny poziom badania wymaga poziomu kuźni wyżej
+func RequiredSmithyLevel(unitType string, level int) int {
+	return researchRules[unitType].SmithyLevel + level - 1
+}
+
+// ResearchCost to koszt badania danego poziomu (x1.8 za każdy poziom)
+func ResearchCost(unitType string, level int) Cost {
+	base := researchRules[unitType].Cost
+	multiplier := math.Pow(1.8, float64(level-1))
+	return Cost{
+		Wood: int(float64(base.Wood) * multiplier),
+		Clay: int(float64(base.Clay) * multiplier),
+		Iron: int(float64(base.Iron) * multiplier),
+	}
+}
+
+// ResearchDuration to czas badania: rośnie z poziomem, każdy poziom kuźni
+// skraca go o 5%, a szybszy świat dzieli go przez speed
+func ResearchDuration(unitType string, level, smithyLevel int, speed float64) time.Duration {
+	base := float64(researchRules[unitType].Duration * level)
+	factor := math.Pow(0.95, float64(smithyLevel-1)) / speed
+	return time.Duration(base*factor) * time.Second
+}
+
+// Production to przyrost surowca z kopalni danego poziomu w ciągu minutes minut
+func Production(level, minutes int, speed float64) int {
+	return int(float64(level*5*minutes) * speed)
+}
+
+// StorageCapacity to pojemność magazynu na każdy surowiec (x2.5 za poziom, jak koszty).
+// Na razie tylko informacyjnie - produkcja nie jest nią ograniczana.
+func StorageCapacity(warehouseLevel int) int {
+	if warehouseLevel < 1 {
+		warehouseLevel = 1
+	}
+	return int(1000 * math.Pow(2.5, float64(warehouseLevel-1)))
+}
+
+// MaxVillages to limit wiosek gracza: jedna więcej co 10 poziomów ratusza
+func MaxVillages(maxTownhallLevel int) int {
+	return maxTownhallLevel/10 + 1
+}
+
+// Kit opisuje stan nowo założonej wioski
+type Kit struct {
+	Resources     Cost
+	Buildings     []string
+	BuildingLevel int
+	Units         []repo.Unit
+	Research      map[string]int
+}
+
+// StarterVillageName to nazwa wioski zakładanej przy rejestracji
+const StarterVillageName = "Startowa wioska"
+
+var startBuildings = []string{"townhall", "lumbermill", "claypit", "ironmine", "warehouse", "barracks", "smithy"}
+
+// StarterKit dostaje pierwsza wioska przy rejestracji
+var StarterKit = Kit{
+	Resources:     Cost{Wood: 100, Clay: 100, Iron: 100},
+	Buildings:     startBuildings,
+	BuildingLevel: 1,
+	Units:         []repo.Unit{{Type: "spearman", Count: 5}},
+	Research:      map[string]int{"spearman": 1},
+}
+
+// VillageKit dostaje każda kolejna wioska
+var VillageKit = Kit{
+	Resources:     Cost{Wood: 100, Clay: 100, Iron: 100},
+	Buildings:     startBuildings,
+	BuildingLevel: 1,
+	Units:         []repo.Unit{{Type: "spearman"}, {Type: "swordsman"}, {Type: "archer"}},
+	Research:      map[string]int{"spearman": 1},
+}
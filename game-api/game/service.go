@@ -0,0 +1,687 @@
+package game
+
+import (
+	"context"
+	"time"
+
+	"PawTribalWars/battle"
+	"PawTribalWars/events"
+	"PawTribalWars/repo"
+)
+
+// JobCompleteResearch to zdarzenie schedulera kończące badania w kuźni wioski
+const JobCompleteResearch = "research_complete"
+
+// ResearchJob to payload JobCompleteResearch
+type ResearchJob struct {
+	VillageID int `json:"village_id"`
+}
+
+// Config to ustawienia świata potrzebne regułom gry
+type Config struct {
+	Speed                   float64       // mnożnik produkcji i badań
+	BeginnerProtection      time.Duration // ochrona nowego gracza od rejestracji
+	BeginnerPointsThreshold int           // po tylu punktach kończy się ochrona początkujących
+}
+
+// Service wykonuje polecenia graczy na repozytoriach
+type Service struct {
+	store repo.Store
+	cfg   Config
+}
+
+func NewService(store repo.Store, cfg Config) *Service {
+	return &Service{store: store, cfg: cfg}
+}
+
+// WithStore zwraca serwis z tą samą konfiguracją na innych repozytoriach,
+// np. na transakcji, w której działa już wywołujący
+func (s *Service) WithStore(store repo.Store) *Service {
+	return &Service{store: store, cfg: s.cfg}
+}
+
+// FoundResult opisuje nowo założoną wioskę
+type FoundResult struct {
+	Village repo.Village
+	Max     int // limit wiosek gracza
+	Current int // liczba wiosek po założeniu
+}
+
+// Upgrade opisuje (planowaną albo wykonaną) rozbudowę budynku
+type Upgrade struct {
+	Type      string
+	Level     int // poziom przed rozbudową
+	NextLevel int
+	Cost      Cost
+}
+
+// Recruitment opisuje wykonaną rekrutację
+type Recruitment struct {
+	Type  string
+	Count int
+	Cost  Cost
+}
+
+// Registration opisuje konto założone przy rejestracji
+type Registration struct {
+	User    repo.User
+	Village repo.Village
+}
+
+// ResearchStatus to stan badania jednej jednostki w kuźni
+type ResearchStatus struct {
+	Type          string
+	Level         int
+	RequiredLevel int // poziom kuźni wymagany dla poziomu 1
+	// koszt i czas kolejnego poziomu; MaxLevel = nie ma kolejnego
+	MaxLevel     bool
+	NextCost     Cost
+	NextDuration time.Duration
+}
+
+// Smithy to kuźnia wioski: poziomy badań i kolejka
+type Smithy struct {
+	Level    int // poziom budynku
+	Research []ResearchStatus
+	Queue    []repo.QueuedResearch
+}
+
+// ResearchOrder opisuje rozpoczęte badanie
+type ResearchOrder struct {
+	Type       string
+	Level      int
+	Cost       Cost
+	FinishesAt time.Time
+}
+
+// wioska gracza; cudza i nieistniejąca dają ten sam błąd
+func (s *Service) ownedVillage(ctx context.Context, st repo.Store, playerID, villageID int) (repo.Village, error) {
+	village, err := st.Villages.ByID(ctx, villageID)
+	if err == repo.ErrNotFound || (err == nil && village.UserID != playerID) {
+		return repo.Village{}, ErrVillageNotFound
+	}
+	return village, err
+}
+
+// ===== Rejestracja =====
+
+// RegisterPlayer zakłada konto z pierwszą wioską i zestawem startowym w jednej
+// transakcji - nieudana rejestracja nie zostawia konta bez wioski
+func (s *Service) RegisterPlayer(ctx context.Context, username, email, passwordHash string, now time.Time) (Registration, error) {
+	var reg Registration
+	err := s.store.Tx.InTx(ctx, func(st repo.Store) error {
+		user, err := st.Users.Create(ctx, repo.NewUser{
+			Username:       username,
+			Email:          email,
+			PasswordHash:   passwordHash,
+			ProtectedUntil: now.Add(s.cfg.BeginnerProtection),
+		})
+		if err == repo.ErrDuplicate {
+			return ErrPlayerExists
+		}
+		if err != nil {
+			return err
+		}
+		village, err := st.Villages.Create(ctx, user.ID, StarterVillageName)
+		if err != nil {
+			return err
+		}
+		if err := applyKit(ctx, st, village.ID, StarterKit); err != nil {
+			return err
+		}
+		// punkty za startowe budynki
+		if err := s.recalculatePoints(ctx, st, village.ID); err != nil {
+			return err
+		}
+		reg = Registration{User: user, Village: village}
+		return nil
+	})
+	return reg, err
+}
+
+// ===== Wioski =====
+
+func (s *Service) Villages(ctx context.Context, playerID int) ([]repo.Village, error) {
+	return s.store.Villages.ListByUser(ctx, playerID)
+}
+
+// FoundVillage zakłada kolejną wioskę, jeśli pozwala na to najwyższy ratusz gracza
+func (s *Service) FoundVillage(ctx context.Context, playerID int, name string) (FoundResult, error) {
+	if name == "" {
+		return FoundResult{}, ErrMissingName
+	}
+	var result FoundResult
+	err := s.store.Tx.InTx(ctx, func(st repo.Store) error {
+		count, err := st.Villages.CountByUser(ctx, playerID)
+		if err != nil {
+			return err
+		}
+		maxTownhall, err := st.Buildings.MaxLevelForUser(ctx, playerID, "townhall")
+		if err != nil {
+			return err
+		}
+		max := MaxVillages(maxTownhall)
+		if count >= max {
+			return &VillageLimitError{Max: max, Current: count}
+		}
+
+		village, err := st.Villages.Create(ctx, playerID, name)
+		if err != nil {
+			return err
+		}
+		if err := applyKit(ctx, st, village.ID, VillageKit); err != nil {
+			return err
+		}
+		// punkty za startowe budynki
+		if err := s.recalculatePoints(ctx, st, village.ID); err != nil {
+			return err
+		}
+		result = FoundResult{Village: village, Max: max, Current: count + 1}
+		return nil
+	})
+	return result, err
+}
+
+// zakłada surowce, budynki, jednostki i badania nowej wioski
+func applyKit(ctx context.Context, st repo.Store, villageID int, kit Kit) error {
+	if err := st.Resources.Init(ctx, villageID, kit.Resources.Wood, kit.Resources.Clay, kit.Resources.Iron); err != nil {
+		return err
+	}
+	if err := st.Buildings.Init(ctx, villageID, kit.Buildings, kit.BuildingLevel); err != nil {
+		return err
+	}
+	types := make([]string, 0, len(kit.Units))
+	for _, u := range kit.Units {
+		types = append(types, u.Type)
+	}
+	if err := st.Units.Init(ctx, villageID, types); err != nil {
+		return err
+	}
+	for _, u := range kit.Units {
+		if u.Count > 0 {
+			if err := st.Units.Add(ctx, villageID, u.Type, u.Count); err != nil {
+				return err
+			}
+		}
+	}
+	for unitType, level := range kit.Research {
+		if err := st.Research.Set(ctx, villageID, unitType, level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) RenameVillage(ctx context.Context, playerID, villageID int, name string) error {
+	if _, err := s.ownedVillage(ctx, s.store, playerID, villageID); err != nil {
+		return err
+	}
+	return s.store.Villages.Rename(ctx, villageID, name)
+}
+
+// AbandonVillage usuwa wioskę; gracz traci jej punkty
+func (s *Service) AbandonVillage(ctx context.Context, playerID, villageID int) error {
+	return s.store.Tx.InTx(ctx, func(st repo.Store) error {
+		if _, err := s.ownedVillage(ctx, st, playerID, villageID); err != nil {
+			return err
+		}
+		if err := st.Villages.Delete(ctx, villageID); err != nil {
+			return err
+		}
+		return s.recalculatePlayerPoints(ctx, st, playerID)
+	})
+}
+
+// ===== Surowce =====
+
+// Resources dolicza produkcję do now i zwraca stan magazynu
+func (s *Service) Resources(ctx context.Context, villageID int, now time.Time) (repo.Resources, error) {
+	var res repo.Resources
+	err := s.store.Tx.InTx(ctx, func(st repo.Store) error {
+		village, err := st.Villages.ByID(ctx, villageID)
+		if err != nil {
+			return err
+		}
+		res, err = s.settle(ctx, st, village, now)
+		return err
+	})
+	if err == repo.ErrNotFound {
+		return res, ErrVillageNotFound
+	}
+	return res, err
+}
+
+// rozlicza produkcję wioski do now z pominięciem nierozliczonego urlopu właściciela
+func (s *Service) settle(ctx context.Context, st repo.Store, village repo.Village, now time.Time) (repo.Resources, error) {
+	var vacation *repo.Vacation
+	if village.UserID != 0 {
+		var err error
+		if vacation, err = st.Users.Vacation(ctx, village.UserID); err != nil {
+			return repo.Resources{}, err
+		}
+	}
+	return s.settleResources(ctx, st, village.ID, vacation, now)
+}
+
+// dolicza produkcję od ostatniego zapisu do now; czas urlopu się nie liczy.
+// updated_at nigdy się nie cofa, a przesuwa się także wtedy, gdy cały okres
+// przypadł na urlop - po jego rozliczeniu urlop nie będzie już znany.
+// Wiersz surowców zostaje zablokowany do końca transakcji st.
+func (s *Service) settleResources(ctx context.Context, st repo.Store, villageID int, vacation *repo.Vacation, now time.Time) (repo.Resources, error) {
+	res, err := st.Resources.Lock(ctx, villageID)
+	if err != nil {
+		return res, err
+	}
+
+	productive, paused := productiveTime(res.UpdatedAt, now, vacation)
+	elapsedMinutes := int(productive.Minutes())
+	if elapsedMinutes > 0 {
+		for bType, amount := range map[string]*int{"lumbermill": &res.Wood, "claypit": &res.Clay, "ironmine": &res.Iron} {
+			level, err := st.Buildings.Level(ctx, villageID, bType)
+			if err != nil && err != repo.ErrNotFound {
+				return res, err
+			}
+			*amount += Production(level, elapsedMinutes, s.cfg.Speed)
+		}
+	}
+	if elapsedMinutes > 0 || paused > 0 {
+		res.UpdatedAt = now
+		err = st.Resources.Save(ctx, res)
+	}
+	return res, err
+}
+
+// dzieli okres [from, now] na czas produkcji i czas urlopu
+func productiveTime(from, now time.Time, vacation *repo.Vacation) (productive, paused time.Duration) {
+	if !now.After(from) {
+		return 0, 0
+	}
+	if vacation != nil {
+		start, end := vacation.StartsAt, vacation.EndsAt
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(now) {
+			end = now
+		}
+		if end.After(start) {
+			paused = end.Sub(start)
+		}
+	}
+	return now.Sub(from) - paused, paused
+}
+
+// SettleVacation rozlicza surowce wiosek gracza po urlopie: produkcja do now
+// bez czasu urlopu. Wywoływane przed oznaczeniem urlopu jako rozliczonego.
+func (s *Service) SettleVacation(ctx context.Context, playerID int, vacation repo.Vacation, now time.Time) error {
+	villages, err := s.store.Villages.ListByUser(ctx, playerID)
+	if err != nil {
+		return err
+	}
+	for _, village := range villages {
+		err := s.store.Tx.InTx(ctx, func(st repo.Store) error {
+			_, err := s.settleResources(ctx, st, village.ID, &vacation, now)
+			return err
+		})
+		if err != nil && err != repo.ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// rozlicza produkcję i odejmuje koszt albo zwraca NotEnoughResourcesError;
+// st musi być transakcją, żeby rozliczenie i wydatek nie rozjechały się z innym żądaniem
+func (s *Service) spend(ctx context.Context, st repo.Store, village repo.Village, cost Cost, now time.Time) error {
+	res, err := s.settle(ctx, st, village, now)
+	if err != nil {
+		return err
+	}
+	available := Cost{Wood: res.Wood, Clay: res.Clay, Iron: res.Iron}
+	if available.Wood < cost.Wood || available.Clay < cost.Clay || available.Iron < cost.Iron {
+		return &NotEnoughResourcesError{Cost: cost, Available: available}
+	}
+	err = st.Resources.Spend(ctx, village.ID, cost.Wood, cost.Clay, cost.Iron)
+	if err == repo.ErrNotEnoughRes {
+		return &NotEnoughResourcesError{Cost: cost, Available: available}
+	}
+	return err
+}
+
+// ===== Budynki =====
+
+func (s *Service) Buildings(ctx context.Context, villageID int) ([]repo.Building, error) {
+	return s.store.Buildings.List(ctx, villageID)
+}
+
+// UpgradeQuote zwraca koszt rozbudowy budynku o jeden poziom
+func (s *Service) UpgradeQuote(ctx context.Context, villageID int, buildingType string) (Upgrade, error) {
+	return upgradeQuote(ctx, s.store, villageID, buildingType)
+}
+
+func upgradeQuote(ctx context.Context, st repo.Store, villageID int, buildingType string) (Upgrade, error) {
+	level, err := st.Buildings.Level(ctx, villageID, buildingType)
+	if err == repo.ErrNotFound {
+		return Upgrade{}, ErrBuildingNotFound
+	}
+	if err != nil {
+		return Upgrade{}, err
+	}
+	return Upgrade{
+		Type:      buildingType,
+		Level:     level,
+		NextLevel: level + 1,
+		Cost:      UpgradeCost(buildingType, level+1),
+	}, nil
+}
+
+// UpgradeBuilding rozbudowuje budynek w wiosce gracza o jeden poziom
+func (s *Service) UpgradeBuilding(ctx context.Context, playerID, villageID int, buildingType string, now time.Time) (Upgrade, error) {
+	var upgrade Upgrade
+	err := s.store.Tx.InTx(ctx, func(st repo.Store) error {
+		village, err := s.ownedVillage(ctx, st, playerID, villageID)
+		if err != nil {
+			return err
+		}
+		upgrade, err = upgradeQuote(ctx, st, villageID, buildingType)
+		if err != nil {
+			return err
+		}
+		if err := s.spend(ctx, st, village, upgrade.Cost, now); err != nil {
+			return err
+		}
+		// równoległa rozbudowa zdążyła zmienić poziom - cała transakcja się wycofuje
+		if err := st.Buildings.LevelUp(ctx, villageID, buildingType, upgrade.Level); err != nil {
+			return err
+		}
+		return s.recalculatePoints(ctx, st, villageID)
+	})
+	if err == repo.ErrConflict {
+		return Upgrade{}, ErrConcurrentUpdate
+	}
+	if err != nil {
+		return Upgrade{}, err
+	}
+
+	events.Publish(playerID, events.BuildingFinished, map[string]interface{}{
+		"village_id":    villageID,
+		"building_type": buildingType,
+		"level":         upgrade.NextLevel,
+	})
+	s.publishResourceSnapshot(ctx, playerID, villageID)
+	return upgrade, nil
+}
+
+// SetBuildingLevel ustawia poziom budynku z pominięciem kosztów i kolejki
+// (panel admina) i przelicza punkty wioski oraz jej właściciela
+func (s *Service) SetBuildingLevel(ctx context.Context, villageID int, buildingType string, level int) error {
+	return s.store.Tx.InTx(ctx, func(st repo.Store) error {
+		err := st.Buildings.Set(ctx, villageID, buildingType, level)
+		if err == repo.ErrNotFound {
+			return ErrBuildingNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return s.recalculatePoints(ctx, st, villageID)
+	})
+}
+
+// ===== Jednostki =====
+
+func (s *Service) Units(ctx context.Context, villageID int) ([]repo.Unit, error) {
+	return s.store.Units.List(ctx, villageID)
+}
+
+// RecruitUnits szkoli jednostki zbadane w kuźni wioski
+func (s *Service) RecruitUnits(ctx context.Context, playerID, villageID int, unitType string, count int, now time.Time) (Recruitment, error) {
+	var cost Cost
+	err := s.store.Tx.InTx(ctx, func(st repo.Store) error {
+		village, err := s.ownedVillage(ctx, st, playerID, villageID)
+		if err != nil {
+			return err
+		}
+		unitCost, ok := UnitCost(unitType)
+		if !ok {
+			return ErrUnknownUnit
+		}
+		if count < 1 {
+			return ErrInvalidCount
+		}
+		cost = unitCost.Times(count)
+
+		// jednostka musi być zbadana w kuźni
+		if err := st.Research.CompleteDue(ctx, villageID); err != nil {
+			return err
+		}
+		level, err := st.Research.Level(ctx, villageID, unitType)
+		if err != nil {
+			return err
+		}
+		if level < 1 {
+			return ErrNotResearched
+		}
+		if err := s.spend(ctx, st, village, cost, now); err != nil {
+			return err
+		}
+		return st.Units.Add(ctx, villageID, unitType, count)
+	})
+	if err != nil {
+		return Recruitment{}, err
+	}
+
+	events.Publish(playerID, events.TroopsTrained, map[string]interface{}{
+		"village_id": villageID,
+		"type":       unitType,
+		"count":      count,
+	})
+	s.publishResourceSnapshot(ctx, playerID, villageID)
+	return Recruitment{Type: unitType, Count: count, Cost: cost}, nil
+}
+
+// ===== Kuźnia =====
+
+// Smithy zwraca poziomy badań wioski gracza z kosztem kolejnych poziomów
+func (s *Service) Smithy(ctx context.Context, playerID, villageID int) (Smithy, error) {
+	if _, err := s.ownedVillage(ctx, s.store, playerID, villageID); err != nil {
+		return Smithy{}, err
+	}
+	if err := s.store.Research.CompleteDue(ctx, villageID); err != nil {
+		return Smithy{}, err
+	}
+	smithyLevel, err := s.store.Buildings.Level(ctx, villageID, "smithy")
+	if err != nil && err != repo.ErrNotFound {
+		return Smithy{}, err
+	}
+	levels, err := s.store.Research.Levels(ctx, villageID)
+	if err != nil {
+		return Smithy{}, err
+	}
+	queue, err := s.store.Research.Queue(ctx, villageID)
+	if err != nil {
+		return Smithy{}, err
+	}
+
+	smithy := Smithy{Level: smithyLevel, Queue: queue}
+	for _, unitType := range ResearchTypes() {
+		status := ResearchStatus{
+			Type:          unitType,
+			Level:         levels[unitType],
+			RequiredLevel: RequiredSmithyLevel(unitType, 1),
+			MaxLevel:      levels[unitType] >= battle.MaxResearchLevel,
+		}
+		if !status.MaxLevel {
+			status.NextCost = ResearchCost(unitType, status.Level+1)
+			status.NextDuration = ResearchDuration(unitType, status.Level+1, smithyLevel, s.cfg.Speed)
+		}
+		smithy.Research = append(smithy.Research, status)
+	}
+	return smithy, nil
+}
+
+// StartResearch zleca w kuźni badanie kolejnego poziomu jednostki
+func (s *Service) StartResearch(ctx context.Context, playerID, villageID int, unitType string, now time.Time) (ResearchOrder, error) {
+	if _, ok := researchRules[unitType]; !ok {
+		return ResearchOrder{}, ErrUnknownUnit
+	}
+	var order ResearchOrder
+	err := s.store.Tx.InTx(ctx, func(st repo.Store) error {
+		village, err := s.ownedVillage(ctx, st, playerID, villageID)
+		if err != nil {
+			return err
+		}
+		// blokada surowców wioski szereguje równoległe zlecenia badań, więc
+		// sprawdzenie kolejki i pobranie kosztu nie rozjadą się z innym poleceniem
+		if _, err := st.Resources.Lock(ctx, villageID); err != nil {
+			return err
+		}
+		if err := st.Research.CompleteDue(ctx, villageID); err != nil {
+			return err
+		}
+		queue, err := st.Research.Queue(ctx, villageID)
+		if err != nil {
+			return err
+		}
+		if len(queue) > 0 {
+			return ErrResearchInProgress
+		}
+
+		level, err := st.Research.Level(ctx, villageID, unitType)
+		if err != nil {
+			return err
+		}
+		if level >= battle.MaxResearchLevel {
+			return ErrResearchMaxLevel
+		}
+		smithyLevel, err := st.Buildings.Level(ctx, villageID, "smithy")
+		if err == repo.ErrNotFound {
+			return ErrBuildingNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if required := RequiredSmithyLevel(unitType, level+1); smithyLevel < required {
+			return &SmithyLevelError{Required: required, Current: smithyLevel}
+		}
+
+		order = ResearchOrder{
+			Type:       unitType,
+			Level:      level + 1,
+			Cost:       ResearchCost(unitType, level+1),
+			FinishesAt: now.Add(ResearchDuration(unitType, level+1, smithyLevel, s.cfg.Speed)),
+		}
+		if err := s.spend(ctx, st, village, order.Cost, now); err != nil {
+			return err
+		}
+		err = st.Research.Enqueue(ctx, villageID, repo.QueuedResearch{
+			UnitType:   unitType,
+			Level:      order.Level,
+			FinishesAt: order.FinishesAt,
+		})
+		if err != nil {
+			return err
+		}
+		// scheduler zakończy badanie, nawet jeśli nikt nie zajrzy do kuźni
+		return st.Jobs.Schedule(ctx, JobCompleteResearch, ResearchJob{VillageID: villageID}, order.FinishesAt)
+	})
+	if err != nil {
+		return ResearchOrder{}, err
+	}
+
+	s.publishResourceSnapshot(ctx, playerID, villageID)
+	return order, nil
+}
+
+// CompleteResearch kończy badania wioski, których czas minął (zdarzenie schedulera)
+func (s *Service) CompleteResearch(ctx context.Context, villageID int) error {
+	return s.store.Research.CompleteDue(ctx, villageID)
+}
+
+// ===== Ataki =====
+
+// CheckAttack mówi, czy gracz może wysłać atak na wioskę (nil = może). Gra nie
+// ma jeszcze rozkazu ataku - to strażnik, przez który ten rozkaz ma przejść.
+// Chroniony początkujący może atakować, ale wysłany atak kończy jego ochronę
+// (Users.EndBeginnerProtection w transakcji rozkazu).
+func (s *Service) CheckAttack(ctx context.Context, attackerID, targetVillageID int, now time.Time) error {
+	target, err := s.store.Villages.ByID(ctx, targetVillageID)
+	if err == repo.ErrNotFound {
+		return ErrTargetNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if target.UserID == 0 {
+		return nil // wioska barbarzyńska
+	}
+	vacation, err := s.store.Users.Vacation(ctx, target.UserID)
+	if err != nil {
+		return err
+	}
+	if vacation.Active(now) {
+		return ErrTargetOnVacation
+	}
+	protectedUntil, err := s.store.Users.ProtectedUntil(ctx, target.UserID)
+	if err != nil {
+		return err
+	}
+	if protectedUntil != nil && protectedUntil.After(now) {
+		return ErrTargetProtected
+	}
+	return nil
+}
+
+// ===== Punkty i powiadomienia =====
+
+// przelicza punkty wioski i jej właściciela
+func (s *Service) recalculatePoints(ctx context.Context, st repo.Store, villageID int) error {
+	buildings, err := st.Buildings.List(ctx, villageID)
+	if err != nil {
+		return err
+	}
+	points := 0
+	for _, b := range buildings {
+		points += BuildingPoints(b.Type, b.Level)
+	}
+	if err := st.Villages.SetPoints(ctx, villageID, points); err != nil {
+		return err
+	}
+
+	village, err := st.Villages.ByID(ctx, villageID)
+	if err != nil {
+		return err
+	}
+	if village.UserID == 0 {
+		return nil // wioska barbarzyńska
+	}
+	return s.recalculatePlayerPoints(ctx, st, village.UserID)
+}
+
+// suma punktów wiosek gracza; po przekroczeniu progu kończy się ochrona początkujących
+func (s *Service) recalculatePlayerPoints(ctx context.Context, st repo.Store, playerID int) error {
+	total, err := st.Villages.SumPointsByUser(ctx, playerID)
+	if err != nil {
+		return err
+	}
+	if err := st.Users.SetPoints(ctx, playerID, total); err != nil {
+		return err
+	}
+	if total >= s.cfg.BeginnerPointsThreshold {
+		return st.Users.EndBeginnerProtection(ctx, playerID)
+	}
+	return nil
+}
+
+// wysyła graczowi aktualny stan surowców wioski
+func (s *Service) publishResourceSnapshot(ctx context.Context, playerID, villageID int) {
+	res, err := s.store.Resources.Get(ctx, villageID)
+	if err != nil {
+		return
+	}
+	events.Publish(playerID, events.ResourceSnapshot, map[string]interface{}{
+		"village_id": villageID,
+		"wood":       res.Wood,
+		"clay":       res.Clay,
+		"iron":       res.Iron,
+	})
+}
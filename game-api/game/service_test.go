@@ -0,0 +1,285 @@
+package game_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"PawTribalWars/game"
+	"PawTribalWars/repo"
+	"PawTribalWars/repo/memory"
+)
+
+func newVillage(t *testing.T) (*memory.DB, *game.Service, int, int) {
+	t.Helper()
+	m := memory.New()
+	user := m.AddUser("tester", "tester@example.com", "player")
+	svc := game.NewService(m.Store(), game.Config{Speed: 1, BeginnerPointsThreshold: 1000})
+	found, err := svc.FoundVillage(context.Background(), user.ID, "Test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, svc, user.ID, found.Village.ID
+}
+
+func TestConcurrentUpgradesEachPayAndLevelUp(t *testing.T) {
+	m, svc, userID, villageID := newVillage(t)
+	ctx := context.Background()
+	store := m.Store()
+	res, _ := store.Resources.Get(ctx, villageID)
+	res.Wood, res.Clay, res.Iron = 100000, 100000, 100000
+	store.Resources.Save(ctx, res)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var paid game.Cost
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			upgrade, err := svc.UpgradeBuilding(ctx, userID, villageID, "lumbermill", time.Now())
+			if err != nil && !errors.Is(err, game.ErrConcurrentUpdate) {
+				t.Error(err)
+				return
+			}
+			if err == nil {
+				mu.Lock()
+				paid.Wood += upgrade.Cost.Wood
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	level, _ := store.Buildings.Level(ctx, villageID, "lumbermill")
+	want := 0
+	for l := 2; l <= level; l++ {
+		want += game.UpgradeCost("lumbermill", l).Wood
+	}
+	if paid.Wood != want {
+		t.Fatalf("level %d: paid %d wood, levels cost %d", level, paid.Wood, want)
+	}
+	after, _ := store.Resources.Get(ctx, villageID)
+	if after.Wood > 100000-want {
+		t.Fatalf("wood %d after paying %d from 100000", after.Wood, want)
+	}
+}
+
+func TestFailedUpgradeLeavesVillageUnchanged(t *testing.T) {
+	m, svc, userID, villageID := newVillage(t)
+	ctx := context.Background()
+	store := m.Store()
+	res, _ := store.Resources.Get(ctx, villageID)
+	res.Wood, res.Clay, res.Iron = 0, 0, 0
+	store.Resources.Save(ctx, res)
+
+	_, err := svc.UpgradeBuilding(ctx, userID, villageID, "smithy", time.Now())
+	var notEnough *game.NotEnoughResourcesError
+	if !errors.As(err, &notEnough) {
+		t.Fatalf("got %v, want NotEnoughResourcesError", err)
+	}
+	if level, _ := store.Buildings.Level(ctx, villageID, "smithy"); level != 1 {
+		t.Fatalf("smithy level %d after failed upgrade", level)
+	}
+}
+
+func TestOtherPlayersVillageIsNotFound(t *testing.T) {
+	m, svc, _, villageID := newVillage(t)
+	other := m.AddUser("other", "other@example.com", "player")
+
+	_, err := svc.UpgradeBuilding(context.Background(), other.ID, villageID, "lumbermill", time.Now())
+	if !errors.Is(err, game.ErrVillageNotFound) {
+		t.Fatalf("got %v, want ErrVillageNotFound", err)
+	}
+}
+
+func setResources(t *testing.T, m *memory.DB, villageID, amount int, updatedAt time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	res, err := m.Store().Resources.Get(ctx, villageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Wood, res.Clay, res.Iron, res.UpdatedAt = amount, amount, amount, updatedAt
+	m.Store().Resources.Save(ctx, res)
+}
+
+func TestVacationTimeIsNotProduced(t *testing.T) {
+	m, svc, userID, villageID := newVillage(t)
+	ctx := context.Background()
+	now := time.Now()
+	setResources(t, m, villageID, 0, now.Add(-10*time.Hour))
+	// urlop od -8h do -2h już minął, ale scheduler go jeszcze nie rozliczył
+	m.SetVacation(userID, &repo.Vacation{StartsAt: now.Add(-8 * time.Hour), EndsAt: now.Add(-2 * time.Hour)})
+
+	res, err := svc.Resources(ctx, villageID, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := game.Production(1, 4*60, 1); res.Wood != want {
+		t.Fatalf("wood %d, want %d (4h of production)", res.Wood, want)
+	}
+
+	// rozliczenie urlopu niczego już nie dolicza ani nie cofa zegara
+	if err := svc.SettleVacation(ctx, userID, repo.Vacation{StartsAt: now.Add(-8 * time.Hour), EndsAt: now.Add(-2 * time.Hour)}, now); err != nil {
+		t.Fatal(err)
+	}
+	m.SetVacation(userID, nil)
+	after, _ := m.Store().Resources.Get(ctx, villageID)
+	if after.Wood != res.Wood || after.UpdatedAt.Before(now) {
+		t.Fatalf("after settlement: wood %d at %v, want %d at %v", after.Wood, after.UpdatedAt, res.Wood, now)
+	}
+}
+
+func TestSettleNeverMovesClockBack(t *testing.T) {
+	m, svc, _, villageID := newVillage(t)
+	now := time.Now()
+	setResources(t, m, villageID, 0, now)
+
+	if _, err := svc.Resources(context.Background(), villageID, now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	res, _ := m.Store().Resources.Get(context.Background(), villageID)
+	if !res.UpdatedAt.Equal(now) || res.Wood != 0 {
+		t.Fatalf("settling in the past changed resources: %+v", res)
+	}
+}
+
+func TestRegisterPlayerAppliesStarterKit(t *testing.T) {
+	m := memory.New()
+	svc := game.NewService(m.Store(), game.Config{Speed: 1, BeginnerProtection: time.Hour, BeginnerPointsThreshold: 1000})
+	ctx := context.Background()
+
+	reg, err := svc.RegisterPlayer(ctx, "anna", "anna@example.com", "hash", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reg.Village.Name != game.StarterVillageName || !m.Protected(reg.User.ID) {
+		t.Fatalf("registration: %+v, protected %v", reg, m.Protected(reg.User.ID))
+	}
+	store := m.Store()
+	units, _ := store.Units.List(ctx, reg.Village.ID)
+	if len(units) != 1 || units[0] != (repo.Unit{Type: "spearman", Count: 5}) {
+		t.Fatalf("starter units %v", units)
+	}
+	if level, _ := store.Research.Level(ctx, reg.Village.ID, "spearman"); level != 1 {
+		t.Fatalf("spearman research level %d, want 1", level)
+	}
+	if user, _ := store.Users.ByID(ctx, reg.User.ID); user.Points == 0 {
+		t.Fatal("starter buildings gave no points")
+	}
+
+	// zajęta nazwa nie zakłada ani konta, ani wioski
+	_, err = svc.RegisterPlayer(ctx, "anna", "other@example.com", "hash", time.Now())
+	if !errors.Is(err, game.ErrPlayerExists) {
+		t.Fatalf("got %v, want ErrPlayerExists", err)
+	}
+	if _, err := store.Villages.ByID(ctx, reg.Village.ID+1); err != repo.ErrNotFound {
+		t.Fatalf("failed registration left a village: %v", err)
+	}
+}
+
+func TestResearchSettlesProductionBeforeCost(t *testing.T) {
+	m, svc, userID, villageID := newVillage(t)
+	ctx := context.Background()
+	now := time.Now()
+	store := m.Store()
+	store.Buildings.LevelUp(ctx, villageID, "smithy", 1)
+	// magazyn pusty, ale przez 2h kopalnie wyprodukowały więcej, niż kosztuje badanie
+	setResources(t, m, villageID, 0, now.Add(-2*time.Hour))
+
+	order, err := svc.StartResearch(ctx, userID, villageID, "spearman", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Level != 2 || order.Cost != game.ResearchCost("spearman", 2) {
+		t.Fatalf("order %+v", order)
+	}
+	res, _ := store.Resources.Get(ctx, villageID)
+	if want := game.Production(1, 120, 1) - order.Cost.Wood; res.Wood != want {
+		t.Fatalf("wood %d, want %d", res.Wood, want)
+	}
+	jobs := m.Jobs()
+	if len(jobs) != 1 || jobs[0].Kind != game.JobCompleteResearch || !jobs[0].DueAt.Equal(order.FinishesAt) {
+		t.Fatalf("scheduled jobs %+v", jobs)
+	}
+
+	_, err = svc.StartResearch(ctx, userID, villageID, "archer", now)
+	if !errors.Is(err, game.ErrResearchInProgress) {
+		t.Fatalf("got %v, want ErrResearchInProgress", err)
+	}
+}
+
+func TestResearchRequirements(t *testing.T) {
+	m, svc, userID, villageID := newVillage(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	_, err := svc.StartResearch(ctx, userID, villageID, "swordsman", now)
+	var tooLow *game.SmithyLevelError
+	if !errors.As(err, &tooLow) || tooLow.Required != 2 || tooLow.Current != 1 {
+		t.Fatalf("got %v, want SmithyLevelError 2/1", err)
+	}
+
+	m.Store().Buildings.LevelUp(ctx, villageID, "smithy", 1)
+	setResources(t, m, villageID, 0, now)
+	_, err = svc.StartResearch(ctx, userID, villageID, "swordsman", now)
+	var notEnough *game.NotEnoughResourcesError
+	if !errors.As(err, &notEnough) || notEnough.Cost != game.ResearchCost("swordsman", 1) {
+		t.Fatalf("got %v, want NotEnoughResourcesError", err)
+	}
+	if queue, _ := m.Store().Research.Queue(ctx, villageID); len(queue) != 0 || len(m.Jobs()) != 0 {
+		t.Fatalf("failed research left queue %v, jobs %v", queue, m.Jobs())
+	}
+
+	if _, err := svc.StartResearch(ctx, userID, villageID, "catapult", now); !errors.Is(err, game.ErrUnknownUnit) {
+		t.Fatalf("got %v, want ErrUnknownUnit", err)
+	}
+}
+
+func TestAttackOnVacationingPlayerIsRefused(t *testing.T) {
+	m, svc, userID, villageID := newVillage(t)
+	ctx := context.Background()
+	attacker := m.AddUser("napastnik", "napastnik@example.com", "player")
+	now := time.Now()
+
+	if err := svc.CheckAttack(ctx, attacker.ID, villageID, now); err != nil {
+		t.Fatalf("attack before vacation: %v", err)
+	}
+	// zaplanowany urlop jeszcze nie chroni
+	m.SetVacation(userID, &repo.Vacation{StartsAt: now.Add(time.Hour), EndsAt: now.Add(25 * time.Hour)})
+	if err := svc.CheckAttack(ctx, attacker.ID, villageID, now); err != nil {
+		t.Fatalf("attack before vacation starts: %v", err)
+	}
+	m.SetVacation(userID, &repo.Vacation{StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)})
+	if err := svc.CheckAttack(ctx, attacker.ID, villageID, now); !errors.Is(err, game.ErrTargetOnVacation) {
+		t.Fatalf("attack during vacation: %v", err)
+	}
+	if err := svc.CheckAttack(ctx, attacker.ID, villageID+100, now); !errors.Is(err, game.ErrTargetNotFound) {
+		t.Fatalf("attack on missing village: %v", err)
+	}
+}
+
+func TestAttackOnProtectedBeginnerIsRefused(t *testing.T) {
+	m, svc, userID, villageID := newVillage(t)
+	ctx := context.Background()
+	attacker := m.AddUser("napastnik", "napastnik@example.com", "player")
+	now := time.Now()
+
+	m.SetProtection(userID, now.Add(time.Hour))
+	if err := svc.CheckAttack(ctx, attacker.ID, villageID, now); !errors.Is(err, game.ErrTargetProtected) {
+		t.Fatalf("attack on protected player: %v", err)
+	}
+	// ochrona kończy się z czasem albo po przekroczeniu progu punktów
+	m.SetProtection(userID, now.Add(-time.Minute))
+	if err := svc.CheckAttack(ctx, attacker.ID, villageID, now); err != nil {
+		t.Fatalf("attack after protection expired: %v", err)
+	}
+	m.SetProtection(userID, now.Add(time.Hour))
+	m.Store().Users.EndBeginnerProtection(ctx, userID)
+	if err := svc.CheckAttack(ctx, attacker.ID, villageID, now); err != nil {
+		t.Fatalf("attack after protection ended: %v", err)
+	}
+}
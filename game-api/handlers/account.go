@@ -0,0 +1,512 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+	"unicode/utf8"
+
+	"PawTribalWars/db"
+	"PawTribalWars/mailer"
+	"PawTribalWars/repo/postgres"
+	"PawTribalWars/scheduler"
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	maxProfileText = 1000
+	maxAvatarURL   = 500
+)
+
+// avatar tylko jako link http(s), bez javascript: i podobnych
+func isAvatarURLValid(raw string) bool {
+	if len(raw) > maxAvatarURL {
+		return false
+	}
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// hasło przed operacjami na koncie
+func checkPassword(userID int, password string) (bool, error) {
+	var hashed string
+	if err := db.DB.QueryRow("SELECT password_hash FROM users WHERE id=$1", userID).Scan(&hashed); err != nil {
+		return false, err
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password)) == nil, nil
+}
+
+// =============================
+// GET /me
+// =============================
+func GetMeHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var username, email, role string
+	var pendingEmail, profileText, avatarURL sql.NullString
+	var points int
+	var verified, mfaEnabled bool
+	var createdAt time.Time
+	var deletionScheduledAt, protectedUntil *time.Time
+	err := db.DB.QueryRow(`
+		SELECT username, email, pending_email, role, points, email_verified_at IS NOT NULL,
+		       totp_enabled_at IS NOT NULL, profile_text, avatar_url, created_at, deletion_scheduled_at,
+		       CASE WHEN protected_until > NOW() THEN protected_until END
+		FROM users WHERE id=$1
+	`, userID).Scan(&username, &email, &pendingEmail, &role, &points, &verified,
+		&mfaEnabled, &profileText, &avatarURL, &createdAt, &deletionScheduledAt, &protectedUntil)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":                        userID,
+		"username":                  username,
+		"email":                     email,
+		"email_verified":            verified,
+		"pending_email":             pendingEmail.String,
+		"role":                      role,
+		"points":                    points,
+		"profile_text":              profileText.String,
+		"avatar_url":                avatarURL.String,
+		"two_factor_enabled":        mfaEnabled,
+		"created_at":                createdAt,
+		"deletion_scheduled_at":     deletionScheduledAt,
+		"beginner_protection_until": protectedUntil,
+	})
+}
+
+// =============================
+// PUT /me (email, profile_text, avatar_url - każde pole opcjonalne)
+// =============================
+func UpdateMeHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	// nil = pole pominięte (albo null) i bez zmian, "" czyści tekst i avatar
+	var req struct {
+		Email       string  `json:"email"`
+		ProfileText *string `json:"profile_text"`
+		AvatarURL   *string `json:"avatar_url"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	// najpierw walidacja wszystkich pól - błędne żądanie niczego nie zmienia
+	fields := fieldErrors{}
+	setText, text := req.ProfileText != nil, ""
+	if setText {
+		text = *req.ProfileText
+	}
+	if utf8.RuneCountInString(text) > maxProfileText {
+		fields.add("profile_text", fmt.Sprintf("Profile text too long (max %d characters)", maxProfileText))
+	}
+	setAvatar, avatar := req.AvatarURL != nil, ""
+	if setAvatar {
+		avatar = *req.AvatarURL
+	}
+	if avatar != "" && !isAvatarURLValid(avatar) {
+		fields.add("avatar_url", "Avatar must be an http(s) URL")
+	}
+	email := req.Email
+	if email != "" && !isEmailValid(email) {
+		fields.add("email", "Invalid email format")
+	}
+	if fields.write(w) {
+		return
+	}
+
+	if email != "" {
+		var taken bool
+		if err := db.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email=$1)", email).Scan(&taken); err != nil {
+			writeInternalError(w, "DB error")
+			return
+		}
+		if taken {
+			writeError(w, http.StatusConflict, "EMAIL_IN_USE", "Email already in use")
+			return
+		}
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer tx.Rollback()
+
+	// stary adres działa (także do resetu hasła), dopóki nowy nie zostanie potwierdzony
+	var username string
+	err = tx.QueryRow(`
+		UPDATE users SET
+			profile_text  = CASE WHEN $2 THEN NULLIF($3, '') ELSE profile_text END,
+			avatar_url    = CASE WHEN $4 THEN NULLIF($5, '') ELSE avatar_url END,
+			pending_email = COALESCE(NULLIF($6, ''), pending_email)
+		WHERE id=$1
+		RETURNING username
+	`, userID, setText, text, setAvatar, avatar, email).Scan(&username)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	var token string
+	if email != "" {
+		if token, err = issueEmailToken(r.Context(), postgres.WithTx(tx).Auth, userID, purposeChangeEmail, verifyTTL); err != nil {
+			writeInternalError(w, "DB error")
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	message := "Profile updated"
+	if email != "" {
+		err = mail.Send(mailer.Message{
+			To:      email,
+			Subject: "Potwierdź nowy adres e-mail",
+			Body: fmt.Sprintf("Witaj %s!\n\nPotwierdź nowy adres e-mail, otwierając link:\n%s\n\nLink jest ważny przez %s.\n",
+				username, emailLink("/confirm-email", token), verifyTTL),
+		})
+		if err != nil {
+			log.Println("email change:", err)
+			writeInternalError(w, "Cannot send email")
+			return
+		}
+		message = "Profile updated. Confirm the new email address to complete the change"
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// =============================
+// POST /me/email/confirm (token)
+// =============================
+func ConfirmEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	token := req.Token
+	if token == "" {
+		writeFieldError(w, "token", "Missing token")
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer tx.Rollback()
+
+	userID, err := consumeEmailToken(tx, token, purposeChangeEmail)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusBadRequest, "INVALID_TOKEN", "Invalid or expired token")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	var oldEmail, newEmail, username string
+	err = tx.QueryRow(`
+		UPDATE users u SET email=u.pending_email, pending_email=NULL, email_verified_at=NOW()
+		FROM users old
+		WHERE u.id=$1 AND old.id=u.id AND u.pending_email IS NOT NULL
+		RETURNING old.email, u.email, u.username
+	`, userID).Scan(&oldEmail, &newEmail, &username)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusBadRequest, "NO_PENDING_EMAIL_CHANGE", "No pending email change")
+		return
+	} else if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		writeError(w, http.StatusConflict, "EMAIL_IN_USE", "Email already in use")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	// stary adres dostaje informację, na wypadek przejęcia konta
+	err = mail.Send(mailer.Message{
+		To:      oldEmail,
+		Subject: "Zmieniono adres e-mail",
+		Body: fmt.Sprintf("Witaj %s!\n\nAdres e-mail Twojego konta został zmieniony na %s.\n"+
+			"Jeśli to nie Ty, natychmiast skontaktuj się z administracją.\n", username, newEmail),
+	})
+	if err != nil {
+		log.Println("email change notice:", err)
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Email changed"})
+}
+
+// =============================
+// POST /me/password (old_password, new_password)
+// =============================
+func ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	sessionID := r.Context().Value("session_id").(string)
+	var req struct {
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	newPassword := req.NewPassword
+
+	ok, err := checkPassword(userID, req.OldPassword)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusForbidden, "INVALID_CREDENTIALS", "Invalid credentials")
+		return
+	}
+	if !isPasswordStrong(newPassword) {
+		writeFieldError(w, "new_password", "Password too weak. Must be at least 8 characters, with upper, lower, digit, and special character.")
+		return
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		writeInternalError(w, "Cannot hash password")
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("UPDATE users SET password_hash=$1 WHERE id=$2", string(hashed), userID); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	// bieżące urządzenie zostaje zalogowane, pozostałe tracą dostęp
+	res, err := tx.Exec(
+		"UPDATE sessions SET revoked_at=NOW() WHERE user_id=$1 AND id<>$2 AND revoked_at IS NULL",
+		userID, sessionID,
+	)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	n, _ := res.RowsAffected()
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":          "Password changed",
+		"revoked_sessions": n,
+	})
+}
+
+// =============================
+// DELETE /me (password, code gdy włączone 2FA)
+// =============================
+func DeleteMeHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req struct {
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	ok, err := checkPassword(userID, req.Password)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusForbidden, "INVALID_CREDENTIALS", "Invalid credentials")
+		return
+	}
+	var mfaEnabled bool
+	if err := db.DB.QueryRow("SELECT totp_enabled_at IS NOT NULL FROM users WHERE id=$1", userID).Scan(&mfaEnabled); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if mfaEnabled {
+		ok, err := verifySecondFactor(userID, req.Code, req.RecoveryCode)
+		if err != nil {
+			writeInternalError(w, "DB error")
+			return
+		}
+		if !ok {
+			writeError(w, http.StatusForbidden, "INVALID_CODE", "Invalid code")
+			return
+		}
+	}
+
+	deleteAt := time.Now().Add(deletionGrace)
+	tx, err := db.DB.Begin()
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		"UPDATE users SET deletion_scheduled_at=$1 WHERE id=$2 AND deletion_scheduled_at IS NULL",
+		deleteAt, userID,
+	)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, http.StatusConflict, "DELETION_ALREADY_SCHEDULED", "Account deletion already scheduled")
+		return
+	}
+	if err := scheduler.Schedule(tx, JobDeleteAccount, userJob{UserID: userID}, deleteAt); err != nil {
+		writeInternalError(w, "DB error on scheduling")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "Account scheduled for deletion. Log in and call POST /me/restore to cancel",
+		"delete_at": deleteAt,
+	})
+}
+
+// =============================
+// POST /me/restore
+// =============================
+func RestoreMeHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	// zaplanowane zdarzenie zostaje, ale deleteAccount je zignoruje
+	res, err := db.DB.Exec(
+		"UPDATE users SET deletion_scheduled_at=NULL WHERE id=$1 AND deletion_scheduled_at IS NOT NULL AND deleted_at IS NULL",
+		userID,
+	)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, http.StatusNotFound, "DELETION_NOT_SCHEDULED", "No deletion scheduled")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Account deletion cancelled"})
+}
+
+// anonimizuje konto po okresie karencji; wioski przechodzą do barbarzyńców.
+// Działa w transakcji zdarzenia schedulera, commit robi scheduler.
+func deleteAccount(tx *sql.Tx, userID int) error {
+	// usunięcie anulowane albo przełożone - nic nie robimy
+	res, err := tx.Exec(`
+		UPDATE users SET
+			username = 'deleted_' || id,
+			email = 'deleted_' || id || '@deleted.invalid',
+			password_hash = '!',
+			pending_email = NULL, profile_text = NULL, avatar_url = NULL,
+			email_verified_at = NULL, totp_secret = NULL, totp_enabled_at = NULL,
+			tribe_id = NULL, role = 'player', points = 0,
+			deletion_scheduled_at = NULL, deleted_at = NOW()
+		WHERE id=$1 AND deleted_at IS NULL AND deletion_scheduled_at <= NOW()
+	`, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+
+	cleanup := []string{
+		"UPDATE villages SET user_id=NULL WHERE user_id=$1",
+		"DELETE FROM sessions WHERE user_id=$1 OR sitter_id=$1",
+		"UPDATE sitters SET revoked_at=NOW() WHERE (owner_id=$1 OR sitter_id=$1) AND revoked_at IS NULL",
+		"DELETE FROM recovery_codes WHERE user_id=$1",
+		"DELETE FROM email_tokens WHERE user_id=$1",
+		"DELETE FROM notifications WHERE user_id=$1",
+		"UPDATE reports SET target_snapshot='' WHERE target_type='user' AND target_id=$1",
+		// dzienniki zostają dla moderacji, ale bez adresów IP i wolnego tekstu o graczu
+		"UPDATE sitter_actions SET ip=NULL WHERE owner_id=$1 OR sitter_id=$1",
+		"UPDATE audit_log SET ip=NULL WHERE actor_id=$1",
+		"UPDATE audit_log SET details = details - 'reason' - 'note' WHERE target_type='user' AND target_id=$1::text",
+		`UPDATE audit_log SET details = details - 'note'
+		 WHERE target_type='report' AND details->>'target_type'='user' AND details->>'target_id'=$1::text`,
+	}
+	for _, q := range cleanup {
+		if _, err := tx.Exec(q, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// =============================
+// GET /players/{id} - publiczny profil gracza
+// =============================
+func GetPlayerProfileHandler(w http.ResponseWriter, r *http.Request) {
+	playerID, err := pathID(r)
+	if err != nil {
+		writeFieldError(w, "id", "Invalid player ID")
+		return
+	}
+
+	var username, tribe string
+	var profileText, avatarURL sql.NullString
+	var points, villages int
+	var createdAt time.Time
+	var vacationUntil, protectedUntil *time.Time
+	err = db.DB.QueryRow(`
+		SELECT u.username, COALESCE(t.tag, ''), u.points, u.profile_text, u.avatar_url, u.created_at,
+		       CASE WHEN u.protected_until > NOW() THEN u.protected_until END,
+		       (SELECT COUNT(*) FROM villages v WHERE v.user_id = u.id),
+		       (SELECT COALESCE(vac.ended_at, vac.ends_at) FROM vacations vac
+		        WHERE vac.user_id = u.id AND vac.starts_at <= NOW() AND COALESCE(vac.ended_at, vac.ends_at) > NOW())
+		FROM users u
+		LEFT JOIN tribes t ON u.tribe_id = t.id
+		WHERE u.id=$1 AND u.deleted_at IS NULL
+	`, playerID).Scan(&username, &tribe, &points, &profileText, &avatarURL, &createdAt, &protectedUntil, &villages, &vacationUntil)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, "PLAYER_NOT_FOUND", "Player not found")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":             playerID,
+		"username":       username,
+		"tribe":          tribe,
+		"points":         points,
+		"villages":       villages,
+		"profile_text":   profileText.String,
+		"avatar_url":     avatarURL.String,
+		"created_at":     createdAt,
+		"on_vacation":    vacationUntil != nil,
+		"vacation_until": vacationUntil,
+		// ochrona początkujących - ataki na gracza odrzuca game.Service.CheckAttack
+		"beginner_protection":       protectedUntil != nil,
+		"beginner_protection_until": protectedUntil,
+	})
+}
@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"PawTribalWars/game"
+	"PawTribalWars/repo"
+	"github.com/gorilla/mux"
+)
+
+// Admin obsługuje panel admina. Zmiany i ich wpisy w audit_log idą w jednej
+// transakcji store, a poziomy budynków przez game.Service, żeby punkty
+// liczyły się tak samo jak w grze.
+type Admin struct {
+	svc   *game.Service
+	store repo.Store
+}
+
+func NewAdmin(svc *game.Service, store repo.Store) *Admin {
+	return &Admin{svc: svc, store: store}
+}
+
+// id z {id} w ścieżce
+func pathID(r *http.Request) (int, error) {
+	return strconv.Atoi(mux.Vars(r)["id"])
+}
+
+// =============================
+// GET /admin/users?search=name&page=1&per_page=25
+// =============================
+func (a *Admin) SearchUsersHandler(w http.ResponseWriter, r *http.Request) {
+	page, perPage := parsePagination(r)
+	search := r.URL.Query().Get("search")
+
+	found, total, err := a.store.Admin.SearchUsers(r.Context(), search, perPage, (page-1)*perPage)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	// przeglądanie danych graczy (e-maile) też zostawia ślad
+	if err := audit(a.store.Admin, r, "user.search", "user", "", map[string]interface{}{
+		"search": search, "page": page, "results": len(found),
+	}); err != nil {
+		writeInternalError(w, "DB error on audit log")
+		return
+	}
+
+	users := []map[string]interface{}{}
+	for _, u := range found {
+		users = append(users, map[string]interface{}{
+			"id":         u.ID,
+			"username":   u.Username,
+			"email":      u.Email,
+			"role":       u.Role,
+			"points":     u.Points,
+			"created_at": u.CreatedAt,
+			"banned":     u.Banned,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"page":     page,
+		"per_page": perPage,
+		"total":    total,
+		"results":  users,
+	})
+}
+
+// =============================
+// GET /admin/villages/{id}
+// =============================
+func (a *Admin) GetVillageHandler(w http.ResponseWriter, r *http.Request) {
+	villageID, err := pathID(r)
+	if err != nil {
+		writeFieldError(w, "id", "Invalid village ID")
+		return
+	}
+
+	snap, err := a.store.Villages.Snapshot(r.Context(), villageID)
+	if err == repo.ErrNotFound {
+		writeError(w, http.StatusNotFound, "VILLAGE_NOT_FOUND", "Village not found")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	owner := ""
+	if snap.Village.UserID != 0 {
+		u, err := a.store.Users.ByID(r.Context(), snap.Village.UserID)
+		if err != nil && err != repo.ErrNotFound {
+			writeInternalError(w, "DB error")
+			return
+		}
+		owner = u.Username
+	}
+	if err := audit(a.store.Admin, r, "village.inspect", "village", villageID, map[string]interface{}{
+		"owner_id": snap.Village.UserID,
+	}); err != nil {
+		writeInternalError(w, "DB error on audit log")
+		return
+	}
+
+	buildings := map[string]int{}
+	for _, b := range snap.Buildings {
+		buildings[b.Type] = b.Level
+	}
+	units := map[string]int{}
+	for _, u := range snap.Units {
+		units[u.Type] = u.Count
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":       villageID,
+		"name":     snap.Village.Name,
+		"points":   snap.Village.Points,
+		"owner_id": snap.Village.UserID,
+		"owner":    owner,
+		"resources": map[string]int{
+			"wood": snap.Resources.Wood, "clay": snap.Resources.Clay, "iron": snap.Resources.Iron,
+		},
+		"buildings": buildings,
+		"units":     units,
+	})
+}
+
+// =============================
+// PUT /admin/villages/{id}/resources (wood, clay, iron)
+// =============================
+func (a *Admin) SetResourcesHandler(w http.ResponseWriter, r *http.Request) {
+	villageID, err := pathID(r)
+	if err != nil {
+		writeFieldError(w, "id", "Invalid village ID")
+		return
+	}
+	var req struct {
+		Wood *int `json:"wood"`
+		Clay *int `json:"clay"`
+		Iron *int `json:"iron"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Wood == nil || req.Clay == nil || req.Iron == nil || *req.Wood < 0 || *req.Clay < 0 || *req.Iron < 0 {
+		writeFieldError(w, "resources", "wood, clay and iron must be non-negative integers")
+		return
+	}
+	wood, clay, iron := *req.Wood, *req.Clay, *req.Iron
+
+	err = a.store.Tx.InTx(r.Context(), func(st repo.Store) error {
+		// blokada wiersza: równoległa produkcja nie nadpisze ustawionych surowców
+		if _, err := st.Resources.Lock(r.Context(), villageID); err != nil {
+			return err
+		}
+		err := st.Resources.Save(r.Context(), repo.Resources{
+			VillageID: villageID, Wood: wood, Clay: clay, Iron: iron, UpdatedAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		return audit(st.Admin, r, "village.set_resources", "village", villageID, map[string]interface{}{
+			"wood": wood, "clay": clay, "iron": iron,
+		})
+	})
+	if err == repo.ErrNotFound {
+		writeError(w, http.StatusNotFound, "VILLAGE_NOT_FOUND", "Village not found")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Resources updated"})
+}
+
+// =============================
+// PUT /admin/villages/{id}/buildings/{type} (level)
+// =============================
+func (a *Admin) SetBuildingHandler(w http.ResponseWriter, r *http.Request) {
+	villageID, err := pathID(r)
+	if err != nil {
+		writeFieldError(w, "id", "Invalid village ID")
+		return
+	}
+	buildingType := mux.Vars(r)["type"]
+	if !game.IsBuilding(buildingType) {
+		writeFieldError(w, "type", "Invalid building type")
+		return
+	}
+	var req struct {
+		Level *int `json:"level"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Level == nil || *req.Level < 0 || *req.Level > game.MaxBuildingLevel {
+		writeFieldError(w, "level", "Invalid level")
+		return
+	}
+	level := *req.Level
+
+	err = a.store.Tx.InTx(r.Context(), func(st repo.Store) error {
+		if err := a.svc.WithStore(st).SetBuildingLevel(r.Context(), villageID, buildingType, level); err != nil {
+			return err
+		}
+		return audit(st.Admin, r, "village.set_building", "village", villageID, map[string]interface{}{
+			"type": buildingType, "level": level,
+		})
+	})
+	if err != nil {
+		writeGameError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Building updated"})
+}
+
+// =============================
+// PUT /admin/villages/{id}/units/{type} (count)
+// =============================
+func (a *Admin) SetUnitsHandler(w http.ResponseWriter, r *http.Request) {
+	villageID, err := pathID(r)
+	if err != nil {
+		writeFieldError(w, "id", "Invalid village ID")
+		return
+	}
+	unitType := mux.Vars(r)["type"]
+	if _, ok := game.UnitCost(unitType); !ok {
+		writeFieldError(w, "type", "Invalid unit type")
+		return
+	}
+	var req struct {
+		Count *int `json:"count"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Count == nil || *req.Count < 0 {
+		writeFieldError(w, "count", "Invalid count")
+		return
+	}
+	count := *req.Count
+
+	err = a.store.Tx.InTx(r.Context(), func(st repo.Store) error {
+		// wioski startowe mają tylko pikinierów - brakujący wiersz jest dokładany
+		if err := st.Units.Set(r.Context(), villageID, unitType, count); err != nil {
+			return err
+		}
+		return audit(st.Admin, r, "village.set_units", "village", villageID, map[string]interface{}{
+			"type": unitType, "count": count,
+		})
+	})
+	if err == repo.ErrNotFound {
+		writeError(w, http.StatusNotFound, "VILLAGE_NOT_FOUND", "Village not found")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Units updated"})
+}
+
+// =============================
+// PUT /admin/users/{id}/role (role)
+// =============================
+func (a *Admin) ChangeRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r)
+	if err != nil {
+		writeFieldError(w, "id", "Invalid user ID")
+		return
+	}
+	var req struct {
+		Role string `json:"role"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	role := req.Role
+	if !isValidRole(role) {
+		writeFieldError(w, "role", "Invalid role")
+		return
+	}
+	if userID == r.Context().Value("user_id").(int) {
+		writeError(w, http.StatusForbidden, "CANNOT_CHANGE_OWN_ROLE", "Cannot change your own role")
+		return
+	}
+
+	err = a.store.Tx.InTx(r.Context(), func(st repo.Store) error {
+		// rola siedzi w tokenie - SetRole zamyka sesje i wymusza ponowne logowanie
+		oldRole, err := st.Admin.SetRole(r.Context(), userID, role)
+		if err != nil {
+			return err
+		}
+		return audit(st.Admin, r, "user.change_role", "user", userID, map[string]interface{}{
+			"from": oldRole, "to": role,
+		})
+	})
+	if err == repo.ErrNotFound {
+		writeError(w, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Role changed"})
+}
+
+// =============================
+// GET /admin/audit?page=1&per_page=25
+// =============================
+func (a *Admin) AuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	page, perPage := parsePagination(r)
+
+	log, total, err := a.store.Admin.AuditLog(r.Context(), perPage, (page-1)*perPage)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	entries := []map[string]interface{}{}
+	for _, e := range log {
+		entries = append(entries, map[string]interface{}{
+			"id":          e.ID,
+			"actor":       e.Actor,
+			"action":      e.Action,
+			"target_type": e.TargetType,
+			"target_id":   e.TargetID,
+			"details":     e.Details,
+			"ip":          e.IP,
+			"created_at":  e.CreatedAt,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"page":     page,
+		"per_page": perPage,
+		"total":    total,
+		"results":  entries,
+	})
+}
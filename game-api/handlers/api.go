@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Kontrakt API: odpowiedzi zawsze w JSON, błędy jako
+// {"code": "NOT_ENOUGH_RESOURCES", "message": "...", "details": {...}}.
+// code jest stały i przeznaczony dla programów, message dla ludzi.
+
+// ogólne kody; kody konkretnych błędów są przy wywołaniach writeError
+const (
+	CodeValidationFailed = "VALIDATION_FAILED"
+	CodeInvalidBody      = "INVALID_BODY"
+	CodeInternalError    = "INTERNAL_ERROR"
+	CodeNotFound         = "NOT_FOUND"
+	CodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+	// zmiana w /api/v1 z ciałem innym niż JSON
+	CodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+)
+
+// prefiks wersjonowanego API; trasy bez prefiksu to przestarzałe aliasy
+const APIPrefix = "/api/v1"
+
+// maksymalny rozmiar ciała JSON
+const maxJSONBody = 1 << 20
+
+type apiError struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeErrorDetails(w, status, code, message, nil)
+}
+
+func writeErrorDetails(w http.ResponseWriter, status int, code, message string, details interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiError{Code: code, Message: message, Details: details})
+}
+
+// błąd serwera; szczegóły zostają w logach, klient dostaje tylko komunikat
+func writeInternalError(w http.ResponseWriter, message string) {
+	writeError(w, http.StatusInternalServerError, CodeInternalError, message)
+}
+
+// ===== Walidacja =====
+
+// fieldErrors zbiera błędy wszystkich pól, żeby klient dostał je naraz
+type fieldErrors map[string]string
+
+// add zapisuje pierwszy błąd danego pola
+func (f fieldErrors) add(field, message string) {
+	if _, ok := f[field]; !ok {
+		f[field] = message
+	}
+}
+
+// write wysyła 400 VALIDATION_FAILED, jeśli jest jakiś błąd; zwraca true, gdy wysłał
+func (f fieldErrors) write(w http.ResponseWriter) bool {
+	if len(f) == 0 {
+		return false
+	}
+	message := "Validation failed"
+	if len(f) == 1 {
+		for _, m := range f {
+			message = m
+		}
+	}
+	writeErrorDetails(w, http.StatusBadRequest, CodeValidationFailed, message, map[string]interface{}{"fields": f})
+	return true
+}
+
+// błąd jednego pola
+func writeFieldError(w http.ResponseWriter, field, message string) {
+	fieldErrors{field: message}.write(w)
+}
+
+// wymagana dodatnia liczba całkowita
+func (f fieldErrors) positiveInt(r *http.Request, field string) int {
+	raw := r.FormValue(field)
+	if raw == "" {
+		f.add(field, "Missing "+field)
+		return 0
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		f.add(field, "Invalid "+field)
+		return 0
+	}
+	return n
+}
+
+// wymagana dodatnia liczba całkowita z ciała żądania (nil - pola nie podano)
+func (f fieldErrors) positive(field string, v *int) int {
+	if v == nil {
+		f.add(field, "Missing "+field)
+		return 0
+	}
+	if *v < 1 {
+		f.add(field, "Invalid "+field)
+		return 0
+	}
+	return *v
+}
+
+// wymagany niepusty tekst; zmienna trasy (np. {type}) ma pierwszeństwo przed formularzem
+func (f fieldErrors) required(r *http.Request, field string) string {
+	v, ok := mux.Vars(r)[field]
+	if !ok {
+		v = r.FormValue(field)
+	}
+	if v == "" {
+		f.add(field, "Missing "+field)
+	}
+	return v
+}
+
+// wioska z trasy /api/v1/villages/{id}/... albo z parametru village_id starych tras
+func (f fieldErrors) villageID(r *http.Request) int {
+	raw, ok := mux.Vars(r)["id"]
+	if !ok {
+		return f.positiveInt(r, "village_id")
+	}
+	id, err := strconv.Atoi(raw)
+	if err != nil || id < 1 {
+		f.add("id", "Invalid village id")
+		return 0
+	}
+	return id
+}
+
+// ===== Middleware =====
+
+// JSONMiddleware ustawia application/json jako domyślny typ odpowiedzi. Zmiany
+// w /api/v1 przyjmują tylko ciała JSON (inne dostają 415), a handlery czytają je
+// przez decodeBody. Stare trasy przyjmują też JSON jako formularz: skalarne pola
+// obiektu trafiają do r.Form, surowe ciało zostaje w r.Body.
+func JSONMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if isAPIRequest(r) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead && r.ContentLength != 0 && !isJSONRequest(r) {
+				writeError(w, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "Request body must be application/json")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if isJSONRequest(r) && r.Body != nil {
+			raw, err := io.ReadAll(io.LimitReader(r.Body, maxJSONBody))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, CodeInvalidBody, "Cannot read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(raw))
+			if len(bytes.TrimSpace(raw)) > 0 {
+				if err := jsonToForm(r, raw); err != nil {
+					writeError(w, http.StatusBadRequest, CodeInvalidBody, "Invalid JSON body")
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/json"
+}
+
+// trasa wersjonowanego API (nie przestarzały alias)
+func isAPIRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, APIPrefix+"/")
+}
+
+// skalarne pola obiektu JSON dopisuje do r.Form i r.PostForm (tylko stare trasy)
+func jsonToForm(r *http.Request, raw []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var body interface{}
+	if err := dec.Decode(&body); err != nil {
+		return err
+	}
+	fields, ok := body.(map[string]interface{})
+	if !ok {
+		// tablice i inne wartości czytają handlery same (np. symulator)
+		return nil
+	}
+
+	form := url.Values{}
+	for k, v := range r.URL.Query() {
+		form[k] = append(form[k], v...)
+	}
+	post := url.Values{}
+	for k, v := range fields {
+		var s string
+		switch v := v.(type) {
+		case string:
+			s = v
+		case json.Number:
+			s = v.String()
+		case bool:
+			s = strconv.FormatBool(v)
+		default:
+			continue // null, obiekty i tablice nie mają odpowiednika w formularzu
+		}
+		post.Set(k, s)
+		// ciało ma pierwszeństwo przed query, jak w ParseForm
+		form[k] = append([]string{s}, form[k]...)
+	}
+	r.Form = form
+	r.PostForm = post
+	return nil
+}
+
+// ===== Ciała żądań =====
+
+// decodeBody wypełnia dst (wskaźnik na strukturę z tagami json) z ciała żądania;
+// false = odpowiedź z błędem już wysłana. W /api/v1 ciało musi być obiektem
+// JSON zgodnym typami z dst, stare trasy czytają pola z formularza.
+func decodeBody(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	if isAPIRequest(r) {
+		return decodeJSON(w, r, dst)
+	}
+	return decodeForm(w, r, dst)
+}
+
+// decodeJSON czyta ciało JSON bez żadnych konwersji: złe typy ("count": "5")
+// i nieznane pola to 400 VALIDATION_FAILED z nazwą pola; puste ciało to {}
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	raw, err := io.ReadAll(io.LimitReader(r.Body, maxJSONBody+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "Cannot read request body")
+		return false
+	}
+	if len(raw) > maxJSONBody {
+		writeError(w, http.StatusRequestEntityTooLarge, CodeInvalidBody, "Request body too large")
+		return false
+	}
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return true
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(dst)
+	if err == nil && dec.More() {
+		err = errors.New("trailing data")
+	}
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		writeFieldError(w, typeErr.Field, "Must be "+jsonTypeName(typeErr.Type))
+	case errors.As(err, &typeErr):
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "Body must be a JSON object")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		writeFieldError(w, field, "Unknown field")
+	default:
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "Invalid JSON body")
+	}
+	return false
+}
+
+// nazwa typu JSON dla komunikatu o złym typie pola
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// decodeForm to decodeBody starych tras: pola dst z r.Form (formularz, query
+// albo JSON rozpakowany przez JSONMiddleware) konwertowane według typów pól.
+// Pola wskaźnikowe zostają nil, gdy żądanie ich nie zawiera.
+func decodeForm(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	if err := parseForm(r); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "Invalid form")
+		return false
+	}
+
+	fields := fieldErrors{}
+	v := reflect.ValueOf(dst).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		values, ok := r.Form[name]
+		if name == "" || name == "-" || !ok {
+			continue
+		}
+		raw := values[0]
+		f := v.Field(i)
+		if f.Kind() == reflect.Ptr {
+			f.Set(reflect.New(f.Type().Elem()))
+			f = f.Elem()
+		}
+		switch f.Kind() {
+		case reflect.String:
+			f.SetString(raw)
+		case reflect.Int:
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				fields.add(name, "Must be an integer")
+				continue
+			}
+			f.SetInt(int64(n))
+		case reflect.Bool:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				fields.add(name, "Must be a boolean")
+				continue
+			}
+			f.SetBool(b)
+		}
+	}
+	return !fields.write(w)
+}
+
+// ParseForm czyta ciało tylko dla POST/PUT/PATCH; DELETE też przyjmuje formularz
+func parseForm(r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	if r.Method != http.MethodDelete || isJSONRequest(r) || r.Body == nil {
+		// ciało JSON rozpakował już JSONMiddleware
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxJSONBody))
+	if err != nil {
+		return err
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range values {
+		r.Form[k] = append(r.Form[k], v...)
+	}
+	return nil
+}
+
+// od kiedy trasy bez prefiksu są przestarzałe (wprowadzenie /api/v1)
+var legacyDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// Deprecated oznacza stare trasy bez prefiksu /api/v1 nagłówkiem Deprecation
+// (RFC 9745); link prowadzi do dokumentacji z następcami
+func Deprecated(next http.Handler) http.Handler {
+	deprecation := "@" + strconv.FormatInt(legacyDeprecatedAt.Unix(), 10)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", deprecation)
+		w.Header().Set("Link", `</docs>; rel="deprecation"`)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// =============================
+// Nieznane ścieżki i metody
+// =============================
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, CodeNotFound, "Route not found")
+}
+
+func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+}
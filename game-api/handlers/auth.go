@@ -2,24 +2,32 @@ package handlers
 
 import (
 	"context"
-	"database/sql"
+	"crypto/subtle"
 	"encoding/json"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
 	"golang.org/x/crypto/bcrypt"
+	"log"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
 
 	"PawTribalWars/db"
+	"PawTribalWars/game"
+	"PawTribalWars/repo"
 )
 
-var jwtKey = []byte("super_secret_key")
-
 type Claims struct {
+	UserID   int    `json:"uid"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	// identyfikator sesji urządzenia, jti tokenu jest w RegisteredClaims.ID
+	SessionID string `json:"sid"`
+	// gracz zalogowany jako zastępca konta UserID (0 = właściciel)
+	SitterID int `json:"sitter_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -54,151 +62,269 @@ func isEmailValid(email string) bool {
 
 // ===== Handlery =====
 
-func RegisterHandler(w http.ResponseWriter, r *http.Request) {
-	username := r.FormValue("username")
-	email := r.FormValue("email")
-	password := r.FormValue("password")
+// Accounts obsługuje rejestrację i logowanie: konto ze startową wioską zakłada
+// game.Service, a hasła, blokady i sesje są w repo.AuthRepo
+type Accounts struct {
+	svc    *game.Service
+	auth   repo.AuthRepo
+	access repo.AccessRepo
+}
 
-	if len(username) < 4 {
-		http.Error(w, "Username too short", http.StatusBadRequest)
+func NewAccounts(svc *game.Service, store repo.Store) *Accounts {
+	return &Accounts{svc: svc, auth: store.Auth, access: store.Access}
+}
+
+func (a *Accounts) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username string `json:"username"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if !decodeBody(w, r, &req) {
 		return
 	}
+	username, email, password := req.Username, req.Email, req.Password
+
+	fields := fieldErrors{}
+	if len(username) < 4 {
+		fields.add("username", "Username too short")
+	}
 	if !isEmailValid(email) {
-		http.Error(w, "Invalid email format", http.StatusBadRequest)
-		return
+		fields.add("email", "Invalid email format")
 	}
 	if !isPasswordStrong(password) {
-		http.Error(w, "Password too weak. Must be at least 8 characters, with upper, lower, digit, and special character.", http.StatusBadRequest)
+		fields.add("password", "Password too weak. Must be at least 8 characters, with upper, lower, digit, and special character.")
+	}
+	if fields.write(w) {
 		return
 	}
 
 	hashed, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 
-	// dodajemy użytkownika
-	var userID int
-	err := db.DB.QueryRow(
-		"INSERT INTO users (username, email, password_hash) VALUES ($1, $2, $3) RETURNING id",
-		username, email, string(hashed),
-	).Scan(&userID)
+	// konto ze startową wioską i zestawem startowym (pikinier od razu zbadany)
+	reg, err := a.svc.RegisterPlayer(r.Context(), username, email, string(hashed), time.Now())
 	if err != nil {
-		http.Error(w, "User exists or DB error", http.StatusConflict)
+		writeGameError(w, err)
 		return
 	}
 
-	// startowa wioska
-	var villageID int
-	err = db.DB.QueryRow(
-		"INSERT INTO villages (user_id, name) VALUES ($1, $2) RETURNING id",
-		userID, "Startowa wioska",
-	).Scan(&villageID)
-	if err != nil {
-		http.Error(w, "DB error on village init", http.StatusInternalServerError)
-		return
+	// link weryfikacyjny; niewysłany mail można ponowić przez /email/resend
+	if err := sendVerificationEmail(r.Context(), a.auth, reg.User.ID, username, email); err != nil {
+		log.Println("verification email:", err)
 	}
 
-	// startowe surowce
-	_, err = db.DB.Exec(
-		"INSERT INTO resources (village_id, wood, clay, iron) VALUES ($1, 100, 100, 100)",
-		villageID,
-	)
-	if err != nil {
-		http.Error(w, "DB error on resources init", http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "User registered with starting village, resources, buildings and units. Check your email to verify the account",
+	})
+}
+
+func (a *Accounts) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if !decodeBody(w, r, &req) {
 		return
 	}
+	username, password := req.Username, req.Password
 
-	// startowe budynki lvl 1
-	buildings := []string{"townhall", "lumbermill", "claypit", "ironmine", "warehouse", "barracks"}
-	for _, b := range buildings {
-		_, err = db.DB.Exec(
-			"INSERT INTO buildings (village_id, type, level) VALUES ($1, $2, 1)",
-			villageID, b,
-		)
+	cred, err := a.auth.Credentials(r.Context(), username)
+	if err == repo.ErrNotFound {
+		// nieistniejąca nazwa przechodzi tę samą blokadę i to samo bcrypt co konto,
+		// żeby ani 429, ani czas odpowiedzi nie zdradzały, kto ma konto
+		remaining, err := unknownLoginLockRemaining(r.Context(), a.auth, username)
 		if err != nil {
-			http.Error(w, "DB error on buildings init", http.StatusInternalServerError)
+			writeInternalError(w, "DB error")
+			return
+		}
+		if remaining > 0 {
+			writeAccountLocked(w, remaining)
 			return
 		}
+		_ = bcrypt.CompareHashAndPassword(unknownUserHash, []byte(password))
+		recordUnknownLoginFailure(r.Context(), a.auth, username)
+		writeError(w, http.StatusUnauthorized, "INVALID_CREDENTIALS", "Invalid credentials")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
 	}
 
-	// startowe jednostki
-	_, err = db.DB.Exec("INSERT INTO units (village_id, type, count) VALUES ($1, $2, $3)",
-		villageID, "spearman", 5,
-	)
-	if err != nil {
-		http.Error(w, "DB error on units init", http.StatusInternalServerError)
+	// zablokowanego konta nie sprawdzamy nawet hasłem
+	if remaining := loginLockRemaining(cred.LockedUntil); remaining > 0 {
+		writeAccountLocked(w, remaining)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "User registered with starting village, resources, buildings and units",
-	})
-}
+	if bcrypt.CompareHashAndPassword([]byte(cred.PasswordHash), []byte(password)) != nil {
+		recordLoginFailure(r.Context(), a.auth, cred.UserID)
+		writeError(w, http.StatusUnauthorized, "INVALID_CREDENTIALS", "Invalid credentials")
+		return
+	}
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
-	username := r.FormValue("username")
-	password := r.FormValue("password")
-
-	var id int
-	var hashed string
-	var role string
-	err := db.DB.QueryRow("SELECT id, password_hash, role FROM users WHERE username=$1", username).
-		Scan(&id, &hashed, &role)
-	if err == sql.ErrNoRows {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	// zablokowane konto nie dostaje nowych tokenów
+	ban, err := a.access.ActiveBan(r.Context(), cred.UserID)
+	if err != nil {
+		writeInternalError(w, "DB error")
 		return
-	} else if err != nil {
-		http.Error(w, "DB error", http.StatusInternalServerError)
+	}
+	if ban != nil {
+		writeError(w, http.StatusForbidden, "ACCOUNT_BANNED", banMessage(ban))
 		return
 	}
 
-	if bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password)) != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	// z włączonym 2FA hasło daje tylko token do /login/mfa
+	if cred.MFAEnabled {
+		mfaToken, expiresAt, err := issueMFAToken(cred.UserID)
+		if err != nil {
+			writeInternalError(w, "Cannot sign token")
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+			"expires_at":   expiresAt,
+		})
 		return
 	}
 
-	expiration := time.Now().Add(24 * time.Hour)
-	claims := &Claims{
-		Username: username,
-		Role:     role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiration),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
-	}
+	resetLoginFailures(r.Context(), a.auth, cred.UserID)
+	writeNewSession(w, r, a.auth, cred.UserID, cred.Username, cred.Role)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, _ := token.SignedString(jwtKey)
+// unieważnia bieżący token i sesję, z której pochodzi
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.Context().Value("session_id").(string)
+	jti := r.Context().Value("jti").(string)
+	expiresAt := r.Context().Value("token_expires").(time.Time)
+
+	_, err := db.DB.Exec("UPDATE sessions SET revoked_at=NOW() WHERE id=$1 AND revoked_at IS NULL", sessionID)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	_, err = db.DB.Exec(
+		"INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		jti, expiresAt,
+	)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	// przy okazji sprzątamy wpisy, które i tak już wygasły
+	_, _ = db.DB.Exec("DELETE FROM revoked_tokens WHERE expires_at < NOW()")
 
-	json.NewEncoder(w).Encode(map[string]string{"token": tokenString})
+	json.NewEncoder(w).Encode(map[string]string{"message": "User logged out"})
 }
 
-func LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	json.NewEncoder(w).Encode(map[string]string{"message": "User logged out. Discard token client-side."})
+// MetricsAuth wpuszcza do /metrics tylko ze stałym tokenem (Authorization: Bearer,
+// jak w bearer_token Prometheusa). Bez tokenu metryki są otwarte w dev, a poza
+// dev wyłączone.
+func MetricsAuth(token string, dev bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			if dev {
+				next.ServeHTTP(w, r)
+			} else {
+				NotFoundHandler(w, r)
+			}
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "AUTH_REQUIRED", "Missing or invalid metrics token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
 // === JWT Middleware ===
-func AuthMiddleware(next http.Handler) http.Handler {
+func (g *Guard) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
+		// przeglądarki nie ustawiają nagłówków przy WebSocket - token w ?token=
+		if authHeader == "" && websocket.IsWebSocketUpgrade(r) && r.URL.Query().Get("token") != "" {
+			authHeader = "Bearer " + r.URL.Query().Get("token")
+		}
 		if authHeader == "" {
-			http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
+			writeError(w, http.StatusUnauthorized, "AUTH_REQUIRED", "Missing Authorization header")
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		claims := &Claims{}
 
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
+		token, err := jwt.ParseWithClaims(tokenString, claims, signingKeys.Keyfunc,
+			jwt.WithValidMethods(signingKeys.ValidMethods()))
+
+		// token MFA nie ma sid, więc tu nie przejdzie
+		if err != nil || !token.Valid || claims.ID == "" || claims.SessionID == "" {
+			writeError(w, http.StatusUnauthorized, "INVALID_TOKEN", "Invalid token")
+			return
+		}
+
+		// wylogowane tokeny i sesje są odrzucane przed końcem ważności
+		revoked, err := g.access.TokenRevoked(r.Context(), claims.ID, claims.SessionID)
+		if err != nil {
+			writeInternalError(w, "DB error")
+			return
+		}
+		if revoked {
+			writeError(w, http.StatusUnauthorized, "TOKEN_REVOKED", "Token revoked")
+			return
+		}
 
-		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		// blokada nałożona po wydaniu tokenu działa od razu
+		ban, err := g.access.ActiveBan(r.Context(), claims.UserID)
+		if err != nil {
+			writeInternalError(w, "DB error")
+			return
+		}
+		if ban != nil {
+			writeError(w, http.StatusForbidden, "ACCOUNT_BANNED", banMessage(ban))
+			return
+		}
+
+		// zastępstwo mogło zostać zakończone albo zastępca zablokowany
+		if claims.SitterID != 0 {
+			active, err := g.access.SittingActive(r.Context(), claims.UserID, claims.SitterID)
+			if err != nil {
+				writeInternalError(w, "DB error")
+				return
+			}
+			if !active {
+				writeError(w, http.StatusUnauthorized, "SITTING_ENDED", "Sitting ended")
+				return
+			}
+			ban, err := g.access.ActiveBan(r.Context(), claims.SitterID)
+			if err != nil {
+				writeInternalError(w, "DB error")
+				return
+			}
+			if ban != nil {
+				writeError(w, http.StatusForbidden, "ACCOUNT_BANNED", banMessage(ban))
+				return
+			}
+		}
+
+		// limit per gracz niezależnie od tego, z ilu IP gra
+		if !takeToken(w, "user:"+strconv.Itoa(claims.UserID), "user_"+readOrWrite(r)) {
 			return
 		}
 
 		// dodajemy dane z tokena do contextu
-		ctx := context.WithValue(r.Context(), "username", claims.Username)
+		ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
+		ctx = context.WithValue(ctx, "username", claims.Username)
 		ctx = context.WithValue(ctx, "role", claims.Role)
+		ctx = context.WithValue(ctx, "session_id", claims.SessionID)
+		ctx = context.WithValue(ctx, "jti", claims.ID)
+		ctx = context.WithValue(ctx, "token_expires", claims.ExpiresAt.Time)
+		ctx = context.WithValue(ctx, "sitter_id", claims.SitterID)
+		if claims.SitterID != 0 {
+			g.logSitterAction(next, w, r.WithContext(ctx), claims.UserID, claims.SitterID)
+			return
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
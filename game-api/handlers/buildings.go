@@ -2,68 +2,29 @@ package handlers
 
 import (
 	"encoding/json"
-	"math"
 	"net/http"
-	"strconv"
-
-	"PawTribalWars/db"
+	"time"
 )
 
-// struktura kosztów budynków
-type BuildingCost struct {
-	Wood int `json:"wood"`
-	Clay int `json:"clay"`
-	Iron int `json:"iron"`
-}
-
-// bazowe koszty (dla level 1)
-var baseCosts = map[string]BuildingCost{
-	"lumbermill": {Wood: 50, Clay: 50, Iron: 20},
-	"claypit":    {Wood: 50, Clay: 50, Iron: 20},
-	"ironmine":   {Wood: 50, Clay: 50, Iron: 20},
-	"warehouse":  {Wood: 100, Clay: 60, Iron: 40},
-	"barracks":   {Wood: 120, Clay: 100, Iron: 80},
-}
-
-// oblicz koszt ulepszenia
-func calculateUpgradeCost(buildingType string, nextLevel int) BuildingCost {
-	base := baseCosts[buildingType]
-	multiplier := math.Pow(2.5, float64(nextLevel-1))
-	return BuildingCost{
-		Wood: int(float64(base.Wood) * multiplier),
-		Clay: int(float64(base.Clay) * multiplier),
-		Iron: int(float64(base.Iron) * multiplier),
-	}
-}
-
-// GET /buildings?village_id=1
-func GetBuildingsHandler(w http.ResponseWriter, r *http.Request) {
-	villageIDStr := r.URL.Query().Get("village_id")
-	if villageIDStr == "" {
-		http.Error(w, "Missing village_id", http.StatusBadRequest)
-		return
-	}
-	villageID, err := strconv.Atoi(villageIDStr)
-	if err != nil {
-		http.Error(w, "Invalid village_id", http.StatusBadRequest)
+// GET /api/v1/villages/{id}/buildings (stara trasa: GET /buildings?village_id=1)
+func (g *Game) GetBuildingsHandler(w http.ResponseWriter, r *http.Request) {
+	fields := fieldErrors{}
+	villageID := fields.villageID(r)
+	if fields.write(w) {
 		return
 	}
 
-	rows, err := db.DB.Query("SELECT type, level FROM buildings WHERE village_id=$1", villageID)
+	list, err := g.svc.Buildings(r.Context(), villageID)
 	if err != nil {
-		http.Error(w, "DB error", http.StatusInternalServerError)
+		writeInternalError(w, "DB error")
 		return
 	}
-	defer rows.Close()
 
 	buildings := []map[string]interface{}{}
-	for rows.Next() {
-		var bType string
-		var level int
-		rows.Scan(&bType, &level)
+	for _, b := range list {
 		buildings = append(buildings, map[string]interface{}{
-			"type":  bType,
-			"level": level,
+			"type":  b.Type,
+			"level": b.Level,
 		})
 	}
 
@@ -73,107 +34,49 @@ func GetBuildingsHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// PUT /buildings/upgrade?village_id=1&type=lumbermill
-func UpgradeBuildingHandler(w http.ResponseWriter, r *http.Request) {
-	villageIDStr := r.URL.Query().Get("village_id")
-	buildingType := r.URL.Query().Get("type")
-
-	if villageIDStr == "" || buildingType == "" {
-		http.Error(w, "Missing village_id or building type", http.StatusBadRequest)
-		return
-	}
-	villageID, err := strconv.Atoi(villageIDStr)
-	if err != nil {
-		http.Error(w, "Invalid village_id", http.StatusBadRequest)
-		return
-	}
-
-	// pobierz aktualny level
-	var currentLevel int
-	err = db.DB.QueryRow("SELECT level FROM buildings WHERE village_id=$1 AND type=$2",
-		villageID, buildingType).Scan(&currentLevel)
-	if err != nil {
-		http.Error(w, "Building not found", http.StatusNotFound)
-		return
-	}
-
-	nextLevel := currentLevel + 1
-	cost := calculateUpgradeCost(buildingType, nextLevel)
-
-	// pobierz zasoby
-	var wood, clay, iron int
-	err = db.DB.QueryRow("SELECT wood, clay, iron FROM resources WHERE village_id=$1", villageID).
-		Scan(&wood, &clay, &iron)
-	if err != nil {
-		http.Error(w, "Resources not found", http.StatusInternalServerError)
-		return
-	}
-
-	// sprawdź czy stać
-	if wood < cost.Wood || clay < cost.Clay || iron < cost.Iron {
-		http.Error(w, "Not enough resources", http.StatusForbidden)
+// POST /api/v1/villages/{id}/buildings/{type}/upgrade (stara trasa: PUT /buildings/upgrade?village_id=1&type=lumbermill)
+func (g *Game) UpgradeBuildingHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	fields := fieldErrors{}
+	villageID := fields.villageID(r)
+	buildingType := fields.required(r, "type")
+	if fields.write(w) {
 		return
 	}
 
-	// odejmij koszt
-	_, err = db.DB.Exec(
-		"UPDATE resources SET wood=wood-$1, clay=clay-$2, iron=iron-$3 WHERE village_id=$4",
-		cost.Wood, cost.Clay, cost.Iron, villageID,
-	)
+	upgrade, err := g.svc.UpgradeBuilding(r.Context(), userID, villageID, buildingType, time.Now())
 	if err != nil {
-		http.Error(w, "DB error on resources update", http.StatusInternalServerError)
-		return
-	}
-
-	// podnieś level
-	_, err = db.DB.Exec(
-		"UPDATE buildings SET level=level+1 WHERE village_id=$1 AND type=$2",
-		villageID, buildingType,
-	)
-	if err != nil {
-		http.Error(w, "DB error on building update", http.StatusInternalServerError)
+		writeGameError(w, err)
 		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"message":       "Building upgraded",
 		"building_type": buildingType,
-		"new_level":     nextLevel,
-		"cost":          cost,
+		"new_level":     upgrade.NextLevel,
+		"cost":          upgrade.Cost,
 	})
 }
 
-// GET /buildings/cost?village_id=1&type=lumbermill
-func GetBuildingCostHandler(w http.ResponseWriter, r *http.Request) {
-	villageIDStr := r.URL.Query().Get("village_id")
-	buildingType := r.URL.Query().Get("type")
-
-	if villageIDStr == "" || buildingType == "" {
-		http.Error(w, "Missing village_id or building type", http.StatusBadRequest)
-		return
-	}
-	villageID, err := strconv.Atoi(villageIDStr)
-	if err != nil {
-		http.Error(w, "Invalid village_id", http.StatusBadRequest)
+// GET /api/v1/villages/{id}/buildings/{type}/cost (stara trasa: GET /buildings/cost?village_id=1&type=lumbermill)
+func (g *Game) GetBuildingCostHandler(w http.ResponseWriter, r *http.Request) {
+	fields := fieldErrors{}
+	villageID := fields.villageID(r)
+	buildingType := fields.required(r, "type")
+	if fields.write(w) {
 		return
 	}
 
-	// pobierz aktualny level
-	var currentLevel int
-	err = db.DB.QueryRow("SELECT level FROM buildings WHERE village_id=$1 AND type=$2",
-		villageID, buildingType).Scan(&currentLevel)
+	quote, err := g.svc.UpgradeQuote(r.Context(), villageID, buildingType)
 	if err != nil {
-		http.Error(w, "Building not found", http.StatusNotFound)
+		writeGameError(w, err)
 		return
 	}
 
-	nextLevel := currentLevel + 1
-	cost := calculateUpgradeCost(buildingType, nextLevel)
-
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"building_type": buildingType,
-		"current_level": currentLevel,
-		"next_level":    nextLevel,
-		"cost":          cost,
+		"current_level": quote.Level,
+		"next_level":    quote.NextLevel,
+		"cost":          quote.Cost,
 	})
 }
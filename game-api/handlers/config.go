@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/netip"
+	"os"
+	"time"
+
+	"PawTribalWars/config"
+	"PawTribalWars/jwks"
+	"PawTribalWars/mailer"
+)
+
+// ustawienia serwera używane przez handlery (nadpisywane w Configure)
+var (
+	accessTTL  = 15 * time.Minute
+	refreshTTL = 30 * 24 * time.Hour
+
+	linkBaseURL = "http://localhost:3000"
+	verifyTTL   = 48 * time.Hour
+	resetTTL    = time.Hour
+
+	deletionGrace       = 7 * 24 * time.Hour
+	vacationDelay       = 48 * time.Hour
+	vacationDaysPerYear = 60
+
+	// proxy, którym wierzymy w X-Forwarded-For (domyślnie żadnym)
+	trustedProxies []netip.Prefix
+)
+
+// klucze do podpisywania i weryfikacji JWT
+var signingKeys = jwks.NewHMAC([]byte(config.DefaultJWTSecret))
+
+// UseKeys ustawia zestaw kluczy JWT (HS256 z sekretu albo RS256/EdDSA z bazy)
+func UseKeys(keys *jwks.KeySet) {
+	signingKeys = keys
+}
+
+// poczta do graczy; domyślnie tylko wypisywana na stderr
+var mail mailer.Mailer = mailer.NewLog(os.Stderr, "PAW <noreply@localhost>")
+
+// UseMailer ustawia sposób wysyłania maili (SMTP, plik albo log)
+func UseMailer(m mailer.Mailer) {
+	mail = m
+}
+
+// Configure przekazuje handlerom konfigurację wczytaną przy starcie
+func Configure(cfg *config.Config) {
+	accessTTL = cfg.JWT.AccessTTL.Duration
+	refreshTTL = cfg.JWT.RefreshTTL.Duration
+	linkBaseURL = cfg.Mail.LinkBaseURL
+	verifyTTL = cfg.Mail.VerifyTTL.Duration
+	resetTTL = cfg.Mail.ResetTTL.Duration
+	rateLimitEnabled = cfg.RateLimit.Enabled
+	rateLimits = limitsFromConfig(cfg.RateLimit)
+	lockoutThreshold = cfg.RateLimit.LockoutThreshold
+	lockoutBase = cfg.RateLimit.LockoutBase.Duration
+	lockoutMax = cfg.RateLimit.LockoutMax.Duration
+	deletionGrace = cfg.Account.DeletionGrace.Duration
+	vacationDelay = cfg.Account.VacationDelay.Duration
+	vacationDaysPerYear = cfg.Account.VacationDaysPerYear
+	// poprawność sprawdził już config.Validate
+	trustedProxies, _ = config.ParsePrefixes(cfg.TrustedProxies)
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"PawTribalWars/db"
+	"PawTribalWars/mailer"
+	"PawTribalWars/repo"
+	"PawTribalWars/repo/postgres"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// przeznaczenie tokenów z tabeli email_tokens
+const (
+	purposeVerifyEmail   = "verify_email"
+	purposeResetPassword = "reset_password"
+	purposeChangeEmail   = "change_email"
+)
+
+// nowy jednorazowy token; poprzednie niewykorzystane tokeny tego typu przestają działać
+func issueEmailToken(ctx context.Context, tokens repo.AuthRepo, userID int, purpose string, ttl time.Duration) (string, error) {
+	token := randomToken(32)
+	return token, tokens.IssueEmailToken(ctx, userID, purpose, hashToken(token), ttl)
+}
+
+// zużywa token w jednym UPDATE, więc ten sam token nie zadziała dwa razy
+func consumeEmailToken(tx *sql.Tx, token, purpose string) (int, error) {
+	var userID int
+	err := tx.QueryRow(`
+		UPDATE email_tokens SET used_at=NOW()
+		WHERE token_hash=$1 AND purpose=$2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`, hashToken(token), purpose).Scan(&userID)
+	return userID, err
+}
+
+// link do frontendu z tokenem w parametrze
+func emailLink(path, token string) string {
+	return strings.TrimRight(linkBaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+func sendVerificationEmail(ctx context.Context, tokens repo.AuthRepo, userID int, username, email string) error {
+	token, err := issueEmailToken(ctx, tokens, userID, purposeVerifyEmail, verifyTTL)
+	if err != nil {
+		return err
+	}
+	return mail.Send(mailer.Message{
+		To:      email,
+		Subject: "Potwierdź adres e-mail",
+		Body: fmt.Sprintf("Witaj %s!\n\nPotwierdź swój adres e-mail, otwierając link:\n%s\n\nLink jest ważny przez %s.\n",
+			username, emailLink("/verify-email", token), verifyTTL),
+	})
+}
+
+// RequireVerifiedEmail blokuje funkcje dostępne tylko po potwierdzeniu maila; używać po AuthMiddleware
+func (g *Guard) RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verified, err := g.access.EmailVerified(r.Context(), r.Context().Value("user_id").(int))
+		if err != nil {
+			writeInternalError(w, "DB error")
+			return
+		}
+		if !verified {
+			writeError(w, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "Email not verified")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// =============================
+// POST /email/verify (token)
+// =============================
+func VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	token := req.Token
+	if token == "" {
+		writeFieldError(w, "token", "Missing token")
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer tx.Rollback()
+
+	userID, err := consumeEmailToken(tx, token, purposeVerifyEmail)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusBadRequest, "INVALID_TOKEN", "Invalid or expired token")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	_, err = tx.Exec("UPDATE users SET email_verified_at=NOW() WHERE id=$1 AND email_verified_at IS NULL", userID)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Email verified"})
+}
+
+// =============================
+// POST /email/resend
+// =============================
+func ResendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var username, email string
+	var verified bool
+	err := db.DB.QueryRow(
+		"SELECT username, email, email_verified_at IS NOT NULL FROM users WHERE id=$1", userID,
+	).Scan(&username, &email, &verified)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if verified {
+		writeError(w, http.StatusConflict, "EMAIL_ALREADY_VERIFIED", "Email already verified")
+		return
+	}
+
+	if err := sendVerificationEmail(r.Context(), postgres.New(db.DB).Auth, userID, username, email); err != nil {
+		log.Println("verification email:", err)
+		writeInternalError(w, "Cannot send email")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Verification email sent"})
+}
+
+// =============================
+// POST /password/forgot (email)
+// =============================
+func ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	email := req.Email
+	if !isEmailValid(email) {
+		writeFieldError(w, "email", "Invalid email format")
+		return
+	}
+
+	// odpowiedź jest zawsze taka sama, żeby nie zdradzać, które adresy mają konto
+	response := map[string]string{"message": "If the account exists, a reset link has been sent"}
+
+	var userID int
+	var username string
+	err := db.DB.QueryRow("SELECT id, username FROM users WHERE email=$1", email).Scan(&userID, &username)
+	if err != nil && err != sql.ErrNoRows {
+		writeInternalError(w, "DB error")
+		return
+	}
+	// token i mail powstają po odpowiedzi: zapis tokenu i SMTP trwają na tyle
+	// długo, że po czasie odpowiedzi dałoby się odróżnić istniejące konto
+	if err == nil {
+		go sendPasswordReset(userID, username, email)
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func sendPasswordReset(userID int, username, email string) {
+	token, err := issueEmailToken(context.Background(), postgres.New(db.DB).Auth, userID, purposeResetPassword, resetTTL)
+	if err != nil {
+		log.Println("password reset token:", err)
+		return
+	}
+	err = mail.Send(mailer.Message{
+		To:      email,
+		Subject: "Reset hasła",
+		Body: fmt.Sprintf("Witaj %s!\n\nAby ustawić nowe hasło, otwórz link:\n%s\n\nLink jest ważny przez %s. "+
+			"Jeśli to nie Ty prosiłeś o reset, zignoruj tę wiadomość.\n",
+			username, emailLink("/reset-password", token), resetTTL),
+	})
+	if err != nil {
+		log.Println("password reset email:", err)
+	}
+}
+
+// =============================
+// POST /password/reset (token, password)
+// =============================
+func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	token, password := req.Token, req.Password
+	if token == "" {
+		writeFieldError(w, "token", "Missing token")
+		return
+	}
+	if !isPasswordStrong(password) {
+		writeFieldError(w, "password", "Password too weak. Must be at least 8 characters, with upper, lower, digit, and special character.")
+		return
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		writeInternalError(w, "Cannot hash password")
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer tx.Rollback()
+
+	userID, err := consumeEmailToken(tx, token, purposeResetPassword)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusBadRequest, "INVALID_TOKEN", "Invalid or expired token")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	// link z maila dowodzi też posiadania skrzynki, a nowe hasło zdejmuje blokadę
+	// po nieudanych logowaniach - inaczej zablokowany gracz nie wejdzie mimo resetu
+	_, err = tx.Exec(`
+		UPDATE users SET password_hash=$1, email_verified_at=COALESCE(email_verified_at, NOW()),
+			failed_logins=0, locked_until=NULL
+		WHERE id=$2
+	`, string(hashed), userID)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	// po zmianie hasła wylogowujemy wszystkie urządzenia
+	_, err = tx.Exec("UPDATE sessions SET revoked_at=NOW() WHERE user_id=$1 AND revoked_at IS NULL", userID)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password changed, please log in again"})
+}
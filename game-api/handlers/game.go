@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"PawTribalWars/game"
+)
+
+// Game obsługuje endpointy wiosek, surowców, budynków i jednostek - tłumaczy
+// HTTP na polecenia game.Service. W testach serwis stoi na repo/memory.
+type Game struct {
+	svc *game.Service
+}
+
+func NewGame(svc *game.Service) *Game {
+	return &Game{svc: svc}
+}
+
+// tłumaczy błędy domenowe na odpowiedzi HTTP
+func writeGameError(w http.ResponseWriter, err error) {
+	var notEnough *game.NotEnoughResourcesError
+	var limit *game.VillageLimitError
+	var smithy *game.SmithyLevelError
+	switch {
+	case errors.Is(err, game.ErrVillageNotFound):
+		writeError(w, http.StatusForbidden, "VILLAGE_NOT_FOUND", "Village not found or not yours")
+	case errors.Is(err, game.ErrBuildingNotFound):
+		writeError(w, http.StatusNotFound, "BUILDING_NOT_FOUND", "Building not found")
+	case errors.Is(err, game.ErrUnknownUnit):
+		writeFieldError(w, "type", "Invalid unit type")
+	case errors.Is(err, game.ErrInvalidCount):
+		writeFieldError(w, "count", "Invalid count")
+	case errors.Is(err, game.ErrMissingName):
+		writeFieldError(w, "name", "Missing village name")
+	case errors.Is(err, game.ErrConcurrentUpdate):
+		writeError(w, http.StatusConflict, "CONCURRENT_UPDATE", "Village changed by another command, try again")
+	case errors.Is(err, game.ErrNotResearched):
+		writeError(w, http.StatusForbidden, "UNIT_NOT_RESEARCHED", "Unit not researched in smithy")
+	case errors.Is(err, game.ErrPlayerExists):
+		writeError(w, http.StatusConflict, "USER_EXISTS", "Username or email already taken")
+	case errors.Is(err, game.ErrResearchInProgress):
+		writeError(w, http.StatusConflict, "RESEARCH_IN_PROGRESS", "Smithy is already researching")
+	case errors.Is(err, game.ErrResearchMaxLevel):
+		writeFieldError(w, "type", "Research already at max level")
+	case errors.Is(err, game.ErrTargetNotFound):
+		writeError(w, http.StatusNotFound, "TARGET_NOT_FOUND", "Target village not found")
+	case errors.Is(err, game.ErrTargetOnVacation):
+		writeError(w, http.StatusForbidden, "TARGET_ON_VACATION", "Target player is in vacation mode")
+	case errors.Is(err, game.ErrTargetProtected):
+		writeError(w, http.StatusForbidden, "TARGET_PROTECTED", "Target player is under beginner protection")
+	case errors.As(err, &smithy):
+		writeErrorDetails(w, http.StatusForbidden, "SMITHY_LEVEL_TOO_LOW", "Smithy level too low", map[string]interface{}{
+			"required": smithy.Required,
+			"current":  smithy.Current,
+		})
+	case errors.As(err, &notEnough):
+		writeErrorDetails(w, http.StatusForbidden, "NOT_ENOUGH_RESOURCES", "Not enough resources", map[string]interface{}{
+			"cost":      notEnough.Cost,
+			"available": notEnough.Available,
+			"missing":   notEnough.Missing(),
+		})
+	case errors.As(err, &limit):
+		writeErrorDetails(w, http.StatusForbidden, "VILLAGE_LIMIT_REACHED", limit.Error(), map[string]interface{}{
+			"max":     limit.Max,
+			"current": limit.Current,
+		})
+	default:
+		writeInternalError(w, "DB error")
+	}
+}
@@ -0,0 +1,15 @@
+package handlers
+
+import "PawTribalWars/repo"
+
+// Guard to middleware sprawdzające gracza przed handlerem: ważność tokenu,
+// blokady, zastępstwa, urlop i potwierdzony e-mail. Pyta repozytoria, więc
+// w testach stoi na repo/memory tak jak Game.
+type Guard struct {
+	access repo.AccessRepo
+	users  repo.UserRepo
+}
+
+func NewGuard(store repo.Store) *Guard {
+	return &Guard{access: store.Access, users: store.Users}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"PawTribalWars/game"
+	"PawTribalWars/repo/postgres"
+	"PawTribalWars/scheduler"
+)
+
+// typy zdarzeń obsługiwanych przez scheduler
+const (
+	JobCompleteResearch = game.JobCompleteResearch
+	JobDeleteAccount    = "account_delete"
+	JobFinishVacation   = "vacation_finish"
+)
+
+// payload zdarzeń dotyczących konta gracza
+type userJob struct {
+	UserID int `json:"user_id"`
+}
+
+type vacationJob struct {
+	VacationID int `json:"vacation_id"`
+}
+
+// RegisterJobs podpina logikę gry pod scheduler; zmiany zdarzeń idą w jego transakcji
+func RegisterJobs(s *scheduler.Scheduler, g *Game) {
+	s.Register(JobCompleteResearch, func(ctx context.Context, tx *sql.Tx, payload json.RawMessage) error {
+		var job game.ResearchJob
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return err
+		}
+		return g.svc.WithStore(postgres.WithTx(tx)).CompleteResearch(ctx, job.VillageID)
+	})
+	s.Register(JobDeleteAccount, func(ctx context.Context, tx *sql.Tx, payload json.RawMessage) error {
+		var job userJob
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return err
+		}
+		return deleteAccount(tx, job.UserID)
+	})
+	s.Register(JobFinishVacation, func(ctx context.Context, tx *sql.Tx, payload json.RawMessage) error {
+		var job vacationJob
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return err
+		}
+		return g.finishVacationTx(ctx, tx, job.VacationID)
+	})
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"PawTribalWars/db"
+	"PawTribalWars/jwks"
+	"PawTribalWars/repo/postgres"
+)
+
+// =============================
+// GET /.well-known/jwks.json
+// =============================
+func JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	// inne serwisy mogą cache'ować, ale nie dłużej niż trwa rotacja
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(signingKeys.Document())
+}
+
+// =============================
+// POST /admin/keys/rotate (uprawnienie keys:rotate)
+// =============================
+func RotateKeysHandler(w http.ResponseWriter, r *http.Request) {
+	key, err := signingKeys.Rotate()
+	if errors.Is(err, jwks.ErrRotationUnsupported) {
+		writeError(w, http.StatusConflict, "ROTATION_UNSUPPORTED", "Key rotation is not supported for HS256, change jwt.secret instead")
+		return
+	}
+	if err != nil {
+		log.Println("key rotation:", err)
+		writeInternalError(w, "Key rotation failed")
+		return
+	}
+	// nowy klucz zapisuje jwks, więc wpis idzie osobno - po udanej rotacji
+	if err := audit(postgres.New(db.DB).Admin, r, "keys.rotate", "signing_key", key.ID, map[string]interface{}{"algorithm": key.Alg}); err != nil {
+		writeInternalError(w, "DB error on audit log")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":    "Signing key rotated",
+		"kid":        key.ID,
+		"algorithm":  key.Alg,
+		"created_at": key.CreatedAt.Format(time.RFC3339),
+	})
+}
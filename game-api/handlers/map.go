@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"PawTribalWars/db"
+)
+
+// =============================
+// GET /map?page=1&per_page=25
+// =============================
+// Świat nie ma jeszcze współrzędnych, więc mapa to lista wiosek z właścicielem
+// i tym, co inni gracze muszą o nim wiedzieć przed atakiem.
+func GetMapHandler(w http.ResponseWriter, r *http.Request) {
+	page, perPage := parsePagination(r)
+
+	rows, err := db.DB.Query(`
+		SELECT v.id, v.name, v.points, COALESCE(u.id, 0), COALESCE(u.username, ''), COALESCE(t.tag, ''),
+		       (SELECT COALESCE(vac.ended_at, vac.ends_at) FROM vacations vac
+		        WHERE vac.user_id = u.id AND vac.settled_at IS NULL AND vac.starts_at <= NOW()
+		          AND COALESCE(vac.ended_at, vac.ends_at) > NOW()),
+		       CASE WHEN u.protected_until > NOW() THEN u.protected_until END,
+		       COUNT(*) OVER () AS total
+		FROM villages v
+		LEFT JOIN users u ON v.user_id = u.id
+		LEFT JOIN tribes t ON u.tribe_id = t.id
+		ORDER BY v.id
+		LIMIT $1 OFFSET $2
+	`, perPage, (page-1)*perPage)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer rows.Close()
+
+	total := 0
+	results := []map[string]interface{}{}
+	for rows.Next() {
+		var id, points, ownerID int
+		var name, owner, tribe string
+		var vacationUntil, protectedUntil *time.Time
+		rows.Scan(&id, &name, &points, &ownerID, &owner, &tribe, &vacationUntil, &protectedUntil, &total)
+		results = append(results, map[string]interface{}{
+			"id":             id,
+			"name":           name,
+			"points":         points,
+			"owner_id":       ownerID,
+			"owner":          owner,
+			"tribe":          tribe,
+			"on_vacation":    vacationUntil != nil,
+			"vacation_until": vacationUntil,
+			// ochrona początkujących (CheckAttack odrzuca ataki na te wioski)
+			"beginner_protection":       protectedUntil != nil,
+			"beginner_protection_until": protectedUntil,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"page":     page,
+		"per_page": perPage,
+		"total":    total,
+		"results":  results,
+	})
+}
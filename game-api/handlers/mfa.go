@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"PawTribalWars/db"
+	"PawTribalWars/repo/postgres"
+	"PawTribalWars/totp"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// nazwa wyświetlana w aplikacji uwierzytelniającej
+const totpIssuer = "PAW"
+
+// token między hasłem a kodem 2FA; nie daje dostępu do API
+const mfaTokenTTL = 5 * time.Minute
+const mfaAudience = "mfa"
+
+const recoveryCodeCount = 10
+
+type mfaClaims struct {
+	UserID int `json:"uid"`
+	jwt.RegisteredClaims
+}
+
+func issueMFAToken(userID int) (string, time.Time, error) {
+	expiration := time.Now().Add(mfaTokenTTL)
+	claims := &mfaClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        randomToken(16),
+			Audience:  jwt.ClaimStrings{mfaAudience},
+			ExpiresAt: jwt.NewNumericDate(expiration),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	tokenString, err := signingKeys.Sign(claims)
+	return tokenString, expiration, err
+}
+
+// kody zapasowe w postaci "xxxxx-xxxxx"; w bazie tylko skróty
+func generateRecoveryCodes(tx *sql.Tx, userID int) ([]string, error) {
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id=$1", userID); err != nil {
+		return nil, err
+	}
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := randomToken(5)
+		codes[i] = raw[:5] + "-" + raw[5:]
+		_, err := tx.Exec(
+			"INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)",
+			userID, hashToken(normalizeRecoveryCode(codes[i])),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// sprawdza kod z aplikacji albo kod zapasowy; oba działają tylko raz
+func verifySecondFactor(userID int, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		res, err := db.DB.Exec(
+			"UPDATE recovery_codes SET used_at=NOW() WHERE user_id=$1 AND code_hash=$2 AND used_at IS NULL",
+			userID, hashToken(normalizeRecoveryCode(recoveryCode)),
+		)
+		if err != nil {
+			return false, err
+		}
+		n, _ := res.RowsAffected()
+		return n == 1, nil
+	}
+
+	var secret sql.NullString
+	var enabled bool
+	var lastStep int64
+	err := db.DB.QueryRow(
+		"SELECT totp_secret, totp_enabled_at IS NOT NULL, COALESCE(totp_last_step, 0) FROM users WHERE id=$1", userID,
+	).Scan(&secret, &enabled, &lastStep)
+	if err != nil {
+		return false, err
+	}
+	if !enabled || !secret.Valid {
+		return false, nil
+	}
+	step, ok := totp.ValidateAfter(secret.String, code, lastStep, time.Now())
+	if !ok {
+		return false, nil
+	}
+	// ten sam kod (lub starszy) nie przejdzie drugi raz, także przy dwóch
+	// równoległych logowaniach z tym samym kodem
+	res, err := db.DB.Exec("UPDATE users SET totp_last_step=$1 WHERE id=$2 AND totp_last_step < $1", step, userID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// =============================
+// POST /2fa/enroll
+// =============================
+func EnrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	username := r.Context().Value("username").(string)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		writeInternalError(w, "Cannot generate secret")
+		return
+	}
+	// nowy sekret nadpisuje niepotwierdzony; włączonego 2FA nie ruszamy
+	res, err := db.DB.Exec(
+		"UPDATE users SET totp_secret=$1, totp_last_step=0 WHERE id=$2 AND totp_enabled_at IS NULL",
+		secret, userID,
+	)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, http.StatusConflict, "MFA_ALREADY_ENABLED", "Two-factor authentication already enabled")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret":      secret,
+		"otpauth_uri": totp.URI(totpIssuer, username, secret),
+	})
+}
+
+// =============================
+// POST /2fa/confirm (code)
+// =============================
+func ConfirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req struct {
+		Code string `json:"code"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	var secret sql.NullString
+	var enabled bool
+	err := db.DB.QueryRow(
+		"SELECT totp_secret, totp_enabled_at IS NOT NULL FROM users WHERE id=$1", userID,
+	).Scan(&secret, &enabled)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if enabled {
+		writeError(w, http.StatusConflict, "MFA_ALREADY_ENABLED", "Two-factor authentication already enabled")
+		return
+	}
+	if !secret.Valid {
+		writeError(w, http.StatusBadRequest, "MFA_NOT_ENROLLED", "Call /2fa/enroll first")
+		return
+	}
+	step, ok := totp.Validate(secret.String, req.Code, time.Now())
+	if !ok {
+		writeError(w, http.StatusBadRequest, "INVALID_CODE", "Invalid code")
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE users SET totp_enabled_at=NOW(), totp_last_step=$1
+		WHERE id=$2 AND totp_secret=$3 AND totp_enabled_at IS NULL
+	`, step, userID, secret.String)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, http.StatusConflict, "MFA_ENROLLMENT_CHANGED", "Enrollment changed, try again")
+		return
+	}
+	codes, err := generateRecoveryCodes(tx, userID)
+	if err != nil {
+		writeInternalError(w, "DB error on recovery codes")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":        "Two-factor authentication enabled. Store the recovery codes, they are shown only once",
+		"recovery_codes": codes,
+	})
+}
+
+// =============================
+// POST /2fa/recovery-codes (code)
+// =============================
+func RegenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req struct {
+		Code string `json:"code"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	ok, err := verifySecondFactor(userID, req.Code, "")
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusForbidden, "INVALID_CODE", "Invalid code")
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer tx.Rollback()
+	codes, err := generateRecoveryCodes(tx, userID)
+	if err != nil {
+		writeInternalError(w, "DB error on recovery codes")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"recovery_codes": codes})
+}
+
+// =============================
+// POST /2fa/disable (password, code albo recovery_code)
+// =============================
+func DisableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req struct {
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	var hashed string
+	if err := db.DB.QueryRow("SELECT password_hash FROM users WHERE id=$1", userID).Scan(&hashed); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hashed), []byte(req.Password)) != nil {
+		writeError(w, http.StatusForbidden, "INVALID_CREDENTIALS", "Invalid credentials")
+		return
+	}
+	ok, err := verifySecondFactor(userID, req.Code, req.RecoveryCode)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusForbidden, "INVALID_CODE", "Invalid code")
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("UPDATE users SET totp_secret=NULL, totp_enabled_at=NULL, totp_last_step=0 WHERE id=$1", userID); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id=$1", userID); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Two-factor authentication disabled"})
+}
+
+// =============================
+// POST /login/mfa (mfa_token, code albo recovery_code)
+// =============================
+func LoginMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	claims := &mfaClaims{}
+	token, err := jwt.ParseWithClaims(req.MFAToken, claims, signingKeys.Keyfunc,
+		jwt.WithValidMethods(signingKeys.ValidMethods()), jwt.WithAudience(mfaAudience))
+	if err != nil || !token.Valid || claims.ID == "" {
+		writeError(w, http.StatusUnauthorized, "INVALID_TOKEN", "Invalid or expired MFA token")
+		return
+	}
+
+	// błędne kody liczą się do tej samej blokady co błędne hasła
+	var lockedUntil *time.Time
+	if err := db.DB.QueryRow("SELECT locked_until FROM users WHERE id=$1", claims.UserID).Scan(&lockedUntil); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if remaining := loginLockRemaining(lockedUntil); remaining > 0 {
+		writeAccountLocked(w, remaining)
+		return
+	}
+
+	ok, err := verifySecondFactor(claims.UserID, req.Code, req.RecoveryCode)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if !ok {
+		recordLoginFailure(r.Context(), postgres.New(db.DB).Auth, claims.UserID)
+		writeError(w, http.StatusUnauthorized, "INVALID_CODE", "Invalid code")
+		return
+	}
+
+	// token pośredni działa tylko raz
+	res, err := db.DB.Exec(
+		"INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		claims.ID, claims.ExpiresAt.Time,
+	)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, http.StatusUnauthorized, "TOKEN_ALREADY_USED", "MFA token already used")
+		return
+	}
+	resetLoginFailures(r.Context(), postgres.New(db.DB).Auth, claims.UserID)
+
+	// blokada mogła zostać nałożona między hasłem a kodem
+	ban, err := activeBan(r.Context(), claims.UserID)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if ban != nil {
+		writeError(w, http.StatusForbidden, "ACCOUNT_BANNED", banMessage(ban))
+		return
+	}
+
+	var username, role string
+	err = db.DB.QueryRow("SELECT username, role FROM users WHERE id=$1", claims.UserID).Scan(&username, &role)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	writeNewSession(w, r, postgres.New(db.DB).Auth, claims.UserID, username, role)
+}
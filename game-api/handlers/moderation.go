@@ -0,0 +1,454 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"PawTribalWars/db"
+	"PawTribalWars/repo"
+	"PawTribalWars/repo/postgres"
+)
+
+// typy zgłaszanych treści. Wiadomości dojdą razem z ich tabelą: treść dowodu
+// musi pochodzić z serwera, nie od zgłaszającego.
+var reportTargets = map[string]bool{
+	"village": true, // obraźliwa nazwa wioski
+	"user":    true,
+}
+
+// aktywna blokada konta dla handlerów logowania, które działają na db.DB
+func activeBan(ctx context.Context, userID int) (*repo.Ban, error) {
+	return postgres.New(db.DB).Access.ActiveBan(ctx, userID)
+}
+
+// komunikat dla zablokowanego gracza
+func banMessage(ban *repo.Ban) string {
+	if ban.ExpiresAt == nil {
+		return "Account permanently banned: " + ban.Reason
+	}
+	return fmt.Sprintf("Account banned until %s: %s", ban.ExpiresAt.Format(time.RFC3339), ban.Reason)
+}
+
+// czas blokady: "12h", "90m" albo dni "7d"; pusty = na zawsze
+func parseBanDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// =============================
+// POST /admin/users/{id}/ban (reason, duration=7d; bez duration na zawsze)
+// =============================
+func BanUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r)
+	if err != nil {
+		writeFieldError(w, "id", "Invalid user ID")
+		return
+	}
+	var req struct {
+		Reason   string `json:"reason"`
+		Duration string `json:"duration"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	reason := req.Reason
+	if reason == "" {
+		writeFieldError(w, "reason", "Missing ban reason")
+		return
+	}
+	duration, err := parseBanDuration(req.Duration)
+	if err != nil {
+		writeFieldError(w, "duration", err.Error())
+		return
+	}
+	moderatorID := r.Context().Value("user_id").(int)
+	if userID == moderatorID {
+		writeError(w, http.StatusForbidden, "CANNOT_BAN_SELF", "Cannot ban yourself")
+		return
+	}
+
+	// moderator nie może zablokować admina ani innego moderatora
+	var targetRole string
+	err = db.DB.QueryRow("SELECT role FROM users WHERE id=$1", userID).Scan(&targetRole)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if targetRole != RolePlayer && r.Context().Value("role").(string) != RoleAdmin {
+		writeError(w, http.StatusForbidden, "CANNOT_BAN_STAFF", "Only admins can ban staff accounts")
+		return
+	}
+
+	var expiresAt *time.Time
+	if duration > 0 {
+		t := time.Now().Add(duration)
+		expiresAt = &t
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer tx.Rollback()
+
+	var banID int
+	err = tx.QueryRow(
+		"INSERT INTO bans (user_id, reason, banned_by, expires_at) VALUES ($1, $2, $3, $4) RETURNING id",
+		userID, reason, moderatorID, expiresAt,
+	).Scan(&banID)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	// już wydane tokeny blokuje AuthMiddleware, sesje zamykamy od razu
+	_, err = tx.Exec("UPDATE sessions SET revoked_at=NOW() WHERE user_id=$1 AND revoked_at IS NULL", userID)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	if err := audit(postgres.WithTx(tx).Admin, r, "user.ban", "user", userID, map[string]interface{}{
+		"ban_id": banID, "reason": reason, "expires_at": expiresAt,
+	}); err != nil {
+		writeInternalError(w, "DB error on audit log")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":    "User banned",
+		"ban_id":     banID,
+		"expires_at": expiresAt,
+	})
+}
+
+// =============================
+// POST /admin/users/{id}/unban
+// =============================
+func UnbanUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r)
+	if err != nil {
+		writeFieldError(w, "id", "Invalid user ID")
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE bans SET lifted_at=NOW(), lifted_by=$2
+		WHERE user_id=$1 AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+	`, userID, r.Context().Value("user_id").(int))
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, http.StatusNotFound, "BAN_NOT_FOUND", "User has no active ban")
+		return
+	}
+
+	if err := audit(postgres.WithTx(tx).Admin, r, "user.unban", "user", userID, map[string]interface{}{}); err != nil {
+		writeInternalError(w, "DB error on audit log")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Ban lifted"})
+}
+
+// =============================
+// GET /admin/users/{id}/bans
+// =============================
+func BanHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r)
+	if err != nil {
+		writeFieldError(w, "id", "Invalid user ID")
+		return
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT b.id, b.reason, COALESCE(m.username, ''), b.created_at, b.expires_at,
+		       b.lifted_at, COALESCE(l.username, '')
+		FROM bans b
+		LEFT JOIN users m ON b.banned_by = m.id
+		LEFT JOIN users l ON b.lifted_by = l.id
+		WHERE b.user_id=$1
+		ORDER BY b.created_at DESC
+	`, userID)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer rows.Close()
+
+	bans := []map[string]interface{}{}
+	for rows.Next() {
+		var id int
+		var reason, bannedBy, liftedBy string
+		var createdAt time.Time
+		var expiresAt, liftedAt *time.Time
+		rows.Scan(&id, &reason, &bannedBy, &createdAt, &expiresAt, &liftedAt, &liftedBy)
+		active := liftedAt == nil && (expiresAt == nil || expiresAt.After(time.Now()))
+		bans = append(bans, map[string]interface{}{
+			"id":         id,
+			"reason":     reason,
+			"banned_by":  bannedBy,
+			"created_at": createdAt,
+			"expires_at": expiresAt,
+			"lifted_at":  liftedAt,
+			"lifted_by":  liftedBy,
+			"active":     active,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_id": userID,
+		"bans":    bans,
+	})
+}
+
+// =============================
+// POST /reports (target_type=village|user, target_id, reason)
+// =============================
+func CreateReportHandler(w http.ResponseWriter, r *http.Request) {
+	reporterID := r.Context().Value("user_id").(int)
+	var req struct {
+		TargetType string `json:"target_type"`
+		TargetID   int    `json:"target_id"`
+		Reason     string `json:"reason"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	targetType, targetID, reason := req.TargetType, req.TargetID, req.Reason
+	if targetID < 1 {
+		writeFieldError(w, "target_id", "Invalid target_id")
+		return
+	}
+	if !reportTargets[targetType] {
+		writeFieldError(w, "target_type", "Invalid target_type")
+		return
+	}
+	if reason == "" {
+		writeFieldError(w, "reason", "Missing reason")
+		return
+	}
+
+	// zapamiętujemy zgłaszaną treść - gracz może ją potem zmienić
+	var snapshot string
+	var err error
+	switch targetType {
+	case "village":
+		err = db.DB.QueryRow("SELECT name FROM villages WHERE id=$1", targetID).Scan(&snapshot)
+	case "user":
+		err = db.DB.QueryRow("SELECT username FROM users WHERE id=$1", targetID).Scan(&snapshot)
+	}
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, "TARGET_NOT_FOUND", "Reported "+targetType+" not found")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	var reportID int
+	err = db.DB.QueryRow(`
+		INSERT INTO reports (reporter_id, target_type, target_id, target_snapshot, reason)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id
+	`, reporterID, targetType, targetID, snapshot, reason).Scan(&reportID)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "Report submitted",
+		"report_id": reportID,
+	})
+}
+
+// =============================
+// GET /admin/reports?status=open&page=1
+// =============================
+func ListReportsHandler(w http.ResponseWriter, r *http.Request) {
+	page, perPage := parsePagination(r)
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = "open"
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT rp.id, COALESCE(u.username, ''), rp.target_type, rp.target_id, rp.target_snapshot,
+		       rp.reason, rp.status, COALESCE(h.username, ''), COALESCE(rp.resolution_note, ''),
+		       rp.created_at, rp.handled_at, COUNT(*) OVER () AS total
+		FROM reports rp
+		LEFT JOIN users u ON rp.reporter_id = u.id
+		LEFT JOIN users h ON rp.handled_by = h.id
+		WHERE rp.status=$1
+		ORDER BY rp.created_at
+		LIMIT $2 OFFSET $3
+	`, status, perPage, (page-1)*perPage)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer rows.Close()
+
+	total := 0
+	reports := []map[string]interface{}{}
+	for rows.Next() {
+		var id, targetID int
+		var reporter, targetType, snapshot, reason, rStatus, handledBy, note string
+		var createdAt time.Time
+		var handledAt *time.Time
+		rows.Scan(&id, &reporter, &targetType, &targetID, &snapshot, &reason, &rStatus,
+			&handledBy, &note, &createdAt, &handledAt, &total)
+		reports = append(reports, map[string]interface{}{
+			"id":              id,
+			"reporter":        reporter,
+			"target_type":     targetType,
+			"target_id":       targetID,
+			"target_snapshot": snapshot,
+			"reason":          reason,
+			"status":          rStatus,
+			"handled_by":      handledBy,
+			"resolution_note": note,
+			"created_at":      createdAt,
+			"handled_at":      handledAt,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"page":     page,
+		"per_page": perPage,
+		"total":    total,
+		"results":  reports,
+	})
+}
+
+// =============================
+// POST /admin/reports/{id}/resolve (action=dismiss|resolve|rename_village, note)
+// =============================
+func ResolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	reportID, err := pathID(r)
+	if err != nil {
+		writeFieldError(w, "id", "Invalid report ID")
+		return
+	}
+	var req struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	action, note := req.Action, req.Note
+
+	var targetType, status string
+	var targetID int
+	err = db.DB.QueryRow("SELECT target_type, target_id, status FROM reports WHERE id=$1", reportID).
+		Scan(&targetType, &targetID, &status)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, "REPORT_NOT_FOUND", "Report not found")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if status != "open" {
+		writeError(w, http.StatusConflict, "REPORT_ALREADY_HANDLED", "Report already handled")
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer tx.Rollback()
+
+	var newStatus string
+	switch action {
+	case "dismiss":
+		newStatus = "dismissed"
+	case "resolve", "rename_village":
+		newStatus = "resolved"
+	default:
+		writeFieldError(w, "action", "action must be dismiss, resolve or rename_village")
+		return
+	}
+	if action == "rename_village" && targetType != "village" {
+		writeFieldError(w, "action", "rename_village only applies to village reports")
+		return
+	}
+
+	// status w warunku: decyzja innego moderatora w międzyczasie daje 409, a nie nadpisanie
+	res, err := tx.Exec(`
+		UPDATE reports SET status=$1, handled_by=$2, resolution_note=$3, handled_at=NOW()
+		WHERE id=$4 AND status='open'
+	`, newStatus, r.Context().Value("user_id").(int), note, reportID)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, http.StatusConflict, "REPORT_ALREADY_HANDLED", "Report already handled")
+		return
+	}
+
+	if action == "rename_village" {
+		// obraźliwa nazwa zastąpiona neutralną
+		_, err = tx.Exec("UPDATE villages SET name=$1 WHERE id=$2", fmt.Sprintf("Wioska %d", targetID), targetID)
+		if err != nil {
+			writeInternalError(w, "DB error")
+			return
+		}
+	}
+
+	if err := audit(postgres.WithTx(tx).Admin, r, "report."+action, "report", reportID, map[string]interface{}{
+		"target_type": targetType, "target_id": targetID, "note": note,
+	}); err != nil {
+		writeInternalError(w, "DB error on audit log")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Report " + newStatus})
+}
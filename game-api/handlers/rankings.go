@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"PawTribalWars/db"
+	"PawTribalWars/repo/postgres"
+)
+
+// parametry stronicowania: ?page=1&per_page=25
+func parsePagination(r *http.Request) (page, perPage int) {
+	page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ = strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage < 1 {
+		perPage = 25
+	}
+	if perPage > 100 {
+		perPage = 100
+	}
+	return page, perPage
+}
+
+// =============================
+// GET /rankings/players?page=1&per_page=25&search=name
+// =============================
+func GetPlayerRankingHandler(w http.ResponseWriter, r *http.Request) {
+	playerRanking(w, r, "points")
+}
+
+// =============================
+// GET /rankings/oda?page=1&per_page=25&search=name
+// =============================
+func GetODARankingHandler(w http.ResponseWriter, r *http.Request) {
+	playerRanking(w, r, "kills_att")
+}
+
+// =============================
+// GET /rankings/odd?page=1&per_page=25&search=name
+// =============================
+func GetODDRankingHandler(w http.ResponseWriter, r *http.Request) {
+	playerRanking(w, r, "kills_def")
+}
+
+// wspólny ranking graczy; column pochodzi wyłącznie z handlerów powyżej
+func playerRanking(w http.ResponseWriter, r *http.Request, column string) {
+	page, perPage := parsePagination(r)
+	search := postgres.EscapeLike(r.URL.Query().Get("search"))
+
+	// pozycja liczona w całym rankingu, dopiero potem filtr po nazwie
+	rows, err := db.DB.Query(`
+		WITH ranked AS (
+			SELECT u.id, u.username, COALESCE(t.tag, '') AS tribe, COALESCE(u.`+column+`, 0) AS score,
+			       (SELECT COUNT(*) FROM villages v WHERE v.user_id = u.id) AS villages,
+			       RANK() OVER (ORDER BY COALESCE(u.`+column+`, 0) DESC) AS rank
+			FROM users u
+			LEFT JOIN tribes t ON u.tribe_id = t.id
+			WHERE u.deleted_at IS NULL
+		)
+		SELECT rank, id, username, tribe, score, villages, COUNT(*) OVER () AS total
+		FROM ranked
+		WHERE $1 = '' OR username ILIKE '%' || $1 || '%'
+		ORDER BY rank, id
+		LIMIT $2 OFFSET $3
+	`, search, perPage, (page-1)*perPage)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer rows.Close()
+
+	total := 0
+	results := []map[string]interface{}{}
+	for rows.Next() {
+		var rank, id, villages int
+		var score int64
+		var username, tribe string
+		rows.Scan(&rank, &id, &username, &tribe, &score, &villages, &total)
+		results = append(results, map[string]interface{}{
+			"rank":     rank,
+			"id":       id,
+			"username": username,
+			"tribe":    tribe,
+			column:     score,
+			"villages": villages,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"page":     page,
+		"per_page": perPage,
+		"total":    total,
+		"results":  results,
+	})
+}
+
+// =============================
+// GET /rankings/tribes?page=1&per_page=25&search=name
+// =============================
+func GetTribeRankingHandler(w http.ResponseWriter, r *http.Request) {
+	page, perPage := parsePagination(r)
+	search := postgres.EscapeLike(r.URL.Query().Get("search"))
+
+	// punkty plemienia to suma punktów członków (bez kont usuniętych)
+	rows, err := db.DB.Query(`
+		WITH ranked AS (
+			SELECT t.id, t.name, t.tag,
+			       COUNT(u.id) AS members,
+			       COALESCE(SUM(u.points), 0) AS points,
+			       RANK() OVER (ORDER BY COALESCE(SUM(u.points), 0) DESC) AS rank
+			FROM tribes t
+			LEFT JOIN users u ON u.tribe_id = t.id AND u.deleted_at IS NULL
+			GROUP BY t.id
+		)
+		SELECT rank, id, name, tag, members, points, COUNT(*) OVER () AS total
+		FROM ranked
+		WHERE $1 = '' OR name ILIKE '%' || $1 || '%' OR tag ILIKE '%' || $1 || '%'
+		ORDER BY rank, id
+		LIMIT $2 OFFSET $3
+	`, search, perPage, (page-1)*perPage)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer rows.Close()
+
+	total := 0
+	results := []map[string]interface{}{}
+	for rows.Next() {
+		var rank, id, members, points int
+		var name, tag string
+		rows.Scan(&rank, &id, &name, &tag, &members, &points, &total)
+		results = append(results, map[string]interface{}{
+			"rank":    rank,
+			"id":      id,
+			"name":    name,
+			"tag":     tag,
+			"members": members,
+			"points":  points,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"page":     page,
+		"per_page": perPage,
+		"total":    total,
+		"results":  results,
+	})
+}
+
+// =============================
+// GET /rankings/villages?page=1&per_page=25&search=name
+// =============================
+func GetVillageRankingHandler(w http.ResponseWriter, r *http.Request) {
+	page, perPage := parsePagination(r)
+	search := postgres.EscapeLike(r.URL.Query().Get("search"))
+
+	// wioski barbarzyńskie też są w rankingu (bez właściciela);
+	// search pasuje do nazwy wioski albo właściciela
+	rows, err := db.DB.Query(`
+		WITH ranked AS (
+			SELECT v.id, v.name, v.points, COALESCE(u.id, 0) AS owner_id, COALESCE(u.username, '') AS owner,
+			       RANK() OVER (ORDER BY v.points DESC) AS rank
+			FROM villages v
+			LEFT JOIN users u ON v.user_id = u.id
+		)
+		SELECT rank, id, name, points, owner_id, owner, COUNT(*) OVER () AS total
+		FROM ranked
+		WHERE $1 = '' OR name ILIKE '%' || $1 || '%' OR owner ILIKE '%' || $1 || '%'
+		ORDER BY rank, id
+		LIMIT $2 OFFSET $3
+	`, search, perPage, (page-1)*perPage)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer rows.Close()
+
+	total := 0
+	results := []map[string]interface{}{}
+	for rows.Next() {
+		var rank, id, points, ownerID int
+		var name, owner string
+		rows.Scan(&rank, &id, &name, &points, &ownerID, &owner, &total)
+		results = append(results, map[string]interface{}{
+			"rank":     rank,
+			"id":       id,
+			"name":     name,
+			"points":   points,
+			"owner_id": ownerID,
+			"owner":    owner,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"page":     page,
+		"per_page": perPage,
+		"total":    total,
+		"results":  results,
+	})
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"PawTribalWars/config"
+	"PawTribalWars/ratelimit"
+	"PawTribalWars/repo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// limity żądań (nadpisywane w Configure)
+var (
+	rateLimitEnabled                 = true
+	rateStore        ratelimit.Store = ratelimit.NewMemoryStore()
+	rateLimits                       = limitsFromConfig(config.Default().RateLimit)
+
+	lockoutThreshold = 5
+	lockoutBase      = time.Minute
+	lockoutMax       = time.Hour
+)
+
+// ścieżki (bez prefiksu /api/v1) z własną, ostrzejszą polityką; reszta to read/write wg metody
+var routePolicies = map[string]string{
+	"/login":            "login",
+	"/login/mfa":        "login",
+	"/register":         "register",
+	"/password/forgot":  "password",
+	"/password/reset":   "password",
+	"/email/verify":     "password",
+	"/me/email/confirm": "password",
+	"/me/password":      "password",
+	"/refresh":          "refresh",
+}
+
+// UseRateLimitStore ustawia magazyn wiader (pamięć albo Postgres dla wielu instancji)
+func UseRateLimitStore(s ratelimit.Store) {
+	rateStore = s
+}
+
+func limitsFromConfig(cfg config.RateLimitConfig) map[string]ratelimit.Limit {
+	limits := map[string]ratelimit.Limit{}
+	for name, l := range cfg.Limits {
+		limits[name] = ratelimit.PerMinute(l.PerMinute, l.Burst)
+	}
+	return limits
+}
+
+func readOrWrite(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return "read"
+	}
+	return "write"
+}
+
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+}
+
+// pobiera token z wiadra; false = odpowiedź 429 została już wysłana
+func takeToken(w http.ResponseWriter, key, policy string) bool {
+	if !rateLimitEnabled {
+		return true
+	}
+	limit, ok := rateLimits[policy]
+	if !ok {
+		return true
+	}
+	allowed, retryAfter, err := rateStore.Take(policy+":"+key, limit)
+	if err != nil {
+		// awaria magazynu nie może zablokować całej gry
+		log.Println("rate limit:", err)
+		return true
+	}
+	if !allowed {
+		setRetryAfter(w, retryAfter)
+		writeError(w, http.StatusTooManyRequests, "RATE_LIMITED", "Too many requests")
+		return false
+	}
+	return true
+}
+
+// RateLimitMiddleware ogranicza żądania z jednego IP; limity per gracz nakłada AuthMiddleware
+func RateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy, ok := routePolicies[strings.TrimPrefix(r.URL.Path, APIPrefix)]
+		if !ok {
+			policy = readOrWrite(r)
+		}
+		if !takeToken(w, "ip:"+clientIP(r), policy) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ile jeszcze trwa blokada konta po nieudanych logowaniach
+func loginLockRemaining(lockedUntil *time.Time) time.Duration {
+	if lockedUntil == nil {
+		return 0
+	}
+	if d := time.Until(*lockedUntil); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// blokada logowania wg konfiguracji
+func lockout() repo.Lockout {
+	return repo.Lockout{Threshold: lockoutThreshold, Base: lockoutBase, Max: lockoutMax}
+}
+
+// nieudane logowanie; od progu konto blokuje się na coraz dłużej
+func recordLoginFailure(ctx context.Context, auth repo.AuthRepo, userID int) {
+	if err := auth.RecordLoginFailure(ctx, userID, lockout()); err != nil {
+		log.Println("login failure:", err)
+	}
+}
+
+// porównanie hasła dla nieistniejącego gracza, żeby odpowiedź nie była szybsza
+var unknownUserHash, _ = bcrypt.GenerateFromPassword([]byte("no such player"), bcrypt.DefaultCost)
+
+// po ilu dniach bez prób zapominamy nieudane logowania na nieistniejące nazwy
+const unknownLoginRetention = 30 * 24 * time.Hour
+
+// ile jeszcze trwa blokada nieistniejącej nazwy gracza; nazwa trzymana jest tylko jako skrót
+func unknownLoginLockRemaining(ctx context.Context, auth repo.AuthRepo, username string) (time.Duration, error) {
+	lockedUntil, err := auth.UnknownLoginLockedUntil(ctx, hashToken(username))
+	return loginLockRemaining(lockedUntil), err
+}
+
+// nieudane logowanie na nieistniejącą nazwę liczy się jak w recordLoginFailure
+func recordUnknownLoginFailure(ctx context.Context, auth repo.AuthRepo, username string) {
+	if err := auth.RecordUnknownLoginFailure(ctx, hashToken(username), lockout(), unknownLoginRetention); err != nil {
+		log.Println("login failure:", err)
+	}
+}
+
+func resetLoginFailures(ctx context.Context, auth repo.AuthRepo, userID int) {
+	if err := auth.ResetLoginFailures(ctx, userID); err != nil {
+		log.Println("login failure reset:", err)
+	}
+}
+
+func writeAccountLocked(w http.ResponseWriter, remaining time.Duration) {
+	setRetryAfter(w, remaining)
+	writeError(w, http.StatusTooManyRequests, "ACCOUNT_LOCKED", "Account temporarily locked after failed logins")
+}
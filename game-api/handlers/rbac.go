@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"PawTribalWars/repo"
+)
+
+// role graczy
+const (
+	RolePlayer    = "player"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// uprawnienia sprawdzane przez RequirePermission
+const (
+	PermViewUsers     = "users:view"
+	PermChangeRoles   = "users:change_role"
+	PermBanUsers      = "users:ban"
+	PermViewVillages  = "villages:view_any"
+	PermEditVillages  = "villages:edit_any"
+	PermViewAudit     = "audit:view"
+	PermRotateKeys    = "keys:rotate"
+	PermHandleReports = "reports:handle"
+)
+
+var rolePermissions = map[string][]string{
+	RolePlayer:    {},
+	RoleModerator: {PermViewUsers, PermViewVillages, PermBanUsers, PermHandleReports},
+	RoleAdmin: {
+		PermViewUsers, PermChangeRoles, PermBanUsers,
+		PermViewVillages, PermEditVillages,
+		PermViewAudit, PermRotateKeys, PermHandleReports,
+	},
+}
+
+func isValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func hasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireRole przepuszcza tylko podane role; używać po AuthMiddleware
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, _ := r.Context().Value("role").(string)
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			writeError(w, http.StatusForbidden, "INSUFFICIENT_ROLE", "Insufficient role")
+		})
+	}
+}
+
+// RequirePermission przepuszcza role mające dane uprawnienie; używać po AuthMiddleware
+func RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, _ := r.Context().Value("role").(string)
+			if !hasPermission(role, permission) {
+				writeError(w, http.StatusForbidden, "MISSING_PERMISSION", "Missing permission "+permission)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// zapisuje akcję administracyjną w audit_log; log to repozytorium transakcji
+// samej zmiany, żeby zmiana bez wpisu (i wpis bez zmiany) nie mogły się zdarzyć
+func audit(log repo.AdminRepo, r *http.Request, action, targetType string, targetID interface{}, details map[string]interface{}) error {
+	raw, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	return log.Audit(r.Context(), repo.AuditEntry{
+		ActorID:    r.Context().Value("user_id").(int),
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   fmt.Sprint(targetID),
+		Details:    raw,
+		IP:         clientIP(r),
+	})
+}
@@ -3,62 +3,32 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
-	"strconv"
 	"time"
 
-	"PawTribalWars/db"
+	"PawTribalWars/game"
 )
 
-// GET /resources?village_id=1
-func GetResourcesHandler(w http.ResponseWriter, r *http.Request) {
-	villageIDStr := r.URL.Query().Get("village_id")
-	if villageIDStr == "" {
-		http.Error(w, "Missing village_id", http.StatusBadRequest)
-		return
-	}
-	villageID, err := strconv.Atoi(villageIDStr)
-	if err != nil {
-		http.Error(w, "Invalid village_id", http.StatusBadRequest)
+// GET /api/v1/villages/{id}/resources (stara trasa: GET /resources?village_id=1)
+func (g *Game) GetResourcesHandler(w http.ResponseWriter, r *http.Request) {
+	fields := fieldErrors{}
+	villageID := fields.villageID(r)
+	if fields.write(w) {
 		return
 	}
 
-	var wood, clay, iron int
-	var updatedAt time.Time
-
-	// pobieramy aktualne zasoby i timestamp
-	err = db.DB.QueryRow(
-		"SELECT wood, clay, iron, updated_at FROM resources WHERE village_id=$1",
-		villageID,
-	).Scan(&wood, &clay, &iron, &updatedAt)
-	if err != nil {
-		http.Error(w, "Resources not found", http.StatusNotFound)
+	res, err := g.svc.Resources(r.Context(), villageID, time.Now())
+	if err == game.ErrVillageNotFound {
+		writeError(w, http.StatusNotFound, "VILLAGE_NOT_FOUND", "Resources not found")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
 		return
-	}
-
-	// pobieramy poziomy budynków
-	var lumberLvl, clayLvl, ironLvl int
-	_ = db.DB.QueryRow("SELECT level FROM buildings WHERE village_id=$1 AND type='lumbermill'", villageID).Scan(&lumberLvl)
-	_ = db.DB.QueryRow("SELECT level FROM buildings WHERE village_id=$1 AND type='claypit'", villageID).Scan(&clayLvl)
-	_ = db.DB.QueryRow("SELECT level FROM buildings WHERE village_id=$1 AND type='ironmine'", villageID).Scan(&ironLvl)
-
-	// ile minut minęło od ostatniego update
-	elapsedMinutes := int(time.Since(updatedAt).Minutes())
-	if elapsedMinutes > 0 {
-		wood += lumberLvl * 5 * elapsedMinutes
-		clay += clayLvl * 5 * elapsedMinutes
-		iron += ironLvl * 5 * elapsedMinutes
-
-		// zapisujemy nowy stan
-		_, _ = db.DB.Exec(
-			"UPDATE resources SET wood=$1, clay=$2, iron=$3, updated_at=NOW() WHERE village_id=$4",
-			wood, clay, iron, villageID,
-		)
 	}
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"village_id": villageID,
-		"wood":       wood,
-		"clay":       clay,
-		"iron":       iron,
+		"wood":       res.Wood,
+		"clay":       res.Clay,
+		"iron":       res.Iron,
 	})
 }
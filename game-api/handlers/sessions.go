@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"PawTribalWars/db"
+	"PawTribalWars/repo"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+)
+
+// losowy token w postaci hex
+func randomToken(bytes int) string {
+	b := make([]byte, bytes)
+	if _, err := rand.Read(b); err != nil {
+		panic("crypto/rand failed: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// w bazie trzymamy tylko skrót tokenu
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// krótko żyjący access token powiązany z sesją
+func issueAccessToken(userID int, username, role, sessionID string) (string, time.Time, error) {
+	expiration := time.Now().Add(accessTTL)
+	claims := &Claims{
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        randomToken(16),
+			ExpiresAt: jwt.NewNumericDate(expiration),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	tokenString, err := signingKeys.Sign(claims)
+	return tokenString, expiration, err
+}
+
+// nowa sesja urządzenia; refresh token ma postać "<id sesji>.<sekret>"
+func createSession(auth repo.AuthRepo, userID int, r *http.Request) (sessionID, refreshToken string, err error) {
+	sessionID = randomToken(16)
+	secret := randomToken(32)
+	err = auth.CreateSession(r.Context(), repo.NewSession{
+		ID:          sessionID,
+		UserID:      userID,
+		RefreshHash: hashToken(secret),
+		UserAgent:   r.UserAgent(),
+		IP:          clientIP(r),
+		TTL:         refreshTTL,
+	})
+	return sessionID, sessionID + "." + secret, err
+}
+
+// wydaje parę tokenów dla nowej sesji i wysyła ją klientowi
+func writeNewSession(w http.ResponseWriter, r *http.Request, auth repo.AuthRepo, userID int, username, role string) {
+	sessionID, refreshToken, err := createSession(auth, userID, r)
+	if err != nil {
+		writeInternalError(w, "DB error on session")
+		return
+	}
+	accessToken, expiresAt, err := issueAccessToken(userID, username, role, sessionID)
+	if err != nil {
+		writeInternalError(w, "Cannot sign token")
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":         accessToken,
+		"expires_at":    expiresAt,
+		"refresh_token": refreshToken,
+	})
+}
+
+// clientIP to adres klienta dla limitów i sesji. X-Forwarded-For czytamy tylko,
+// gdy połączenie przyszło od zaufanego proxy: od prawej pomijamy kolejne zaufane
+// proxy, pierwszy inny adres to klient. Wpisy dalej w lewo mógł podać sam klient.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !isTrustedProxy(host) {
+		return host
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break // śmieci w nagłówku - zostajemy przy ostatnim pewnym adresie
+		}
+		host = hop
+		if !isTrustedProxy(hop) {
+			break
+		}
+	}
+	return host
+}
+
+func isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// =============================
+// POST /refresh (refresh_token)
+// =============================
+func RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	sessionID, secret, ok := strings.Cut(req.RefreshToken, ".")
+	if !ok || sessionID == "" || secret == "" {
+		writeError(w, http.StatusUnauthorized, "INVALID_TOKEN", "Invalid refresh token")
+		return
+	}
+
+	var userID int
+	var username, role, storedHash string
+	var revoked bool
+	err := db.DB.QueryRow(`
+		SELECT s.user_id, u.username, u.role, s.refresh_hash,
+		       (s.revoked_at IS NOT NULL OR s.expires_at <= NOW())
+		FROM sessions s
+		JOIN users u ON s.user_id = u.id
+		WHERE s.id=$1 AND s.sitter_id IS NULL
+	`, sessionID).Scan(&userID, &username, &role, &storedHash, &revoked)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusUnauthorized, "INVALID_TOKEN", "Invalid refresh token")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if revoked {
+		writeError(w, http.StatusUnauthorized, "SESSION_EXPIRED", "Session expired or revoked")
+		return
+	}
+
+	// stary refresh token użyty ponownie = wyciek, zamykamy całą sesję
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(storedHash)) != 1 {
+		_, _ = db.DB.Exec("UPDATE sessions SET revoked_at=NOW() WHERE id=$1", sessionID)
+		writeError(w, http.StatusUnauthorized, "TOKEN_REUSE_DETECTED", "Refresh token reuse detected, session revoked")
+		return
+	}
+
+	// rotacja: każdy refresh token działa tylko raz
+	newSecret := randomToken(32)
+	res, err := db.DB.Exec(`
+		UPDATE sessions SET refresh_hash=$1, last_used_at=NOW(), ip=$2, user_agent=$3
+		WHERE id=$4 AND refresh_hash=$5
+	`, hashToken(newSecret), clientIP(r), r.UserAgent(), sessionID, storedHash)
+	if err != nil {
+		writeInternalError(w, "DB error on session")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// równoległy refresh tym samym tokenem wygrał wyścig
+		writeError(w, http.StatusUnauthorized, "INVALID_TOKEN", "Invalid refresh token")
+		return
+	}
+
+	accessToken, expiresAt, err := issueAccessToken(userID, username, role, sessionID)
+	if err != nil {
+		writeInternalError(w, "Cannot sign token")
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":         accessToken,
+		"expires_at":    expiresAt,
+		"refresh_token": sessionID + "." + newSecret,
+	})
+}
+
+// =============================
+// POST /logout-all
+// =============================
+func LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	res, err := db.DB.Exec("UPDATE sessions SET revoked_at=NOW() WHERE user_id=$1 AND revoked_at IS NULL", userID)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	n, _ := res.RowsAffected()
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":          "All sessions revoked",
+		"revoked_sessions": n,
+	})
+}
+
+// =============================
+// GET /sessions
+// =============================
+func GetSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	currentID := r.Context().Value("session_id").(string)
+
+	rows, err := db.DB.Query(`
+		SELECT id, COALESCE(user_agent, ''), COALESCE(ip, ''), created_at, last_used_at, expires_at
+		FROM sessions
+		WHERE user_id=$1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC
+	`, userID)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer rows.Close()
+
+	sessions := []map[string]interface{}{}
+	for rows.Next() {
+		var id, userAgent, ip string
+		var createdAt, lastUsedAt, expiresAt time.Time
+		rows.Scan(&id, &userAgent, &ip, &createdAt, &lastUsedAt, &expiresAt)
+		sessions = append(sessions, map[string]interface{}{
+			"id":           id,
+			"user_agent":   userAgent,
+			"ip":           ip,
+			"created_at":   createdAt,
+			"last_used_at": lastUsedAt,
+			"expires_at":   expiresAt,
+			"current":      id == currentID,
+		})
+	}
+
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// =============================
+// DELETE /sessions/{id}
+// =============================
+func RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	sessionID := mux.Vars(r)["id"]
+
+	res, err := db.DB.Exec(
+		"UPDATE sessions SET revoked_at=NOW() WHERE id=$1 AND user_id=$2 AND revoked_at IS NULL",
+		sessionID, userID,
+	)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, http.StatusNotFound, "SESSION_NOT_FOUND", "Session not found")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Session revoked"})
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"PawTribalWars/config"
+)
+
+func TestClientIPTrustsOnlyConfiguredProxies(t *testing.T) {
+	proxies, err := config.ParsePrefixes([]string{"10.0.0.0/8", "192.168.1.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := trustedProxies
+	trustedProxies = proxies
+	t.Cleanup(func() { trustedProxies = old })
+
+	cases := []struct {
+		remote, forwarded, want string
+	}{
+		{"203.0.113.7:5000", "", "203.0.113.7"},
+		// bez zaufanego proxy nagłówek to tylko deklaracja klienta
+		{"203.0.113.7:5000", "1.2.3.4", "203.0.113.7"},
+		{"10.1.2.3:5000", "198.51.100.9", "198.51.100.9"},
+		// klient dopisał własny wpis przed prawdziwym adresem
+		{"10.1.2.3:5000", "1.2.3.4, 198.51.100.9", "198.51.100.9"},
+		// łańcuch zaufanych proxy
+		{"10.1.2.3:5000", "198.51.100.9, 192.168.1.5, 10.9.9.9", "198.51.100.9"},
+		{"10.1.2.3:5000", "not-an-ip", "10.1.2.3"},
+		{"10.1.2.3:5000", "", "10.1.2.3"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = c.remote
+		if c.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", c.forwarded)
+		}
+		if got := clientIP(r); got != c.want {
+			t.Errorf("remote %s, X-Forwarded-For %q: got %s, want %s", c.remote, c.forwarded, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"PawTribalWars/battle"
+)
+
+// =============================
+// POST /simulator
+// {"attacker": {"swordsman": 100}, "defender": {"spearman": 150}, "wall_level": 5, "seed": 42}
+// =============================
+func SimulatorHandler(w http.ResponseWriter, r *http.Request) {
+	var in battle.Input
+	// mapy jednostek nie mają postaci formularza, więc obie trasy czytają JSON
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+
+	if problems := in.Validate(); len(problems) > 0 {
+		writeErrorDetails(w, http.StatusBadRequest, CodeValidationFailed, "Invalid simulation input", map[string]interface{}{
+			"errors": problems,
+		})
+		return
+	}
+
+	result, err := battle.Simulate(in)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationFailed, err.Error())
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
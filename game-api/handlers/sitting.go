@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"PawTribalWars/db"
+	"PawTribalWars/repo"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+)
+
+// najdłuższe zastępstwo, jakie można ustawić jednym wpisem
+const maxSittingDuration = 14 * 24 * time.Hour
+
+// DenySitters blokuje akcje zastrzeżone dla właściciela konta; używać po AuthMiddleware
+func DenySitters(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sitterID, _ := r.Context().Value("sitter_id").(int); sitterID != 0 {
+			writeError(w, http.StatusForbidden, "NOT_ALLOWED_WHILE_SITTING", "Not allowed while sitting")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// zapamiętuje kod odpowiedzi do dziennika zastępcy
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// zapisuje akcję zastępcy; odczyty (GET) pomijamy, liczą się zmiany na koncie
+func (g *Guard) logSitterAction(next http.Handler, w http.ResponseWriter, r *http.Request, ownerID, sitterID int) {
+	if readOrWrite(r) == "read" {
+		next.ServeHTTP(w, r)
+		return
+	}
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	next.ServeHTTP(rec, r)
+	err := g.access.LogSitterAction(r.Context(), repo.SitterAction{
+		OwnerID: ownerID, SitterID: sitterID,
+		Method: r.Method, Path: r.URL.Path, Status: rec.status, IP: clientIP(r),
+	})
+	if err != nil {
+		log.Println("sitter action log:", err)
+	}
+}
+
+// =============================
+// POST /sitters (sitter = nazwa gracza, hours)
+// =============================
+func AddSitterHandler(w http.ResponseWriter, r *http.Request) {
+	ownerID := r.Context().Value("user_id").(int)
+	var req struct {
+		Sitter string `json:"sitter"`
+		Hours  int    `json:"hours"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	hours := req.Hours
+	if hours < 1 {
+		writeFieldError(w, "hours", "hours must be a positive integer")
+		return
+	}
+	duration := time.Duration(hours) * time.Hour
+	if duration > maxSittingDuration {
+		writeFieldError(w, "hours", "Sitting period too long (max 14 days)")
+		return
+	}
+
+	var sitterID int
+	err := db.DB.QueryRow(
+		"SELECT id FROM users WHERE username=$1 AND deleted_at IS NULL", req.Sitter,
+	).Scan(&sitterID)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, "SITTER_NOT_FOUND", "Sitter not found")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if sitterID == ownerID {
+		writeError(w, http.StatusBadRequest, "CANNOT_SIT_OWN_ACCOUNT", "Cannot sit your own account")
+		return
+	}
+
+	// jedno aktywne zastępstwo naraz
+	var sittingID int
+	var endsAt time.Time
+	err = db.DB.QueryRow(`
+		INSERT INTO sitters (owner_id, sitter_id, starts_at, ends_at)
+		SELECT $1, $2, NOW(), NOW() + $3 * INTERVAL '1 second'
+		WHERE NOT EXISTS(SELECT 1 FROM sitters WHERE owner_id=$1 AND revoked_at IS NULL AND ends_at > NOW())
+		RETURNING id, ends_at
+	`, ownerID, sitterID, duration.Seconds()).Scan(&sittingID, &endsAt)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusConflict, "SITTER_ALREADY_ACTIVE", "You already have an active sitter")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Sitter added",
+		"id":      sittingID,
+		"ends_at": endsAt,
+	})
+}
+
+// =============================
+// GET /sitters - moi zastępcy i konta, które mogę zastępować
+// =============================
+func GetSittersHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	rows, err := db.DB.Query(`
+		SELECT s.id, s.owner_id, o.username, s.sitter_id, t.username, s.starts_at, s.ends_at
+		FROM sitters s
+		JOIN users o ON s.owner_id = o.id
+		JOIN users t ON s.sitter_id = t.id
+		WHERE (s.owner_id=$1 OR s.sitter_id=$1) AND s.revoked_at IS NULL AND s.ends_at > NOW()
+		ORDER BY s.ends_at
+	`, userID)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer rows.Close()
+
+	mySitters := []map[string]interface{}{}
+	sitting := []map[string]interface{}{}
+	for rows.Next() {
+		var id, ownerID, sitterID int
+		var owner, sitter string
+		var startsAt, endsAt time.Time
+		rows.Scan(&id, &ownerID, &owner, &sitterID, &sitter, &startsAt, &endsAt)
+		entry := map[string]interface{}{
+			"id":        id,
+			"owner_id":  ownerID,
+			"owner":     owner,
+			"sitter_id": sitterID,
+			"sitter":    sitter,
+			"starts_at": startsAt,
+			"ends_at":   endsAt,
+		}
+		if ownerID == userID {
+			mySitters = append(mySitters, entry)
+		} else {
+			sitting = append(sitting, entry)
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sitters": mySitters,
+		"sitting": sitting,
+	})
+}
+
+// =============================
+// DELETE /sitters/{id} - kończy zastępstwo (właściciel albo zastępca)
+// =============================
+func RemoveSitterHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	sittingID, err := pathID(r)
+	if err != nil {
+		writeFieldError(w, "id", "Invalid sitter ID")
+		return
+	}
+
+	var ownerID, sitterID int
+	err = db.DB.QueryRow(`
+		UPDATE sitters SET revoked_at=NOW()
+		WHERE id=$1 AND (owner_id=$2 OR sitter_id=$2) AND revoked_at IS NULL
+		RETURNING owner_id, sitter_id
+	`, sittingID, userID).Scan(&ownerID, &sitterID)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, "SITTER_NOT_FOUND", "Sitter not found")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	// zastępca traci dostęp od razu, nie po wygaśnięciu tokenu
+	_, _ = db.DB.Exec(
+		"UPDATE sessions SET revoked_at=NOW() WHERE user_id=$1 AND sitter_id=$2 AND revoked_at IS NULL",
+		ownerID, sitterID,
+	)
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Sitting ended"})
+}
+
+// =============================
+// POST /sitting/{id}/login - token zastępcy do konta gracza {id}
+// =============================
+func SitterLoginHandler(w http.ResponseWriter, r *http.Request) {
+	sitterID := r.Context().Value("user_id").(int)
+	ownerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeFieldError(w, "id", "Invalid owner ID")
+		return
+	}
+
+	var ownerName string
+	var endsAt time.Time
+	err = db.DB.QueryRow(`
+		SELECT u.username, s.ends_at
+		FROM sitters s
+		JOIN users u ON s.owner_id = u.id
+		WHERE s.owner_id=$1 AND s.sitter_id=$2 AND s.revoked_at IS NULL
+		  AND s.starts_at <= NOW() AND s.ends_at > NOW()
+		ORDER BY s.ends_at DESC
+		LIMIT 1
+	`, ownerID, sitterID).Scan(&ownerName, &endsAt)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusForbidden, "NOT_A_SITTER", "You are not a sitter for this account")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if ban, err := activeBan(r.Context(), ownerID); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	} else if ban != nil {
+		writeError(w, http.StatusForbidden, "ACCOUNT_BANNED", banMessage(ban))
+		return
+	}
+
+	// sesja zastępcy nie ma refresh tokenu i kończy się najpóźniej z zastępstwem
+	expiration := time.Now().Add(accessTTL)
+	if endsAt.Before(expiration) {
+		expiration = endsAt
+	}
+	sessionID := randomToken(16)
+	_, err = db.DB.Exec(`
+		INSERT INTO sessions (id, user_id, sitter_id, refresh_hash, user_agent, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, sessionID, ownerID, sitterID, hashToken(randomToken(32)), r.UserAgent(), clientIP(r), expiration)
+	if err != nil {
+		writeInternalError(w, "DB error on session")
+		return
+	}
+
+	// rola zawsze player - zastępca nie przejmuje uprawnień moderatora
+	claims := &Claims{
+		UserID:    ownerID,
+		Username:  ownerName,
+		Role:      RolePlayer,
+		SessionID: sessionID,
+		SitterID:  sitterID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        randomToken(16),
+			ExpiresAt: jwt.NewNumericDate(expiration),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	token, err := signingKeys.Sign(claims)
+	if err != nil {
+		writeInternalError(w, "Cannot sign token")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":      token,
+		"expires_at": expiration,
+		"owner":      ownerName,
+	})
+}
+
+// =============================
+// GET /sitters/log?page=1 - co zastępcy robili na moim koncie
+// =============================
+func SitterLogHandler(w http.ResponseWriter, r *http.Request) {
+	ownerID := r.Context().Value("user_id").(int)
+	page, perPage := parsePagination(r)
+
+	rows, err := db.DB.Query(`
+		SELECT a.id, u.username, a.method, a.path, a.status, COALESCE(a.ip, ''), a.created_at,
+		       COUNT(*) OVER () AS total
+		FROM sitter_actions a
+		JOIN users u ON a.sitter_id = u.id
+		WHERE a.owner_id=$1
+		ORDER BY a.id DESC
+		LIMIT $2 OFFSET $3
+	`, ownerID, perPage, (page-1)*perPage)
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer rows.Close()
+
+	total := 0
+	entries := []map[string]interface{}{}
+	for rows.Next() {
+		var id, status int
+		var sitter, method, path, ip string
+		var createdAt time.Time
+		rows.Scan(&id, &sitter, &method, &path, &status, &ip, &createdAt, &total)
+		entries = append(entries, map[string]interface{}{
+			"id":         id,
+			"sitter":     sitter,
+			"method":     method,
+			"path":       path,
+			"status":     status,
+			"ip":         ip,
+			"created_at": createdAt,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"page":     page,
+		"per_page": perPage,
+		"total":    total,
+		"results":  entries,
+	})
+}
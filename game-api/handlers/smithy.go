@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"PawTribalWars/battle"
+)
+
+// =============================
+// GET /api/v1/villages/{id}/smithy (stara trasa: GET /smithy?village_id=1)
+// =============================
+func (g *Game) GetSmithyHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	fields := fieldErrors{}
+	villageID := fields.villageID(r)
+	if fields.write(w) {
+		return
+	}
+
+	smithy, err := g.svc.Smithy(r.Context(), userID, villageID)
+	if err != nil {
+		writeGameError(w, err)
+		return
+	}
+
+	research := []map[string]interface{}{}
+	for _, s := range smithy.Research {
+		entry := map[string]interface{}{
+			"type":           s.Type,
+			"level":          s.Level,
+			"max_level":      battle.MaxResearchLevel,
+			"required_level": s.RequiredLevel,
+			"stats":          battle.ResearchedStats(s.Type, s.Level),
+		}
+		if !s.MaxLevel {
+			entry["next_cost"] = s.NextCost
+			entry["next_duration"] = int(s.NextDuration.Seconds())
+		}
+		research = append(research, entry)
+	}
+
+	queue := []map[string]interface{}{}
+	for _, q := range smithy.Queue {
+		queue = append(queue, map[string]interface{}{
+			"type":        q.UnitType,
+			"level":       q.Level,
+			"finishes_at": q.FinishesAt,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"village_id":   villageID,
+		"smithy_level": smithy.Level,
+		"research":     research,
+		"queue":        queue,
+	})
+}
+
+// =============================
+// POST /api/v1/villages/{id}/smithy/{type}/research
+// stara trasa: POST /smithy/research?village_id=1&type=swordsman
+// =============================
+func (g *Game) StartResearchHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	fields := fieldErrors{}
+	villageID := fields.villageID(r)
+	unitType := fields.required(r, "type")
+	if fields.write(w) {
+		return
+	}
+
+	order, err := g.svc.StartResearch(r.Context(), userID, villageID, unitType, time.Now())
+	if err != nil {
+		writeGameError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "Research started",
+		"type":        order.Type,
+		"level":       order.Level,
+		"cost":        order.Cost,
+		"finishes_at": order.FinishesAt,
+	})
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"PawTribalWars/events"
+)
+
+// co ile wysyłamy komentarz, żeby proxy nie zamknęło połączenia
+const sseHeartbeat = 15 * time.Second
+
+// =============================
+// GET /events  (Last-Event-ID: 42)
+// =============================
+func EventsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	if userID == 0 {
+		writeError(w, http.StatusUnauthorized, "INVALID_TOKEN", "Token without user id, log in again")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeInternalError(w, "Streaming not supported")
+		return
+	}
+
+	// EventSource wysyła Last-Event-ID sam przy ponownym połączeniu
+	lastIDStr := r.Header.Get("Last-Event-ID")
+	if lastIDStr == "" {
+		lastIDStr = r.URL.Query().Get("last_event_id")
+	}
+	var lastID int64
+	if lastIDStr != "" {
+		var err error
+		lastID, err = strconv.ParseInt(lastIDStr, 10, 64)
+		if err != nil {
+			writeFieldError(w, "last_event_id", "Invalid Last-Event-ID")
+			return
+		}
+	}
+
+	ch, missed, cancel := events.Subscribe(userID, lastID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 5000\n\n")
+	flusher.Flush()
+
+	send := func(ev events.Event) error {
+		data, err := json.Marshal(ev.Payload)
+		if err != nil {
+			return err
+		}
+		if ev.ID != 0 {
+			fmt.Fprintf(w, "id: %d\n", ev.ID)
+		}
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+		flusher.Flush()
+		return err
+	}
+
+	lastSent := lastID
+	for _, ev := range missed {
+		if err := send(ev); err != nil {
+			return
+		}
+		lastSent = ev.ID
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return // klient odtworzy resztę przez Last-Event-ID
+			}
+			if ev.ID != 0 && ev.ID <= lastSent {
+				continue
+			}
+			if err := send(ev); err != nil {
+				return
+			}
+			if ev.ID != 0 {
+				lastSent = ev.ID
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"PawTribalWars/db"
+	"PawTribalWars/game"
+	"PawTribalWars/repo"
+	"PawTribalWars/repo/postgres"
+	"PawTribalWars/scheduler"
+)
+
+// urlop: wnioskowany z wyprzedzeniem vacationDelay, najwyżej vacationDaysPerYear dni
+// w roku kalendarzowym. W trakcie urlopu wioski nie produkują surowców, kolejki
+// stoją i gracz nie wydaje rozkazów.
+
+// *sql.DB albo *sql.Tx
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// dni urlopu wykorzystane (lub zarezerwowane) w danym roku
+func vacationDaysUsed(q rowQuerier, userID, year int) (int, error) {
+	var days int
+	err := q.QueryRow(`
+		SELECT COALESCE(SUM(GREATEST(CEIL(EXTRACT(EPOCH FROM COALESCE(ended_at, ends_at) - starts_at) / 86400), 0)), 0)::INT
+		FROM vacations
+		WHERE user_id=$1 AND EXTRACT(YEAR FROM starts_at) = $2
+	`, userID, year).Scan(&days)
+	return days, err
+}
+
+// DenyOnVacation blokuje rozkazy gracza w trakcie urlopu; używać po AuthMiddleware
+func (g *Guard) DenyOnVacation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vacation, err := g.users.Vacation(r.Context(), r.Context().Value("user_id").(int))
+		if err != nil {
+			writeInternalError(w, "DB error")
+			return
+		}
+		if vacation.Active(time.Now()) {
+			writeError(w, http.StatusForbidden, "ON_VACATION", "Account is in vacation mode")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rozlicza zakończony urlop: produkcja zatrzymana od startu, badania przesunięte o czas urlopu
+func (g *Game) finishVacation(ctx context.Context, vacationID int) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := g.finishVacationTx(ctx, tx, vacationID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// finishVacation w transakcji wywołującego (zdarzenie schedulera)
+func (g *Game) finishVacationTx(ctx context.Context, tx *sql.Tx, vacationID int) error {
+	var userID int
+	var startsAt, endedAt time.Time
+	err := tx.QueryRow(`
+		UPDATE vacations SET settled_at=NOW()
+		WHERE id=$1 AND settled_at IS NULL AND COALESCE(ended_at, ends_at) <= NOW()
+		RETURNING user_id, starts_at, COALESCE(ended_at, ends_at)
+	`, vacationID).Scan(&userID, &startsAt, &endedAt)
+	if err == sql.ErrNoRows {
+		return nil // już rozliczony albo jeszcze trwa
+	}
+	if err != nil {
+		return err
+	}
+
+	// surowce do teraz bez czasu urlopu; potem urlop przestaje być widoczny dla rozliczeń
+	if err := g.svc.WithStore(postgres.WithTx(tx)).SettleVacation(ctx, userID, repo.Vacation{StartsAt: startsAt, EndsAt: endedAt}, time.Now()); err != nil {
+		return err
+	}
+
+	shift := endedAt.Sub(startsAt).Seconds()
+	if shift > 0 {
+		qRows, err := tx.Query(`
+			UPDATE research_queue q SET finishes_at = q.finishes_at + $2 * INTERVAL '1 second'
+			FROM villages v
+			WHERE q.village_id = v.id AND v.user_id=$1 AND q.finishes_at > $3
+			RETURNING q.village_id, q.finishes_at
+		`, userID, shift, startsAt)
+		if err != nil {
+			return err
+		}
+		type shifted struct {
+			villageID  int
+			finishesAt time.Time
+		}
+		var jobs []shifted
+		for qRows.Next() {
+			var s shifted
+			qRows.Scan(&s.villageID, &s.finishesAt)
+			jobs = append(jobs, s)
+		}
+		qRows.Close()
+		for _, j := range jobs {
+			if err := scheduler.Schedule(tx, JobCompleteResearch, game.ResearchJob{VillageID: j.villageID}, j.finishesAt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// =============================
+// GET /vacation
+// =============================
+func GetVacationHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	used, err := vacationDaysUsed(db.DB, userID, time.Now().Year())
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	var current map[string]interface{}
+	var id int
+	var startsAt, endsAt time.Time
+	err = db.DB.QueryRow(`
+		SELECT id, starts_at, COALESCE(ended_at, ends_at) FROM vacations
+		WHERE user_id=$1 AND COALESCE(ended_at, ends_at) > NOW()
+		ORDER BY starts_at DESC
+		LIMIT 1
+	`, userID).Scan(&id, &startsAt, &endsAt)
+	if err != nil && err != sql.ErrNoRows {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if err == nil {
+		current = map[string]interface{}{
+			"id":        id,
+			"starts_at": startsAt,
+			"ends_at":   endsAt,
+			"active":    !startsAt.After(time.Now()),
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"vacation":       current,
+		"days_per_year":  vacationDaysPerYear,
+		"days_used":      used,
+		"days_remaining": vacationDaysPerYear - used,
+		"start_delay":    vacationDelay.String(),
+	})
+}
+
+// =============================
+// POST /vacation (days)
+// =============================
+func StartVacationHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req struct {
+		Days int `json:"days"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	days := req.Days
+	if days < 1 {
+		writeFieldError(w, "days", "days must be a positive integer")
+		return
+	}
+
+	startsAt := time.Now().Add(vacationDelay)
+	endsAt := startsAt.Add(time.Duration(days) * 24 * time.Hour)
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	defer tx.Rollback()
+
+	// blokada gracza: dwa równoległe wnioski nie przejdą obu sprawdzeń puli dni
+	if _, err := tx.Exec("SELECT 1 FROM users WHERE id=$1 FOR UPDATE", userID); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	used, err := vacationDaysUsed(tx, userID, startsAt.Year())
+	if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if used+days > vacationDaysPerYear {
+		writeError(w, http.StatusBadRequest, "VACATION_DAYS_EXHAUSTED", "Not enough vacation days left this year ("+strconv.Itoa(vacationDaysPerYear-used)+")")
+		return
+	}
+
+	// jeden urlop naraz (zaplanowany albo trwający)
+	var vacationID int
+	err = tx.QueryRow(`
+		INSERT INTO vacations (user_id, starts_at, ends_at)
+		SELECT $1, $2, $3
+		WHERE NOT EXISTS(SELECT 1 FROM vacations WHERE user_id=$1 AND settled_at IS NULL)
+		RETURNING id
+	`, userID, startsAt, endsAt).Scan(&vacationID)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusConflict, "VACATION_ALREADY_PLANNED", "Vacation already planned or active")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if err := scheduler.Schedule(tx, JobFinishVacation, vacationJob{VacationID: vacationID}, endsAt); err != nil {
+		writeInternalError(w, "DB error on scheduling")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "Vacation planned",
+		"id":        vacationID,
+		"starts_at": startsAt,
+		"ends_at":   endsAt,
+	})
+}
+
+// =============================
+// DELETE /vacation - anuluje zaplanowany albo kończy trwający urlop
+// =============================
+func (g *Game) EndVacationHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	var vacationID int
+	var startsAt time.Time
+	err := db.DB.QueryRow(`
+		SELECT id, starts_at FROM vacations
+		WHERE user_id=$1 AND settled_at IS NULL AND COALESCE(ended_at, ends_at) > NOW()
+	`, userID).Scan(&vacationID, &startsAt)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, "VACATION_NOT_FOUND", "No vacation to end")
+		return
+	} else if err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+
+	if startsAt.After(time.Now()) {
+		// jeszcze się nie zaczął - nic do rozliczenia, dni wracają do puli
+		_, err = db.DB.Exec("UPDATE vacations SET ended_at=starts_at, settled_at=NOW() WHERE id=$1", vacationID)
+		if err != nil {
+			writeInternalError(w, "DB error")
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"message": "Vacation cancelled"})
+		return
+	}
+
+	if _, err := db.DB.Exec("UPDATE vacations SET ended_at=NOW() WHERE id=$1", vacationID); err != nil {
+		writeInternalError(w, "DB error")
+		return
+	}
+	if err := g.finishVacation(r.Context(), vacationID); err != nil {
+		writeInternalError(w, "DB error on vacation settlement")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Vacation ended"})
+}
@@ -2,43 +2,40 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
-	"PawTribalWars/db"
 	"github.com/gorilla/mux"
 )
 
 type Village struct {
 	ID        int    `json:"id"`
 	Name      string `json:"name"`
+	Points    int    `json:"points"`
 	CreatedAt string `json:"created_at"`
 }
 
 // =============================
 // GET /villages
 // =============================
-func GetVillagesHandler(w http.ResponseWriter, r *http.Request) {
-	username := r.Context().Value("username").(string)
-
-	rows, err := db.DB.Query(`
-		SELECT v.id, v.name, v.created_at
-		FROM villages v
-		JOIN users u ON v.user_id = u.id
-		WHERE u.username = $1
-	`, username)
+func (g *Game) GetVillagesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+
+	list, err := g.svc.Villages(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "DB error", http.StatusInternalServerError)
+		writeInternalError(w, "DB error")
 		return
 	}
-	defer rows.Close()
 
 	var villages []Village
-	for rows.Next() {
-		var v Village
-		rows.Scan(&v.ID, &v.Name, &v.CreatedAt)
-		villages = append(villages, v)
+	for _, v := range list {
+		villages = append(villages, Village{
+			ID:        v.ID,
+			Name:      v.Name,
+			Points:    v.Points,
+			CreatedAt: v.CreatedAt.Format(time.RFC3339Nano),
+		})
 	}
 
 	json.NewEncoder(w).Encode(villages)
@@ -47,118 +44,48 @@ func GetVillagesHandler(w http.ResponseWriter, r *http.Request) {
 // =============================
 // POST /villages
 // =============================
-func CreateVillageHandler(w http.ResponseWriter, r *http.Request) {
-	username := r.Context().Value("username").(string)
-	name := r.FormValue("name")
-	if name == "" {
-		http.Error(w, "Missing village name", http.StatusBadRequest)
-		return
-	}
-
-	var userID int
-	err := db.DB.QueryRow("SELECT id FROM users WHERE username=$1", username).Scan(&userID)
-	if err != nil {
-		http.Error(w, "User not found", http.StatusBadRequest)
-		return
+func (g *Game) CreateVillageHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var req struct {
+		Name string `json:"name"`
 	}
-
-	// 🔹 policz ile gracz ma już wiosek
-	var villageCount int
-	err = db.DB.QueryRow("SELECT COUNT(*) FROM villages WHERE user_id=$1", userID).Scan(&villageCount)
-	if err != nil {
-		http.Error(w, "DB error", http.StatusInternalServerError)
+	if !decodeBody(w, r, &req) {
 		return
 	}
 
-	// 🔹 znajdź najwyższy poziom ratusza gracza
-	var maxTownhall int
-	err = db.DB.QueryRow(`
-		SELECT COALESCE(MAX(b.level), 0)
-		FROM buildings b
-		JOIN villages v ON b.village_id = v.id
-		WHERE v.user_id=$1 AND b.type='townhall'
-	`, userID).Scan(&maxTownhall)
+	res, err := g.svc.FoundVillage(r.Context(), userID, req.Name)
 	if err != nil {
-		http.Error(w, "DB error", http.StatusInternalServerError)
+		writeGameError(w, err)
 		return
 	}
 
-	// 🔹 wylicz maksymalną liczbę wiosek
-	maxVillages := (maxTownhall / 10) + 1
-	if villageCount >= maxVillages {
-		http.Error(w,
-			fmt.Sprintf("You need higher Townhall level to create more villages (current max: %d)", maxVillages),
-			http.StatusForbidden,
-		)
-		return
-	}
-
-	// Tworzymy nową wioskę
-	var villageID int
-	err = db.DB.QueryRow(
-		"INSERT INTO villages (user_id, name) VALUES ($1, $2) RETURNING id",
-		userID, name,
-	).Scan(&villageID)
-	if err != nil {
-		http.Error(w, "DB error", http.StatusInternalServerError)
-		return
-	}
-
-	// Inicjalizujemy zasoby
-	_, _ = db.DB.Exec("INSERT INTO resources (village_id, wood, clay, iron) VALUES ($1, 100, 100, 100)", villageID)
-
-	// Inicjalizujemy budynki (lvl 1)
-	buildings := []string{"townhall", "lumbermill", "claypit", "ironmine", "warehouse", "barracks"}
-	for _, b := range buildings {
-		_, _ = db.DB.Exec("INSERT INTO buildings (village_id, type, level) VALUES ($1, $2, 1)", villageID, b)
-	}
-
-	// Inicjalizujemy jednostki (0)
-	units := []string{"spearman", "swordsman", "archer"}
-	for _, u := range units {
-		_, _ = db.DB.Exec("INSERT INTO units (village_id, type, count) VALUES ($1, $2, 0)", villageID, u)
-	}
-
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"message":     "New village created",
-		"village_id":  villageID,
-		"max_allowed": maxVillages,
-		"current":     villageCount + 1,
+		"village_id":  res.Village.ID,
+		"max_allowed": res.Max,
+		"current":     res.Current,
 	})
 }
 
 // =============================
 // PUT /villages/{id}
 // =============================
-func UpdateVillageHandler(w http.ResponseWriter, r *http.Request) {
-	username := r.Context().Value("username").(string)
-	idStr := mux.Vars(r)["id"]
-	name := r.FormValue("name")
-
-	villageID, err := strconv.Atoi(idStr)
+func (g *Game) UpdateVillageHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	villageID, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		http.Error(w, "Invalid village ID", http.StatusBadRequest)
+		writeFieldError(w, "id", "Invalid village ID")
 		return
 	}
-
-	// sprawdzamy czy ta wioska należy do usera
-	var exists bool
-	err = db.DB.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM villages v
-			JOIN users u ON v.user_id = u.id
-			WHERE v.id=$1 AND u.username=$2
-		)
-	`, villageID, username).Scan(&exists)
-
-	if !exists {
-		http.Error(w, "Village not found or not yours", http.StatusForbidden)
+	var req struct {
+		Name string `json:"name"`
+	}
+	if !decodeBody(w, r, &req) {
 		return
 	}
 
-	_, err = db.DB.Exec("UPDATE villages SET name=$1 WHERE id=$2", name, villageID)
-	if err != nil {
-		http.Error(w, "DB error on update", http.StatusInternalServerError)
+	if err := g.svc.RenameVillage(r.Context(), userID, villageID, req.Name); err != nil {
+		writeGameError(w, err)
 		return
 	}
 
@@ -168,36 +95,88 @@ func UpdateVillageHandler(w http.ResponseWriter, r *http.Request) {
 // =============================
 // DELETE /villages/{id}
 // =============================
-func DeleteVillageHandler(w http.ResponseWriter, r *http.Request) {
-	username := r.Context().Value("username").(string)
-	idStr := mux.Vars(r)["id"]
-
-	villageID, err := strconv.Atoi(idStr)
+func (g *Game) DeleteVillageHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	villageID, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		http.Error(w, "Invalid village ID", http.StatusBadRequest)
+		writeFieldError(w, "id", "Invalid village ID")
 		return
 	}
 
-	// sprawdzamy czy ta wioska należy do usera
-	var exists bool
-	err = db.DB.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM villages v
-			JOIN users u ON v.user_id = u.id
-			WHERE v.id=$1 AND u.username=$2
-		)
-	`, villageID, username).Scan(&exists)
+	if err := g.svc.AbandonVillage(r.Context(), userID, villageID); err != nil {
+		writeGameError(w, err)
+		return
+	}
 
-	if !exists {
-		http.Error(w, "Village not found or not yours", http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Village deleted"})
+}
+
+// =============================
+// GET /api/v1/villages/{id}/overview
+// =============================
+func (g *Game) GetVillageOverviewHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	fields := fieldErrors{}
+	villageID := fields.villageID(r)
+	if fields.write(w) {
 		return
 	}
 
-	_, err = db.DB.Exec("DELETE FROM villages WHERE id=$1", villageID)
+	o, err := g.svc.Overview(r.Context(), userID, villageID, time.Now())
 	if err != nil {
-		http.Error(w, "DB error on delete", http.StatusInternalServerError)
+		writeGameError(w, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{"message": "Village deleted"})
+	buildings := []map[string]interface{}{}
+	for _, b := range o.Buildings {
+		buildings = append(buildings, map[string]interface{}{
+			"type":       b.Type,
+			"level":      b.Level,
+			"next_level": b.NextLevel,
+			"next_cost":  b.NextCost,
+			"affordable": b.Affordable,
+		})
+	}
+	units := []map[string]interface{}{}
+	for _, u := range o.Units {
+		units = append(units, map[string]interface{}{
+			"type":   u.Type,
+			"home":   u.Home,
+			"abroad": u.Abroad,
+			"total":  u.Home + u.Abroad,
+		})
+	}
+	research := []map[string]interface{}{}
+	for _, q := range o.Research {
+		research = append(research, map[string]interface{}{
+			"unit_type":   q.UnitType,
+			"level":       q.Level,
+			"finishes_at": q.FinishesAt.Format(time.RFC3339),
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"village": Village{
+			ID:        o.Village.ID,
+			Name:      o.Village.Name,
+			Points:    o.Village.Points,
+			CreatedAt: o.Village.CreatedAt.Format(time.RFC3339Nano),
+		},
+		"resources": map[string]interface{}{
+			"wood":              o.Resources.Wood,
+			"clay":              o.Resources.Clay,
+			"iron":              o.Resources.Iron,
+			"capacity":          o.Resources.Capacity,
+			"per_hour":          o.Resources.PerHour,
+			"production_paused": o.Resources.Paused,
+		},
+		"buildings": buildings,
+		"units":     units,
+		"queues": map[string]interface{}{
+			"research": research,
+		},
+		// ataki i wsparcia w drodze do wioski - pusto, dopóki nie ma ruchów wojsk
+		"incomings": []interface{}{},
+	})
 }
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"PawTribalWars/events"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = 50 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// klienci gry łączą się z różnych domen, autoryzuje nas JWT
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// =============================
+// GET /ws?last_event_id=42
+// =============================
+func WebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	if userID == 0 {
+		writeError(w, http.StatusUnauthorized, "INVALID_TOKEN", "Token without user id, log in again")
+		return
+	}
+
+	var lastID int64
+	if s := r.URL.Query().Get("last_event_id"); s != "" {
+		var err error
+		lastID, err = strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			writeFieldError(w, "last_event_id", "Invalid last_event_id")
+			return
+		}
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade sam odpowiada błędem
+	}
+	defer conn.Close()
+
+	ch, missed, cancel := events.Subscribe(userID, lastID)
+	defer cancel()
+
+	// czytamy tylko po to, żeby obsłużyć pong i wykryć rozłączenie
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(ev events.Event) error {
+		conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		return conn.WriteJSON(ev)
+	}
+
+	// najpierw zdarzenia, które ominęły klienta
+	lastSent := lastID
+	for _, ev := range missed {
+		if err := send(ev); err != nil {
+			return
+		}
+		lastSent = ev.ID
+	}
+
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				// szyna odłączyła wolnego klienta
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "reconnect with last_event_id"),
+					time.Now().Add(wsWriteWait))
+				return
+			}
+			if ev.ID != 0 && ev.ID <= lastSent {
+				continue // już wysłane przy odtwarzaniu
+			}
+			if err := send(ev); err != nil {
+				return
+			}
+			if ev.ID != 0 {
+				lastSent = ev.ID
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
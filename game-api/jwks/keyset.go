@@ -0,0 +1,326 @@
+package jwks
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// obsługiwane algorytmy podpisu
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// klucz HS256 z konfiguracji nie ma własnego kid w bazie
+const hmacKeyID = "hs256"
+
+// nieznany kid przeładowuje klucze z bazy najwyżej raz na ten czas
+const reloadThrottle = 10 * time.Second
+
+var ErrRotationUnsupported = errors.New("key rotation is not supported for HS256, change jwt.secret instead")
+
+type Key struct {
+	ID        string
+	Alg       string
+	private   interface{} // *rsa.PrivateKey, ed25519.PrivateKey albo []byte dla HS256
+	public    interface{} // *rsa.PublicKey, ed25519.PublicKey albo []byte dla HS256
+	CreatedAt time.Time
+	RetiredAt *time.Time
+}
+
+// KeySet trzyma klucz podpisujący i wszystkie klucze, którymi wolno jeszcze
+// weryfikować tokeny. Klucze RS256/EdDSA są w tabeli signing_keys, więc
+// wszystkie instancje serwera widzą tę samą rotację.
+type KeySet struct {
+	db        *sql.DB
+	alg       string
+	retention time.Duration // jak długo po rotacji stary klucz weryfikuje tokeny
+
+	mu         sync.RWMutex
+	signing    *Key
+	verify     map[string]*Key
+	lastReload time.Time
+}
+
+// NewHMAC tworzy zestaw z jednym wspólnym sekretem (tryb zgodności)
+func NewHMAC(secret []byte) *KeySet {
+	key := &Key{ID: hmacKeyID, Alg: AlgHS256, private: secret, public: secret, CreatedAt: time.Now()}
+	return &KeySet{
+		alg:     AlgHS256,
+		signing: key,
+		verify:  map[string]*Key{hmacKeyID: key},
+	}
+}
+
+// Load wczytuje klucze danego algorytmu z bazy; jeśli nie ma aktywnego, generuje pierwszy
+func Load(db *sql.DB, alg string, retention time.Duration) (*KeySet, error) {
+	if alg != AlgRS256 && alg != AlgEdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	ks := &KeySet{db: db, alg: alg, retention: retention}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	if ks.signing == nil {
+		if _, err := ks.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+func (ks *KeySet) Algorithm() string {
+	return ks.alg
+}
+
+// Reload pobiera z bazy aktywne i niedawno wycofane klucze
+func (ks *KeySet) Reload() error {
+	if ks.db == nil {
+		return nil
+	}
+	rows, err := ks.db.Query(`
+		SELECT kid, alg, private_key, created_at, retired_at
+		FROM signing_keys
+		WHERE alg=$1 AND (retired_at IS NULL OR retired_at > NOW() - $2 * INTERVAL '1 second')
+		ORDER BY created_at
+	`, ks.alg, ks.retention.Seconds())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	verify := map[string]*Key{}
+	var signing *Key
+	for rows.Next() {
+		var key Key
+		var privatePEM string
+		if err := rows.Scan(&key.ID, &key.Alg, &privatePEM, &key.CreatedAt, &key.RetiredAt); err != nil {
+			return err
+		}
+		if err := key.decode(privatePEM); err != nil {
+			return fmt.Errorf("signing key %s: %w", key.ID, err)
+		}
+		k := key
+		verify[k.ID] = &k
+		if k.RetiredAt == nil {
+			signing = &k // najnowszy aktywny wygrywa
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	ks.verify = verify
+	ks.signing = signing
+	ks.lastReload = time.Now()
+	ks.mu.Unlock()
+	return nil
+}
+
+// Refresh co interval przeładowuje klucze, żeby po rotacji na innej
+// instancji wszystkie zaczęły podpisywać nowym kluczem
+func (ks *KeySet) Refresh(ctx context.Context, interval time.Duration) {
+	if ks.db == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ks.Reload(); err != nil {
+				log.Println("jwks: reload failed:", err)
+			}
+		}
+	}
+}
+
+// Rotate generuje nowy klucz podpisujący; poprzedni weryfikuje jeszcze przez retention
+func (ks *KeySet) Rotate() (*Key, error) {
+	if ks.db == nil {
+		return nil, ErrRotationUnsupported
+	}
+	key, privatePEM, err := generate(ks.alg)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := ks.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE signing_keys SET retired_at=NOW() WHERE alg=$1 AND retired_at IS NULL", ks.alg); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO signing_keys (kid, alg, private_key) VALUES ($1, $2, $3)",
+		key.ID, key.Alg, privatePEM,
+	); err != nil {
+		return nil, err
+	}
+	// klucze, którymi nie da się już niczego zweryfikować, usuwamy
+	if _, err := tx.Exec(
+		"DELETE FROM signing_keys WHERE retired_at < NOW() - $1 * INTERVAL '1 second'",
+		ks.retention.Seconds(),
+	); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Sign podpisuje claims aktualnym kluczem i ustawia nagłówek kid
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	key := ks.signing
+	ks.mu.RUnlock()
+	if key == nil {
+		return "", errors.New("no active signing key")
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Alg), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
+}
+
+// Keyfunc dla jwt.Parse: wybiera klucz po kid i pilnuje zgodności algorytmu
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && ks.alg == AlgHS256 {
+		kid = hmacKeyID // tokeny wydane przed wprowadzeniem kid
+	}
+
+	key := ks.lookup(kid)
+	if key == nil && ks.db != nil {
+		// być może inna instancja właśnie zrotowała klucz
+		ks.mu.RLock()
+		stale := time.Since(ks.lastReload) > reloadThrottle
+		ks.mu.RUnlock()
+		if stale {
+			if err := ks.Reload(); err != nil {
+				log.Println("jwks: reload failed:", err)
+			}
+			key = ks.lookup(kid)
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.Alg {
+		return nil, fmt.Errorf("unexpected signing method %s for key %s", token.Method.Alg(), kid)
+	}
+	return key.public, nil
+}
+
+// ValidMethods do jwt.WithValidMethods
+func (ks *KeySet) ValidMethods() []string {
+	return []string{ks.alg}
+}
+
+func (ks *KeySet) lookup(kid string) *Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.verify[kid]
+}
+
+// Keys zwraca klucze weryfikujące (kopię, do JWKS)
+func (ks *KeySet) Keys() []*Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	out := make([]*Key, 0, len(ks.verify))
+	for _, k := range ks.verify {
+		out = append(out, k)
+	}
+	return out
+}
+
+func generate(alg string) (*Key, string, error) {
+	var private interface{}
+	var public interface{}
+	switch alg {
+	case AlgRS256:
+		k, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, "", err
+		}
+		private, public = k, &k.PublicKey
+	case AlgEdDSA:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, "", err
+		}
+		private, public = priv, pub
+	default:
+		return nil, "", fmt.Errorf("cannot generate keys for %q", alg)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, "", err
+	}
+	privatePEM := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, "", err
+	}
+	key := &Key{
+		ID:        hex.EncodeToString(id),
+		Alg:       alg,
+		private:   private,
+		public:    public,
+		CreatedAt: time.Now(),
+	}
+	return key, privatePEM, nil
+}
+
+func (k *Key) decode(privatePEM string) error {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return errors.New("invalid PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return err
+	}
+	switch p := parsed.(type) {
+	case *rsa.PrivateKey:
+		if k.Alg != AlgRS256 {
+			return fmt.Errorf("RSA key stored as %s", k.Alg)
+		}
+		k.private, k.public = p, &p.PublicKey
+	case ed25519.PrivateKey:
+		if k.Alg != AlgEdDSA {
+			return fmt.Errorf("Ed25519 key stored as %s", k.Alg)
+		}
+		k.private, k.public = p, p.Public().(ed25519.PublicKey)
+	default:
+		return fmt.Errorf("unsupported key type %T", parsed)
+	}
+	return nil
+}
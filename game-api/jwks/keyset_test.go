@@ -0,0 +1,142 @@
+package jwks
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// rotatedSet odtwarza stan po rotacji bez bazy: stary klucz wycofany, ale
+// jeszcze w okresie retention (tak jak zwróciłby go Reload), nowy podpisuje
+func rotatedSet(t *testing.T, alg string) (ks *KeySet, old, current *Key) {
+	t.Helper()
+	old, _, err := generate(alg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, _, err = generate(alg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retired := time.Now().Add(-time.Minute)
+	old.RetiredAt = &retired
+	ks = &KeySet{
+		alg:       alg,
+		retention: time.Hour,
+		signing:   current,
+		verify:    map[string]*Key{old.ID: old, current.ID: current},
+	}
+	return ks, old, current
+}
+
+func parse(ks *KeySet, token string) (*jwt.Token, error) {
+	return jwt.Parse(token, ks.Keyfunc, jwt.WithValidMethods(ks.ValidMethods()))
+}
+
+func claims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": "1", "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func TestSignUsesCurrentKeyID(t *testing.T) {
+	for _, alg := range []string{AlgRS256, AlgEdDSA} {
+		ks, _, current := rotatedSet(t, alg)
+		signed, err := ks.Sign(claims())
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := parse(ks, signed)
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		if kid := token.Header["kid"]; kid != current.ID {
+			t.Errorf("%s: kid %v, want %s", alg, kid, current.ID)
+		}
+		if token.Method.Alg() != alg {
+			t.Errorf("%s: signed with %s", alg, token.Method.Alg())
+		}
+	}
+}
+
+func TestRetiredKeyVerifiesDuringGracePeriod(t *testing.T) {
+	ks, old, _ := rotatedSet(t, AlgEdDSA)
+
+	// token wydany przed rotacją, podpisany starym kluczem
+	before := &KeySet{alg: AlgEdDSA, signing: old, verify: map[string]*Key{old.ID: old}}
+	signed, err := before.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parse(ks, signed); err != nil {
+		t.Fatalf("token signed with retired key rejected: %v", err)
+	}
+
+	// po retention Reload już nie zwraca starego klucza
+	delete(ks.verify, old.ID)
+	if _, err := parse(ks, signed); err == nil {
+		t.Fatal("token signed with expired key accepted")
+	}
+}
+
+func TestKeyfuncRejectsUnknownKidAndForeignAlgorithm(t *testing.T) {
+	ks, _, _ := rotatedSet(t, AlgEdDSA)
+
+	other, _, _ := rotatedSet(t, AlgEdDSA)
+	signed, err := other.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parse(ks, signed); err == nil {
+		t.Error("token with unknown kid accepted")
+	}
+
+	// HS256 z kid istniejącego klucza EdDSA nie może przejść
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+	forged.Header["kid"] = ks.signing.ID
+	hs, err := forged.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parse(ks, hs); err == nil {
+		t.Error("HS256 token accepted by EdDSA key set")
+	}
+}
+
+func TestDocumentListsPublicKeys(t *testing.T) {
+	ks, old, current := rotatedSet(t, AlgRS256)
+	doc := ks.Document()
+	if len(doc.Keys) != 2 {
+		t.Fatalf("got %d keys, want 2", len(doc.Keys))
+	}
+	seen := map[string]bool{}
+	for _, k := range doc.Keys {
+		seen[k.Kid] = true
+		if k.Kty != "RSA" || k.Alg != AlgRS256 || k.Use != "sig" {
+			t.Errorf("key %s: kty=%s alg=%s use=%s", k.Kid, k.Kty, k.Alg, k.Use)
+		}
+		if k.N == "" || k.E == "" {
+			t.Errorf("key %s: missing modulus or exponent", k.Kid)
+		}
+	}
+	if !seen[old.ID] || !seen[current.ID] {
+		t.Errorf("document kids %v, want %s and %s", seen, old.ID, current.ID)
+	}
+
+	ed, _, _ := rotatedSet(t, AlgEdDSA)
+	for _, k := range ed.Document().Keys {
+		if k.Kty != "OKP" || k.Crv != "Ed25519" || k.X == "" {
+			t.Errorf("key %s: kty=%s crv=%s", k.Kid, k.Kty, k.Crv)
+		}
+	}
+}
+
+func TestHMACSecretNeverPublished(t *testing.T) {
+	ks := NewHMAC([]byte("secret"))
+	if keys := ks.Document().Keys; len(keys) != 0 {
+		t.Fatalf("HS256 secret in JWKS: %+v", keys)
+	}
+	if _, err := ks.Rotate(); !errors.Is(err, ErrRotationUnsupported) {
+		t.Fatalf("Rotate: %v, want ErrRotationUnsupported", err)
+	}
+}
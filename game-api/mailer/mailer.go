@@ -0,0 +1,117 @@
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sterowniki wybierane w konfiguracji
+const (
+	DriverLog  = "log"
+	DriverFile = "file"
+	DriverSMTP = "smtp"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string // zwykły tekst
+}
+
+// Mailer wysyła wiadomości e-mail do graczy
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Config opisuje wybrany sterownik poczty
+type Config struct {
+	Driver   string
+	From     string
+	Dir      string // katalog dla sterownika file
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+// New tworzy mailer dla wybranego sterownika
+func New(cfg Config) (Mailer, error) {
+	switch cfg.Driver {
+	case DriverLog, "":
+		return NewLog(os.Stderr, cfg.From), nil
+	case DriverFile:
+		return NewFile(cfg.Dir, cfg.From)
+	case DriverSMTP:
+		return NewSMTP(cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+// LogMailer wypisuje wiadomości zamiast je wysyłać (dev)
+type LogMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+func NewLog(w io.Writer, from string) *LogMailer {
+	return &LogMailer{w: w, from: from}
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := m.w.Write(format(m.from, msg))
+	return err
+}
+
+// FileMailer zapisuje każdą wiadomość jako osobny plik .eml (dev, testy ręczne)
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFile(dir, from string) (*FileMailer, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("mail directory is required for the file driver")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000000"), sanitize(msg.To))
+	return os.WriteFile(filepath.Join(m.dir, name), format(m.from, msg), 0o644)
+}
+
+// pełna wiadomość z nagłówkami (RFC 5322)
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -1,46 +1,132 @@
 package main
 
 import (
+	"PawTribalWars/config"
 	"PawTribalWars/db"
+	"PawTribalWars/events"
+	"PawTribalWars/game"
 	"PawTribalWars/handlers"
+	"PawTribalWars/jwks"
+	"PawTribalWars/mailer"
+	"PawTribalWars/openapi"
+	"PawTribalWars/ratelimit"
+	"PawTribalWars/repo"
+	"PawTribalWars/repo/postgres"
+	"PawTribalWars/scheduler"
+	"context"
 	"fmt"
-	"github.com/gorilla/mux"
 	"log"
 	"net/http"
+	"os"
+	"time"
 )
 
 //TIP <p>To run your code, right-click the code and select <b>Run</b>.</p> <p>Alternatively, click
 // the <icon src="AllIcons.Actions.Execute"/> icon in the gutter and select the <b>Run</b> menu item from here.</p>
 
 func main() {
+	// Konfiguracja ze zmiennych środowiskowych / pliku
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	handlers.Configure(cfg)
+
+	// game-api openapi - sprawdza, czy openapi.json opisuje wszystkie trasy (do CI, bez bazy)
+	if len(os.Args) > 1 && os.Args[1] == "openapi" {
+		if err := openapi.CheckRoutes(newRouter(routerDeps{
+			Game:     handlers.NewGame(nil),
+			Accounts: handlers.NewAccounts(nil, repo.Store{}),
+			Admin:    handlers.NewAdmin(nil, repo.Store{}),
+			Guard:    handlers.NewGuard(repo.Store{}),
+			Metrics:  http.NotFoundHandler(),
+		})); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println("openapi.json matches the router")
+		os.Exit(0)
+	}
+
+	// game-api migrate ... - tylko migracje, bez startu serwera
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		db.ConnectDB(cfg.DB)
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
+	// Poczta: log na stderr w dev, pliki .eml albo SMTP
+	m, err := mailer.New(mailer.Config{
+		Driver:   cfg.Mail.Driver,
+		From:     cfg.Mail.From,
+		Dir:      cfg.Mail.Dir,
+		Host:     cfg.Mail.SMTPHost,
+		Port:     cfg.Mail.SMTPPort,
+		Username: cfg.Mail.SMTPUsername,
+		Password: cfg.Mail.SMTPPassword,
+	})
+	if err != nil {
+		log.Fatal("Cannot configure mailer:", err)
+	}
+	handlers.UseMailer(m)
+
 	// Połącz się z bazą
-	db.ConnectDB()
+	db.ConnectDB(cfg.DB)
+	if cfg.DB.AutoMigrate {
+		if _, err := db.Migrate(db.DB); err != nil {
+			log.Fatal("Cannot migrate database:", err)
+		}
+	}
+
+	// Klucze JWT: wspólny sekret HS256 albo rotowane klucze RS256/EdDSA z bazy
+	if cfg.JWT.Algorithm == jwks.AlgHS256 {
+		handlers.UseKeys(jwks.NewHMAC([]byte(cfg.JWT.Secret)))
+	} else {
+		keys, err := jwks.Load(db.DB, cfg.JWT.Algorithm, cfg.JWT.KeyRetention.Duration)
+		if err != nil {
+			log.Fatal("Cannot load signing keys:", err)
+		}
+		go keys.Refresh(context.Background(), time.Minute)
+		handlers.UseKeys(keys)
+	}
+
+	// Powiadomienia zapisywane w bazie, żeby dało się je odtworzyć po reconnect
+	events.Default = events.NewBus(events.NewPostgresStore(db.DB))
+
+	// Limity żądań: w pamięci albo w bazie, gdy działa kilka instancji
+	if cfg.RateLimit.Store == "postgres" {
+		handlers.UseRateLimitStore(ratelimit.NewPostgresStore(db.DB))
+	}
+
+	// Reguły gry i sprawdzanie graczy na repozytoriach Postgresa (w testach: repo/memory)
+	store := postgres.New(db.DB)
+	gameService := game.NewService(store, game.Config{
+		Speed:                   cfg.World.Speed,
+		BeginnerProtection:      cfg.World.BeginnerProtection.Duration,
+		BeginnerPointsThreshold: cfg.World.BeginnerPointsThreshold,
+	})
+	gameAPI := handlers.NewGame(gameService)
+
+	// Scheduler zdarzeń czasowych działający w tle
+	sched := scheduler.New(db.DB)
+	handlers.RegisterJobs(sched, gameAPI)
+	go sched.Run(context.Background())
 
 	// Router
-	r := mux.NewRouter()
-	// User authentication
-	r.HandleFunc("/register", handlers.RegisterHandler).Methods("POST")
-	r.HandleFunc("/login", handlers.LoginHandler).Methods("POST")
-	r.HandleFunc("/logout", handlers.LogoutHandler).Methods("POST")
-
-	// Vilages endpoints
-	r.Handle("/villages", handlers.AuthMiddleware(http.HandlerFunc(handlers.GetVillagesHandler))).Methods("GET")
-	r.Handle("/villages", handlers.AuthMiddleware(http.HandlerFunc(handlers.CreateVillageHandler))).Methods("POST")
-	r.Handle("/villages/{id}", handlers.AuthMiddleware(http.HandlerFunc(handlers.UpdateVillageHandler))).Methods("PUT")
-	r.Handle("/villages/{id}", handlers.AuthMiddleware(http.HandlerFunc(handlers.DeleteVillageHandler))).Methods("DELETE")
-
-	// Resources
-	r.Handle("/resources", handlers.AuthMiddleware(http.HandlerFunc(handlers.GetResourcesHandler))).Methods("GET")
-
-	// Buildings
-	r.Handle("/buildings", handlers.AuthMiddleware(http.HandlerFunc(handlers.GetBuildingsHandler))).Methods("GET")
-	r.Handle("/buildings/upgrade", handlers.AuthMiddleware(http.HandlerFunc(handlers.UpgradeBuildingHandler))).Methods("PUT")
-	r.Handle("/buildings/cost", handlers.AuthMiddleware(http.HandlerFunc(handlers.GetBuildingCostHandler))).Methods("GET")
-
-	// Units
-	r.Handle("/units", handlers.AuthMiddleware(http.HandlerFunc(handlers.GetUnitsHandler))).Methods("GET")
-	r.Handle("/units/recruit", handlers.AuthMiddleware(http.HandlerFunc(handlers.RecruitUnitsHandler))).Methods("POST")
-	
-	fmt.Println("🚀 Server running on http://localhost:8080")
-	log.Fatal(http.ListenAndServe(":8080", r))
+	r := newRouter(routerDeps{
+		Game:     gameAPI,
+		Accounts: handlers.NewAccounts(gameService, store),
+		Admin:    handlers.NewAdmin(gameService, store),
+		Guard:    handlers.NewGuard(store),
+		Metrics:  handlers.MetricsAuth(cfg.Metrics.Token, cfg.IsDev(), sched.MetricsHandler()),
+	})
+	if err := openapi.CheckRoutes(r); err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.TLSCert != "" {
+		fmt.Printf("🚀 Server running on https://%s (%s)\n", cfg.ListenAddr, cfg.Env)
+		log.Fatal(http.ListenAndServeTLS(cfg.ListenAddr, cfg.TLSCert, cfg.TLSKey, r))
+	}
+	fmt.Printf("🚀 Server running on http://%s (%s)\n", cfg.ListenAddr, cfg.Env)
+	log.Fatal(http.ListenAndServe(cfg.ListenAddr, r))
 }
@@ -0,0 +1,123 @@
+// Package openapi udostępnia specyfikację API (openapi.json) i sprawdza,
+// czy opisuje dokładnie te trasy, które zarejestrował router.
+//
+// openapi.json jest źródłem prawdy dla klienta w pakiecie client - po
+// zmianie specyfikacji: go generate ./client
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+//go:embed openapi.json
+var Spec []byte
+
+// metody, które mogą wystąpić jako klucze w obiekcie ścieżki
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+type document struct {
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// Operations zwraca opisane operacje jako "METHOD /path"
+func Operations() (map[string]bool, error) {
+	var doc document
+	if err := json.Unmarshal(Spec, &doc); err != nil {
+		return nil, fmt.Errorf("openapi.json: %w", err)
+	}
+	ops := map[string]bool{}
+	for path, item := range doc.Paths {
+		for _, m := range methods {
+			if _, ok := item[m]; ok {
+				ops[strings.ToUpper(m)+" "+path] = true
+			}
+		}
+	}
+	return ops, nil
+}
+
+// CheckRoutes porównuje trasy routera ze specyfikacją: każda trasa musi być
+// opisana i każda opisana operacja musi istnieć
+func CheckRoutes(r *mux.Router) error {
+	documented, err := Operations()
+	if err != nil {
+		return err
+	}
+
+	registered := map[string]bool{}
+	err = r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil // trasa bez ścieżki (np. tylko Host)
+		}
+		routeMethods, err := route.GetMethods()
+		if err != nil {
+			return nil // PathPrefix podroutera - jego trasy przyjdą osobno
+		}
+		for _, m := range routeMethods {
+			registered[m+" "+path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for op := range registered {
+		if !documented[op] {
+			problems = append(problems, "not documented in openapi.json: "+op)
+		}
+	}
+	for op := range documented {
+		if !registered[op] {
+			problems = append(problems, "documented but not registered: "+op)
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("openapi.json out of sync with router:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// =============================
+// GET /openapi.json
+// =============================
+func SpecHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(Spec)
+}
+
+// Swagger UI ładowany z CDN, żeby nie trzymać jego plików w repo
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>PAW Tribal Wars API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => { window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" }); };
+  </script>
+</body>
+</html>
+`
+
+// =============================
+// GET /docs
+// =============================
+func DocsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(docsPage))
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// co ile najwyżej przeglądamy mapę w poszukiwaniu pełnych wiader
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	last    time.Time
+	expires time.Time // po tym czasie wiadro jest pełne
+}
+
+// MemoryStore trzyma wiadra w pamięci procesu (jedna instancja serwera)
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, lastSweep: time.Now(), now: time.Now}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit) (bool, time.Duration, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > sweepInterval {
+		for k, b := range s.buckets {
+			if now.After(b.expires) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+	left, allowed, retryAfter := refill(b.tokens, b.last, now, limit)
+	b.tokens, b.last = left, now
+	b.expires = now.Add(fullAfter(limit))
+	return allowed, retryAfter, nil
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// PostgresStore trzyma wiadra w tabeli rate_limits, wspólnej dla wszystkich instancji
+type PostgresStore struct {
+	DB *sql.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{DB: db, lastSweep: time.Now()}
+}
+
+func (s *PostgresStore) Take(key string, limit Limit) (bool, time.Duration, error) {
+	s.sweep()
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return false, 0, err
+	}
+	defer tx.Rollback()
+
+	// nowe wiadro startuje pełne; FOR UPDATE szereguje równoległe żądania
+	_, err = tx.Exec(
+		"INSERT INTO rate_limits (key, tokens, updated_at) VALUES ($1, $2, NOW()) ON CONFLICT (key) DO NOTHING",
+		key, float64(limit.Burst),
+	)
+	if err != nil {
+		return false, 0, err
+	}
+	var tokens float64
+	var last, now time.Time
+	err = tx.QueryRow(
+		"SELECT tokens, updated_at, NOW() FROM rate_limits WHERE key=$1 FOR UPDATE", key,
+	).Scan(&tokens, &last, &now)
+	if err != nil {
+		return false, 0, err
+	}
+
+	left, allowed, retryAfter := refill(tokens, last, now, limit)
+	if _, err := tx.Exec("UPDATE rate_limits SET tokens=$1, updated_at=$2 WHERE key=$3", left, now, key); err != nil {
+		return false, 0, err
+	}
+	return allowed, retryAfter, tx.Commit()
+}
+
+// co jakiś czas usuwa wiadra nieużywane od doby (przy rozsądnych limitach dawno pełne)
+func (s *PostgresStore) sweep() {
+	s.mu.Lock()
+	due := time.Since(s.lastSweep) > sweepInterval
+	if due {
+		s.lastSweep = time.Now()
+	}
+	s.mu.Unlock()
+	if !due {
+		return
+	}
+	_, _ = s.DB.Exec("DELETE FROM rate_limits WHERE updated_at < NOW() - INTERVAL '1 day'")
+}
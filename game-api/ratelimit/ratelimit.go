@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Limit opisuje wiadro tokenów: Rate tokenów na sekundę, najwyżej Burst naraz
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute to wygodny zapis limitu "n żądań na minutę"
+func PerMinute(n float64, burst int) Limit {
+	return Limit{Rate: n / 60, Burst: burst}
+}
+
+// Store pobiera jeden token z wiadra pod danym kluczem. Gdy wiadro jest
+// puste, zwraca allowed=false i czas do pojawienia się kolejnego tokenu.
+type Store interface {
+	Take(key string, limit Limit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// refill dolicza tokeny przybyłe od ostatniego żądania i próbuje pobrać jeden
+func refill(tokens float64, last, now time.Time, limit Limit) (left float64, allowed bool, retryAfter time.Duration) {
+	elapsed := now.Sub(last).Seconds()
+	if elapsed < 0 {
+		elapsed = 0 // zegary instancji nie są idealnie zgodne
+	}
+	tokens = math.Min(float64(limit.Burst), tokens+elapsed*limit.Rate)
+	if tokens >= 1 {
+		return tokens - 1, true, 0
+	}
+	if limit.Rate <= 0 {
+		return tokens, false, time.Hour
+	}
+	wait := (1 - tokens) / limit.Rate
+	return tokens, false, time.Duration(math.Ceil(wait * float64(time.Second)))
+}
+
+// czas, po którym wiadro na pewno jest pełne i można je zapomnieć
+func fullAfter(limit Limit) time.Duration {
+	if limit.Rate <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second))
+}
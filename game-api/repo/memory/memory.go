@@ -0,0 +1,989 @@
+// Package memory implementuje repozytoria gry w pamięci procesu. Służy do
+// testowania API przez httptest bez bazy - zachowuje się jak implementacja
+// postgres (ErrNotFound, kaskadowe usuwanie wioski, atomowe Spend).
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"PawTribalWars/repo"
+)
+
+// DB trzyma cały stan gry; wszystkie repozytoria dzielą jeden mutex
+type DB struct {
+	mu sync.Mutex
+	tx sync.Mutex // transakcje wykonują się po kolei
+
+	nextUserID    int
+	nextVillageID int
+
+	users     map[int]*repo.User
+	passwords map[int]string        // user_id -> skrót hasła
+	vacations map[int]repo.Vacation // user_id -> nierozliczony urlop
+	protected map[int]time.Time     // user_id -> koniec ochrony początkujących
+	villages  map[int]*repo.Village
+	resources map[int]*repo.Resources
+	buildings map[int][]repo.Building // kolejność jak przy Init
+	units     map[int][]repo.Unit     // kolejność jak przy Init / Add
+	research  map[int]map[string]int
+	queue     map[int][]repo.QueuedResearch // kolejka kuźni, od najwcześniej kończącego się
+	jobs      []Job
+	audit     []repo.AuditEntry
+
+	// dostęp do konta; gra nie zmienia go w transakcjach, więc nie jest w state
+	nextSessionID int
+	sessions      map[string]session
+	failures      map[int]failures    // user_id -> nieudane logowania
+	unknown       map[string]failures // skrót nieistniejącej nazwy -> nieudane logowania
+	emailTokens   map[string]emailToken
+	bans          map[int]repo.Ban
+	sittings      map[[2]int]bool // {owner, sitter} -> zastępstwo trwa
+	verified      map[int]bool
+	sitterActions []repo.SitterAction
+}
+
+// Job to zdarzenie zaplanowane dla schedulera
+type Job struct {
+	Kind    string
+	Payload interface{}
+	DueAt   time.Time
+}
+
+type failures struct {
+	count       int
+	lockedUntil *time.Time
+	updatedAt   time.Time
+}
+
+// kolejna nieudana próba wg blokady l
+func (f failures) next(l repo.Lockout, now time.Time) failures {
+	f.count++
+	f.updatedAt = now
+	if d := l.Duration(f.count); d > 0 {
+		until := now.Add(d)
+		f.lockedUntil = &until
+	}
+	return f
+}
+
+type emailToken struct {
+	userID    int
+	purpose   string
+	expiresAt time.Time
+	used      bool
+}
+
+type session struct {
+	userID    int
+	expiresAt time.Time
+	revoked   bool
+}
+
+func New() *DB {
+	return &DB{
+		users:       map[int]*repo.User{},
+		passwords:   map[int]string{},
+		vacations:   map[int]repo.Vacation{},
+		protected:   map[int]time.Time{},
+		villages:    map[int]*repo.Village{},
+		resources:   map[int]*repo.Resources{},
+		buildings:   map[int][]repo.Building{},
+		units:       map[int][]repo.Unit{},
+		research:    map[int]map[string]int{},
+		queue:       map[int][]repo.QueuedResearch{},
+		sessions:    map[string]session{},
+		failures:    map[int]failures{},
+		unknown:     map[string]failures{},
+		emailTokens: map[string]emailToken{},
+		bans:        map[int]repo.Ban{},
+		sittings:    map[[2]int]bool{},
+		verified:    map[int]bool{},
+	}
+}
+
+// Store zwraca komplet repozytoriów działających na tym stanie
+func (d *DB) Store() repo.Store {
+	return d.store(transactor{d})
+}
+
+func (d *DB) store(tx repo.Transactor) repo.Store {
+	return repo.Store{
+		Users:     users{d},
+		Auth:      auth{d},
+		Access:    access{d},
+		Admin:     admin{d},
+		Villages:  villages{d},
+		Resources: resources{d},
+		Buildings: buildings{d},
+		Units:     units{d},
+		Research:  research{d},
+		Jobs:      jobs{d},
+		Tx:        tx,
+	}
+}
+
+// ===== Transakcje =====
+
+// transakcje są wykonywane po kolei; błąd przywraca stan sprzed transakcji.
+// Zapisy poza InTx nie czekają na trwającą transakcję.
+type transactor struct{ d *DB }
+
+func (t transactor) InTx(ctx context.Context, fn func(repo.Store) error) error {
+	t.d.tx.Lock()
+	defer t.d.tx.Unlock()
+	saved := t.d.clone()
+	if err := fn(t.d.store(joined{t.d})); err != nil {
+		t.d.restore(saved)
+		return err
+	}
+	return nil
+}
+
+// w trwającej transakcji InTx tylko wykonuje fn
+type joined struct{ d *DB }
+
+func (j joined) InTx(ctx context.Context, fn func(repo.Store) error) error {
+	return fn(j.d.store(j))
+}
+
+// stan gry (bez mutexów) do wycofania transakcji
+type state struct {
+	nextUserID, nextVillageID int
+
+	users     map[int]repo.User
+	passwords map[int]string
+	vacations map[int]repo.Vacation
+	protected map[int]time.Time
+	villages  map[int]repo.Village
+	resources map[int]repo.Resources
+	buildings map[int][]repo.Building
+	units     map[int][]repo.Unit
+	research  map[int]map[string]int
+	queue     map[int][]repo.QueuedResearch
+	jobs      []Job
+	audit     []repo.AuditEntry
+}
+
+func (d *DB) clone() state {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := state{
+		nextUserID:    d.nextUserID,
+		nextVillageID: d.nextVillageID,
+		users:         map[int]repo.User{},
+		passwords:     map[int]string{},
+		vacations:     map[int]repo.Vacation{},
+		protected:     map[int]time.Time{},
+		villages:      map[int]repo.Village{},
+		resources:     map[int]repo.Resources{},
+		buildings:     map[int][]repo.Building{},
+		units:         map[int][]repo.Unit{},
+		research:      map[int]map[string]int{},
+		queue:         map[int][]repo.QueuedResearch{},
+		jobs:          append([]Job{}, d.jobs...),
+		audit:         append([]repo.AuditEntry{}, d.audit...),
+	}
+	for id, u := range d.users {
+		s.users[id] = *u
+	}
+	for id, p := range d.passwords {
+		s.passwords[id] = p
+	}
+	for id, v := range d.vacations {
+		s.vacations[id] = v
+	}
+	for id, p := range d.protected {
+		s.protected[id] = p
+	}
+	for id, v := range d.villages {
+		s.villages[id] = *v
+	}
+	for id, r := range d.resources {
+		s.resources[id] = *r
+	}
+	for id, list := range d.buildings {
+		s.buildings[id] = append([]repo.Building{}, list...)
+	}
+	for id, list := range d.units {
+		s.units[id] = append([]repo.Unit{}, list...)
+	}
+	for id, levels := range d.research {
+		s.research[id] = map[string]int{}
+		for t, l := range levels {
+			s.research[id][t] = l
+		}
+	}
+	for id, list := range d.queue {
+		s.queue[id] = append([]repo.QueuedResearch{}, list...)
+	}
+	return s
+}
+
+func (d *DB) restore(s state) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nextUserID, d.nextVillageID = s.nextUserID, s.nextVillageID
+	d.users = map[int]*repo.User{}
+	for id, u := range s.users {
+		u := u
+		d.users[id] = &u
+	}
+	d.villages = map[int]*repo.Village{}
+	for id, v := range s.villages {
+		v := v
+		d.villages[id] = &v
+	}
+	d.resources = map[int]*repo.Resources{}
+	for id, r := range s.resources {
+		r := r
+		d.resources[id] = &r
+	}
+	d.passwords, d.vacations, d.protected = s.passwords, s.vacations, s.protected
+	d.buildings, d.units = s.buildings, s.units
+	d.research, d.queue, d.jobs, d.audit = s.research, s.queue, s.jobs, s.audit
+}
+
+// ===== Dane testowe =====
+
+// AddUser dodaje gracza (w Postgresie robi to rejestracja)
+func (d *DB) AddUser(username, email, role string) repo.User {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nextUserID++
+	user := repo.User{ID: d.nextUserID, Username: username, Email: email, Role: role, CreatedAt: time.Now()}
+	d.users[user.ID] = &user
+	return user
+}
+
+// SetProtection ustawia koniec ochrony początkujących gracza
+func (d *DB) SetProtection(userID int, until time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.protected[userID] = until
+}
+
+// SetVacation zapisuje (v != nil) albo rozlicza urlop gracza
+func (d *DB) SetVacation(userID int, v *repo.Vacation) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if v == nil {
+		delete(d.vacations, userID)
+		return
+	}
+	d.vacations[userID] = *v
+}
+
+// urlop, który się zaczął; wywołujący trzyma d.mu
+func (d *DB) startedVacation(userID int, now time.Time) *repo.Vacation {
+	v, ok := d.vacations[userID]
+	if !ok || v.StartsAt.After(now) {
+		return nil
+	}
+	return &v
+}
+
+// QueueResearch dopisuje badanie do kolejki kuźni (w Postgresie robi to POST /smithy/research)
+func (d *DB) QueueResearch(villageID int, unitType string, level int, finishesAt time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.enqueue(villageID, repo.QueuedResearch{UnitType: unitType, Level: level, FinishesAt: finishesAt})
+}
+
+// wywołujący trzyma d.mu
+func (d *DB) enqueue(villageID int, q repo.QueuedResearch) {
+	queue := append(d.queue[villageID], q)
+	sort.SliceStable(queue, func(i, j int) bool { return queue[i].FinishesAt.Before(queue[j].FinishesAt) })
+	d.queue[villageID] = queue
+}
+
+// Jobs zwraca zdarzenia zaplanowane dla schedulera
+func (d *DB) Jobs() []Job {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Job{}, d.jobs...)
+}
+
+// Protected mówi, czy gracz ma jeszcze ochronę początkujących
+func (d *DB) Protected(userID int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.protected[userID].After(time.Now())
+}
+
+// AddSession otwiera sesję gracza i zwraca jej id (w Postgresie robi to logowanie)
+func (d *DB) AddSession(userID int, expiresAt time.Time) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nextSessionID++
+	id := fmt.Sprintf("session-%d", d.nextSessionID)
+	d.sessions[id] = session{userID: userID, expiresAt: expiresAt}
+	return id
+}
+
+// RevokeSession zamyka sesję (wylogowanie)
+func (d *DB) RevokeSession(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if s, ok := d.sessions[id]; ok {
+		s.revoked = true
+		d.sessions[id] = s
+	}
+}
+
+// SetBan nakłada (ban != nil) albo zdejmuje blokadę konta
+func (d *DB) SetBan(userID int, ban *repo.Ban) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ban == nil {
+		delete(d.bans, userID)
+		return
+	}
+	d.bans[userID] = *ban
+}
+
+// VerifyEmail potwierdza adres e-mail gracza
+func (d *DB) VerifyEmail(userID int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.verified[userID] = true
+}
+
+// SetSitting włącza albo kończy zastępstwo owner -> sitter
+func (d *DB) SetSitting(ownerID, sitterID int, active bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !active {
+		delete(d.sittings, [2]int{ownerID, sitterID})
+		return
+	}
+	d.sittings[[2]int{ownerID, sitterID}] = true
+}
+
+// SitterActions zwraca dziennik zastępców
+func (d *DB) SitterActions() []repo.SitterAction {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]repo.SitterAction{}, d.sitterActions...)
+}
+
+// ===== Logowanie =====
+
+type auth struct{ d *DB }
+
+// 2FA nie jest w pamięci modelowane - konta logują się samym hasłem
+func (a auth) Credentials(ctx context.Context, username string) (repo.Credentials, error) {
+	a.d.mu.Lock()
+	defer a.d.mu.Unlock()
+	for _, user := range a.d.users {
+		if user.Username == username {
+			return repo.Credentials{
+				UserID:       user.ID,
+				Username:     user.Username,
+				Role:         user.Role,
+				PasswordHash: a.d.passwords[user.ID],
+				LockedUntil:  a.d.failures[user.ID].lockedUntil,
+			}, nil
+		}
+	}
+	return repo.Credentials{}, repo.ErrNotFound
+}
+
+func (a auth) RecordLoginFailure(ctx context.Context, userID int, l repo.Lockout) error {
+	a.d.mu.Lock()
+	defer a.d.mu.Unlock()
+	a.d.failures[userID] = a.d.failures[userID].next(l, time.Now())
+	return nil
+}
+
+func (a auth) ResetLoginFailures(ctx context.Context, userID int) error {
+	a.d.mu.Lock()
+	defer a.d.mu.Unlock()
+	delete(a.d.failures, userID)
+	return nil
+}
+
+func (a auth) UnknownLoginLockedUntil(ctx context.Context, usernameHash string) (*time.Time, error) {
+	a.d.mu.Lock()
+	defer a.d.mu.Unlock()
+	return a.d.unknown[usernameHash].lockedUntil, nil
+}
+
+func (a auth) RecordUnknownLoginFailure(ctx context.Context, usernameHash string, l repo.Lockout, retention time.Duration) error {
+	a.d.mu.Lock()
+	defer a.d.mu.Unlock()
+	now := time.Now()
+	a.d.unknown[usernameHash] = a.d.unknown[usernameHash].next(l, now)
+	for hash, f := range a.d.unknown {
+		if f.updatedAt.Before(now.Add(-retention)) {
+			delete(a.d.unknown, hash)
+		}
+	}
+	return nil
+}
+
+func (a auth) CreateSession(ctx context.Context, s repo.NewSession) error {
+	a.d.mu.Lock()
+	defer a.d.mu.Unlock()
+	a.d.sessions[s.ID] = session{userID: s.UserID, expiresAt: time.Now().Add(s.TTL)}
+	return nil
+}
+
+func (a auth) IssueEmailToken(ctx context.Context, userID int, purpose, tokenHash string, ttl time.Duration) error {
+	a.d.mu.Lock()
+	defer a.d.mu.Unlock()
+	for hash, t := range a.d.emailTokens {
+		if t.userID == userID && t.purpose == purpose {
+			t.used = true
+			a.d.emailTokens[hash] = t
+		}
+	}
+	a.d.emailTokens[tokenHash] = emailToken{userID: userID, purpose: purpose, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// ===== Panel admina =====
+
+type admin struct{ d *DB }
+
+func (a admin) SearchUsers(ctx context.Context, search string, limit, offset int) ([]repo.UserSummary, int, error) {
+	a.d.mu.Lock()
+	defer a.d.mu.Unlock()
+	search = strings.ToLower(search)
+	var matching []repo.UserSummary
+	for _, user := range a.d.users {
+		if strings.Contains(strings.ToLower(user.Username), search) || strings.Contains(strings.ToLower(user.Email), search) {
+			ban, banned := a.d.bans[user.ID]
+			banned = banned && (ban.ExpiresAt == nil || ban.ExpiresAt.After(time.Now()))
+			matching = append(matching, repo.UserSummary{User: *user, Banned: banned})
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool { return matching[i].ID < matching[j].ID })
+	page := []repo.UserSummary{}
+	for i := offset; i < len(matching) && i < offset+limit; i++ {
+		page = append(page, matching[i])
+	}
+	return page, len(matching), nil
+}
+
+func (a admin) SetRole(ctx context.Context, userID int, role string) (string, error) {
+	a.d.mu.Lock()
+	defer a.d.mu.Unlock()
+	user, ok := a.d.users[userID]
+	if !ok {
+		return "", repo.ErrNotFound
+	}
+	oldRole := user.Role
+	user.Role = role
+	for id, s := range a.d.sessions {
+		if s.userID == userID {
+			s.revoked = true
+			a.d.sessions[id] = s
+		}
+	}
+	return oldRole, nil
+}
+
+func (a admin) Audit(ctx context.Context, e repo.AuditEntry) error {
+	a.d.mu.Lock()
+	defer a.d.mu.Unlock()
+	e.ID = len(a.d.audit) + 1
+	e.CreatedAt = time.Now()
+	a.d.audit = append(a.d.audit, e)
+	return nil
+}
+
+func (a admin) AuditLog(ctx context.Context, limit, offset int) ([]repo.AuditEntry, int, error) {
+	a.d.mu.Lock()
+	defer a.d.mu.Unlock()
+	page := []repo.AuditEntry{}
+	for i := len(a.d.audit) - 1 - offset; i >= 0 && len(page) < limit; i-- {
+		e := a.d.audit[i]
+		if actor, ok := a.d.users[e.ActorID]; ok {
+			e.Actor = actor.Username
+		}
+		page = append(page, e)
+	}
+	return page, len(a.d.audit), nil
+}
+
+// ===== Dostęp =====
+
+type access struct{ d *DB }
+
+// unieważnionych pojedynczych tokenów nie ma - wylogowanie zamyka całą sesję
+func (a access) TokenRevoked(ctx context.Context, jti, sessionID string) (bool, error) {
+	a.d.mu.Lock()
+	defer a.d.mu.Unlock()
+	s, ok := a.d.sessions[sessionID]
+	return !ok || s.revoked || !s.expiresAt.After(time.Now()), nil
+}
+
+func (a access) ActiveBan(ctx context.Context, userID int) (*repo.Ban, error) {
+	a.d.mu.Lock()
+	defer a.d.mu.Unlock()
+	ban, ok := a.d.bans[userID]
+	if !ok || (ban.ExpiresAt != nil && !ban.ExpiresAt.After(time.Now())) {
+		return nil, nil
+	}
+	return &ban, nil
+}
+
+func (a access) SittingActive(ctx context.Context, ownerID, sitterID int) (bool, error) {
+	a.d.mu.Lock()
+	defer a.d.mu.Unlock()
+	return a.d.sittings[[2]int{ownerID, sitterID}], nil
+}
+
+func (a access) EmailVerified(ctx context.Context, userID int) (bool, error) {
+	a.d.mu.Lock()
+	defer a.d.mu.Unlock()
+	if _, ok := a.d.users[userID]; !ok {
+		return false, repo.ErrNotFound
+	}
+	return a.d.verified[userID], nil
+}
+
+func (a access) LogSitterAction(ctx context.Context, act repo.SitterAction) error {
+	a.d.mu.Lock()
+	defer a.d.mu.Unlock()
+	a.d.sitterActions = append(a.d.sitterActions, act)
+	return nil
+}
+
+// ===== Gracze =====
+
+type users struct{ d *DB }
+
+// nazwa i e-mail są unikalne jak w schemacie
+func (u users) Create(ctx context.Context, nu repo.NewUser) (repo.User, error) {
+	u.d.mu.Lock()
+	defer u.d.mu.Unlock()
+	for _, user := range u.d.users {
+		if user.Username == nu.Username || user.Email == nu.Email {
+			return repo.User{}, repo.ErrDuplicate
+		}
+	}
+	u.d.nextUserID++
+	user := repo.User{ID: u.d.nextUserID, Username: nu.Username, Email: nu.Email, Role: "player", CreatedAt: time.Now()}
+	u.d.users[user.ID] = &user
+	u.d.passwords[user.ID] = nu.PasswordHash
+	u.d.protected[user.ID] = nu.ProtectedUntil
+	return user, nil
+}
+
+func (u users) ByID(ctx context.Context, id int) (repo.User, error) {
+	u.d.mu.Lock()
+	defer u.d.mu.Unlock()
+	user, ok := u.d.users[id]
+	if !ok {
+		return repo.User{}, repo.ErrNotFound
+	}
+	return *user, nil
+}
+
+func (u users) ByUsername(ctx context.Context, username string) (repo.User, error) {
+	u.d.mu.Lock()
+	defer u.d.mu.Unlock()
+	for _, user := range u.d.users {
+		if user.Username == username {
+			return *user, nil
+		}
+	}
+	return repo.User{}, repo.ErrNotFound
+}
+
+func (u users) SetPoints(ctx context.Context, id, points int) error {
+	u.d.mu.Lock()
+	defer u.d.mu.Unlock()
+	if user, ok := u.d.users[id]; ok {
+		user.Points = points
+	}
+	return nil
+}
+
+func (u users) EndBeginnerProtection(ctx context.Context, id int) error {
+	u.d.mu.Lock()
+	defer u.d.mu.Unlock()
+	delete(u.d.protected, id)
+	return nil
+}
+
+func (u users) ProtectedUntil(ctx context.Context, id int) (*time.Time, error) {
+	u.d.mu.Lock()
+	defer u.d.mu.Unlock()
+	until, ok := u.d.protected[id]
+	if !ok || !until.After(time.Now()) {
+		return nil, nil
+	}
+	return &until, nil
+}
+
+func (u users) Vacation(ctx context.Context, id int) (*repo.Vacation, error) {
+	u.d.mu.Lock()
+	defer u.d.mu.Unlock()
+	return u.d.startedVacation(id, time.Now()), nil
+}
+
+// ===== Wioski =====
+
+type villages struct{ d *DB }
+
+func (v villages) Create(ctx context.Context, userID int, name string) (repo.Village, error) {
+	v.d.mu.Lock()
+	defer v.d.mu.Unlock()
+	v.d.nextVillageID++
+	village := repo.Village{ID: v.d.nextVillageID, UserID: userID, Name: name, CreatedAt: time.Now()}
+	v.d.villages[village.ID] = &village
+	return village, nil
+}
+
+func (v villages) ByID(ctx context.Context, id int) (repo.Village, error) {
+	v.d.mu.Lock()
+	defer v.d.mu.Unlock()
+	village, ok := v.d.villages[id]
+	if !ok {
+		return repo.Village{}, repo.ErrNotFound
+	}
+	return *village, nil
+}
+
+func (v villages) ListByUser(ctx context.Context, userID int) ([]repo.Village, error) {
+	v.d.mu.Lock()
+	defer v.d.mu.Unlock()
+	var list []repo.Village
+	for _, village := range v.d.villages {
+		if village.UserID == userID {
+			list = append(list, *village)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+func (v villages) CountByUser(ctx context.Context, userID int) (int, error) {
+	list, _ := v.ListByUser(ctx, userID)
+	return len(list), nil
+}
+
+func (v villages) Rename(ctx context.Context, id int, name string) error {
+	v.d.mu.Lock()
+	defer v.d.mu.Unlock()
+	village, ok := v.d.villages[id]
+	if !ok {
+		return repo.ErrNotFound
+	}
+	village.Name = name
+	return nil
+}
+
+// jak ON DELETE CASCADE w schemacie
+func (v villages) Delete(ctx context.Context, id int) error {
+	v.d.mu.Lock()
+	defer v.d.mu.Unlock()
+	if _, ok := v.d.villages[id]; !ok {
+		return repo.ErrNotFound
+	}
+	delete(v.d.villages, id)
+	delete(v.d.resources, id)
+	delete(v.d.buildings, id)
+	delete(v.d.units, id)
+	delete(v.d.research, id)
+	delete(v.d.queue, id)
+	return nil
+}
+
+func (v villages) SetPoints(ctx context.Context, id, points int) error {
+	v.d.mu.Lock()
+	defer v.d.mu.Unlock()
+	village, ok := v.d.villages[id]
+	if !ok {
+		return repo.ErrNotFound
+	}
+	village.Points = points
+	return nil
+}
+
+func (v villages) SumPointsByUser(ctx context.Context, userID int) (int, error) {
+	list, _ := v.ListByUser(ctx, userID)
+	total := 0
+	for _, village := range list {
+		total += village.Points
+	}
+	return total, nil
+}
+
+func (v villages) Snapshot(ctx context.Context, id int) (repo.VillageSnapshot, error) {
+	v.d.mu.Lock()
+	defer v.d.mu.Unlock()
+	village, ok := v.d.villages[id]
+	res, hasRes := v.d.resources[id]
+	if !ok || !hasRes {
+		return repo.VillageSnapshot{}, repo.ErrNotFound
+	}
+	snap := repo.VillageSnapshot{
+		Village:   *village,
+		Resources: *res,
+		Buildings: append([]repo.Building{}, v.d.buildings[id]...),
+		Units:     append([]repo.Unit{}, v.d.units[id]...),
+		Research:  append([]repo.QueuedResearch{}, v.d.queue[id]...),
+	}
+	if village.UserID != 0 {
+		snap.Vacation = v.d.startedVacation(village.UserID, time.Now())
+	}
+	return snap, nil
+}
+
+// ===== Surowce =====
+
+type resources struct{ d *DB }
+
+func (r resources) Init(ctx context.Context, villageID, wood, clay, iron int) error {
+	r.d.mu.Lock()
+	defer r.d.mu.Unlock()
+	r.d.resources[villageID] = &repo.Resources{VillageID: villageID, Wood: wood, Clay: clay, Iron: iron, UpdatedAt: time.Now()}
+	return nil
+}
+
+func (r resources) Get(ctx context.Context, villageID int) (repo.Resources, error) {
+	r.d.mu.Lock()
+	defer r.d.mu.Unlock()
+	res, ok := r.d.resources[villageID]
+	if !ok {
+		return repo.Resources{}, repo.ErrNotFound
+	}
+	return *res, nil
+}
+
+// w pamięci transakcje i tak wykonują się po kolei
+func (r resources) Lock(ctx context.Context, villageID int) (repo.Resources, error) {
+	return r.Get(ctx, villageID)
+}
+
+func (r resources) Save(ctx context.Context, res repo.Resources) error {
+	r.d.mu.Lock()
+	defer r.d.mu.Unlock()
+	if _, ok := r.d.resources[res.VillageID]; !ok {
+		return nil // jak UPDATE bez pasującego wiersza
+	}
+	r.d.resources[res.VillageID] = &res
+	return nil
+}
+
+func (r resources) Spend(ctx context.Context, villageID, wood, clay, iron int) error {
+	r.d.mu.Lock()
+	defer r.d.mu.Unlock()
+	res, ok := r.d.resources[villageID]
+	if !ok || res.Wood < wood || res.Clay < clay || res.Iron < iron {
+		return repo.ErrNotEnoughRes
+	}
+	res.Wood -= wood
+	res.Clay -= clay
+	res.Iron -= iron
+	return nil
+}
+
+// ===== Budynki =====
+
+type buildings struct{ d *DB }
+
+func (b buildings) Init(ctx context.Context, villageID int, types []string, level int) error {
+	b.d.mu.Lock()
+	defer b.d.mu.Unlock()
+	for _, t := range types {
+		b.d.buildings[villageID] = append(b.d.buildings[villageID], repo.Building{Type: t, Level: level})
+	}
+	return nil
+}
+
+func (b buildings) List(ctx context.Context, villageID int) ([]repo.Building, error) {
+	b.d.mu.Lock()
+	defer b.d.mu.Unlock()
+	return append([]repo.Building{}, b.d.buildings[villageID]...), nil
+}
+
+func (b buildings) Level(ctx context.Context, villageID int, buildingType string) (int, error) {
+	b.d.mu.Lock()
+	defer b.d.mu.Unlock()
+	for _, building := range b.d.buildings[villageID] {
+		if building.Type == buildingType {
+			return building.Level, nil
+		}
+	}
+	return 0, repo.ErrNotFound
+}
+
+func (b buildings) Set(ctx context.Context, villageID int, buildingType string, level int) error {
+	b.d.mu.Lock()
+	defer b.d.mu.Unlock()
+	list := b.d.buildings[villageID]
+	for i := range list {
+		if list[i].Type == buildingType {
+			list[i].Level = level
+			return nil
+		}
+	}
+	return repo.ErrNotFound
+}
+
+func (b buildings) LevelUp(ctx context.Context, villageID int, buildingType string, from int) error {
+	b.d.mu.Lock()
+	defer b.d.mu.Unlock()
+	list := b.d.buildings[villageID]
+	for i := range list {
+		if list[i].Type == buildingType && list[i].Level == from {
+			list[i].Level++
+			return nil
+		}
+	}
+	return repo.ErrConflict
+}
+
+func (b buildings) MaxLevelForUser(ctx context.Context, userID int, buildingType string) (int, error) {
+	b.d.mu.Lock()
+	defer b.d.mu.Unlock()
+	max := 0
+	for id, village := range b.d.villages {
+		if village.UserID != userID {
+			continue
+		}
+		for _, building := range b.d.buildings[id] {
+			if building.Type == buildingType && building.Level > max {
+				max = building.Level
+			}
+		}
+	}
+	return max, nil
+}
+
+// ===== Jednostki =====
+
+type units struct{ d *DB }
+
+func (u units) Init(ctx context.Context, villageID int, types []string) error {
+	u.d.mu.Lock()
+	defer u.d.mu.Unlock()
+	for _, t := range types {
+		u.d.units[villageID] = append(u.d.units[villageID], repo.Unit{Type: t})
+	}
+	return nil
+}
+
+func (u units) List(ctx context.Context, villageID int) ([]repo.Unit, error) {
+	u.d.mu.Lock()
+	defer u.d.mu.Unlock()
+	return append([]repo.Unit{}, u.d.units[villageID]...), nil
+}
+
+func (u units) Add(ctx context.Context, villageID int, unitType string, delta int) error {
+	u.d.mu.Lock()
+	defer u.d.mu.Unlock()
+	list := u.d.units[villageID]
+	for i := range list {
+		if list[i].Type == unitType {
+			list[i].Count += delta
+			return nil
+		}
+	}
+	u.d.units[villageID] = append(list, repo.Unit{Type: unitType, Count: delta})
+	return nil
+}
+
+func (u units) Set(ctx context.Context, villageID int, unitType string, count int) error {
+	u.d.mu.Lock()
+	defer u.d.mu.Unlock()
+	if _, ok := u.d.villages[villageID]; !ok {
+		return repo.ErrNotFound
+	}
+	list := u.d.units[villageID]
+	for i := range list {
+		if list[i].Type == unitType {
+			list[i].Count = count
+			return nil
+		}
+	}
+	u.d.units[villageID] = append(list, repo.Unit{Type: unitType, Count: count})
+	return nil
+}
+
+// ===== Badania =====
+
+type research struct{ d *DB }
+
+func (r research) Set(ctx context.Context, villageID int, unitType string, level int) error {
+	r.d.mu.Lock()
+	defer r.d.mu.Unlock()
+	if r.d.research[villageID] == nil {
+		r.d.research[villageID] = map[string]int{}
+	}
+	r.d.research[villageID][unitType] = level
+	return nil
+}
+
+func (r research) Level(ctx context.Context, villageID int, unitType string) (int, error) {
+	r.d.mu.Lock()
+	defer r.d.mu.Unlock()
+	return r.d.research[villageID][unitType], nil
+}
+
+func (r research) Levels(ctx context.Context, villageID int) (map[string]int, error) {
+	r.d.mu.Lock()
+	defer r.d.mu.Unlock()
+	levels := map[string]int{}
+	for t, l := range r.d.research[villageID] {
+		levels[t] = l
+	}
+	return levels, nil
+}
+
+func (r research) Queue(ctx context.Context, villageID int) ([]repo.QueuedResearch, error) {
+	r.d.mu.Lock()
+	defer r.d.mu.Unlock()
+	return append([]repo.QueuedResearch{}, r.d.queue[villageID]...), nil
+}
+
+func (r research) Enqueue(ctx context.Context, villageID int, q repo.QueuedResearch) error {
+	r.d.mu.Lock()
+	defer r.d.mu.Unlock()
+	r.d.enqueue(villageID, q)
+	return nil
+}
+
+func (r research) CompleteDue(ctx context.Context, villageID int) error {
+	r.d.mu.Lock()
+	defer r.d.mu.Unlock()
+	now := time.Now()
+	// badania kończące się po starcie trwającego urlopu czekają na jego koniec
+	var vacation *repo.Vacation
+	if village, ok := r.d.villages[villageID]; ok {
+		vacation = r.d.startedVacation(village.UserID, now)
+	}
+	var waiting []repo.QueuedResearch
+	for _, q := range r.d.queue[villageID] {
+		if q.FinishesAt.After(now) || (vacation != nil && vacation.StartsAt.Before(q.FinishesAt)) {
+			waiting = append(waiting, q)
+			continue
+		}
+		if r.d.research[villageID] == nil {
+			r.d.research[villageID] = map[string]int{}
+		}
+		r.d.research[villageID][q.UnitType] = q.Level
+	}
+	r.d.queue[villageID] = waiting
+	return nil
+}
+
+// ===== Zdarzenia schedulera =====
+
+type jobs struct{ d *DB }
+
+func (j jobs) Schedule(ctx context.Context, kind string, payload interface{}, dueAt time.Time) error {
+	j.d.mu.Lock()
+	defer j.d.mu.Unlock()
+	j.d.jobs = append(j.d.jobs, Job{Kind: kind, Payload: payload, DueAt: dueAt})
+	return nil
+}
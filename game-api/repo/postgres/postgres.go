@@ -0,0 +1,816 @@
+// Package postgres implementuje repozytoria gry na bazie PostgreSQL (schemat z db/migrations).
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"PawTribalWars/repo"
+	"PawTribalWars/scheduler"
+	"github.com/lib/pq"
+)
+
+// New zwraca komplet repozytoriów działających na podanym połączeniu
+func New(db *sql.DB) repo.Store {
+	return newStore(db, transactor{db})
+}
+
+// *sql.DB albo *sql.Tx - te same zapytania działają w transakcji i poza nią
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func newStore(q querier, tx repo.Transactor) repo.Store {
+	return repo.Store{
+		Users:     users{q},
+		Auth:      auth{q},
+		Access:    access{q},
+		Admin:     admin{q},
+		Villages:  villages{q},
+		Resources: resources{q},
+		Buildings: buildings{q},
+		Units:     units{q},
+		Research:  research{q},
+		Jobs:      jobs{q},
+		Tx:        tx,
+	}
+}
+
+// ===== Transakcje =====
+
+type transactor struct{ db *sql.DB }
+
+func (t transactor) InTx(ctx context.Context, fn func(repo.Store) error) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(newStore(tx, joined{tx})); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// WithTx zwraca repozytoria działające w już otwartej transakcji (np. zdarzenia
+// schedulera); InTx nie otwiera wtedy nowej
+func WithTx(tx *sql.Tx) repo.Store {
+	return newStore(tx, joined{tx})
+}
+
+// w trwającej transakcji InTx tylko wykonuje fn na jej repozytoriach
+type joined struct{ tx *sql.Tx }
+
+func (j joined) InTx(ctx context.Context, fn func(repo.Store) error) error {
+	return fn(newStore(j.tx, j))
+}
+
+// zamienia brak wiersza na błąd niezależny od bazy
+func notFound(err error) error {
+	if err == sql.ErrNoRows {
+		return repo.ErrNotFound
+	}
+	return err
+}
+
+// naruszenie ograniczenia UNIQUE
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// EscapeLike zamienia wyszukiwaną frazę na wzorzec dla ILIKE '%' || $1 || '%':
+// znaki % i _ z wyszukiwania są dosłowne
+func EscapeLike(search string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(search)
+}
+
+// ===== Gracze =====
+
+type users struct{ db querier }
+
+func (u users) Create(ctx context.Context, nu repo.NewUser) (repo.User, error) {
+	user := repo.User{Username: nu.Username, Email: nu.Email}
+	err := u.db.QueryRowContext(ctx, `
+		INSERT INTO users (username, email, password_hash, protected_until) VALUES ($1, $2, $3, $4)
+		RETURNING id, COALESCE(role, 'player'), created_at
+	`, nu.Username, nu.Email, nu.PasswordHash, nu.ProtectedUntil).Scan(&user.ID, &user.Role, &user.CreatedAt)
+	if isUniqueViolation(err) {
+		return repo.User{}, repo.ErrDuplicate
+	}
+	return user, err
+}
+
+func (u users) ByID(ctx context.Context, id int) (repo.User, error) {
+	return u.one(ctx, "id=$1", id)
+}
+
+func (u users) ByUsername(ctx context.Context, username string) (repo.User, error) {
+	return u.one(ctx, "username=$1", username)
+}
+
+func (u users) one(ctx context.Context, where string, arg interface{}) (repo.User, error) {
+	var user repo.User
+	err := u.db.QueryRowContext(ctx,
+		"SELECT id, username, email, COALESCE(role, 'player'), COALESCE(points, 0), created_at FROM users WHERE "+where, arg,
+	).Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.Points, &user.CreatedAt)
+	return user, notFound(err)
+}
+
+func (u users) SetPoints(ctx context.Context, id, points int) error {
+	_, err := u.db.ExecContext(ctx, "UPDATE users SET points=$1 WHERE id=$2", points, id)
+	return err
+}
+
+func (u users) EndBeginnerProtection(ctx context.Context, id int) error {
+	_, err := u.db.ExecContext(ctx, "UPDATE users SET protected_until=NULL WHERE id=$1 AND protected_until IS NOT NULL", id)
+	return err
+}
+
+// także urlop już zakończony, dopóki scheduler go nie rozliczy
+func (u users) ProtectedUntil(ctx context.Context, id int) (*time.Time, error) {
+	var until *time.Time
+	err := u.db.QueryRowContext(ctx,
+		"SELECT CASE WHEN protected_until > NOW() THEN protected_until END FROM users WHERE id=$1", id,
+	).Scan(&until)
+	return until, notFound(err)
+}
+
+func (u users) Vacation(ctx context.Context, id int) (*repo.Vacation, error) {
+	var v repo.Vacation
+	err := u.db.QueryRowContext(ctx, `
+		SELECT starts_at, COALESCE(ended_at, ends_at) FROM vacations
+		WHERE user_id=$1 AND settled_at IS NULL AND starts_at <= NOW()
+	`, id).Scan(&v.StartsAt, &v.EndsAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ===== Logowanie =====
+
+type auth struct{ db querier }
+
+func (a auth) Credentials(ctx context.Context, username string) (repo.Credentials, error) {
+	var c repo.Credentials
+	err := a.db.QueryRowContext(ctx, `
+		SELECT id, username, password_hash, COALESCE(role, 'player'), totp_enabled_at IS NOT NULL, locked_until
+		FROM users WHERE username=$1
+	`, username).Scan(&c.UserID, &c.Username, &c.PasswordHash, &c.Role, &c.MFAEnabled, &c.LockedUntil)
+	return c, notFound(err)
+}
+
+// licznik i blokada liczone w jednym UPDATE, więc równoległe próby się nie gubią
+func (a auth) RecordLoginFailure(ctx context.Context, userID int, l repo.Lockout) error {
+	_, err := a.db.ExecContext(ctx, `
+		UPDATE users SET
+			failed_logins = failed_logins + 1,
+			locked_until = CASE WHEN failed_logins + 1 >= $2
+				THEN NOW() + LEAST($3 * POWER(2, LEAST(failed_logins + 1 - $2, 30)), $4) * INTERVAL '1 second'
+				ELSE locked_until END
+		WHERE id=$1
+	`, userID, l.Threshold, l.Base.Seconds(), l.Max.Seconds())
+	return err
+}
+
+func (a auth) ResetLoginFailures(ctx context.Context, userID int) error {
+	_, err := a.db.ExecContext(ctx,
+		"UPDATE users SET failed_logins=0, locked_until=NULL WHERE id=$1 AND failed_logins > 0", userID)
+	return err
+}
+
+func (a auth) UnknownLoginLockedUntil(ctx context.Context, usernameHash string) (*time.Time, error) {
+	var lockedUntil *time.Time
+	err := a.db.QueryRowContext(ctx,
+		"SELECT locked_until FROM login_failures WHERE username_hash=$1", usernameHash).Scan(&lockedUntil)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return lockedUntil, err
+}
+
+// ten sam wzór co RecordLoginFailure, tylko w tabeli login_failures
+func (a auth) RecordUnknownLoginFailure(ctx context.Context, usernameHash string, l repo.Lockout, retention time.Duration) error {
+	_, err := a.db.ExecContext(ctx, `
+		INSERT INTO login_failures AS f (username_hash, failed_logins, locked_until)
+		VALUES ($1, 1, CASE WHEN 1 >= $2 THEN NOW() + LEAST($3, $4) * INTERVAL '1 second' END)
+		ON CONFLICT (username_hash) DO UPDATE SET
+			failed_logins = f.failed_logins + 1,
+			locked_until = CASE WHEN f.failed_logins + 1 >= $2
+				THEN NOW() + LEAST($3 * POWER(2, LEAST(f.failed_logins + 1 - $2, 30)), $4) * INTERVAL '1 second'
+				ELSE f.locked_until END,
+			updated_at = NOW()
+	`, usernameHash, l.Threshold, l.Base.Seconds(), l.Max.Seconds())
+	if err != nil {
+		return err
+	}
+	// przy okazji sprzątamy dawno nieużywane nazwy
+	_, err = a.db.ExecContext(ctx,
+		"DELETE FROM login_failures WHERE updated_at < NOW() - $1 * INTERVAL '1 second'", retention.Seconds())
+	return err
+}
+
+func (a auth) CreateSession(ctx context.Context, s repo.NewSession) error {
+	_, err := a.db.ExecContext(ctx, `
+		INSERT INTO sessions (id, user_id, refresh_hash, user_agent, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW() + $6 * INTERVAL '1 second')
+	`, s.ID, s.UserID, s.RefreshHash, s.UserAgent, s.IP, s.TTL.Seconds())
+	return err
+}
+
+// unieważnienie starych tokenów i nowy token w jednym zapytaniu, także poza transakcją
+func (a auth) IssueEmailToken(ctx context.Context, userID int, purpose, tokenHash string, ttl time.Duration) error {
+	_, err := a.db.ExecContext(ctx, `
+		WITH used AS (
+			UPDATE email_tokens SET used_at=NOW() WHERE user_id=$1 AND purpose=$2 AND used_at IS NULL
+		)
+		INSERT INTO email_tokens (token_hash, user_id, purpose, expires_at)
+		VALUES ($3, $1, $2, NOW() + $4 * INTERVAL '1 second')
+	`, userID, purpose, tokenHash, ttl.Seconds())
+	return err
+}
+
+// ===== Dostęp =====
+
+type access struct{ db querier }
+
+func (a access) TokenRevoked(ctx context.Context, jti, sessionID string) (bool, error) {
+	var revoked bool
+	err := a.db.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti=$1)
+		    OR NOT EXISTS(SELECT 1 FROM sessions WHERE id=$2 AND revoked_at IS NULL AND expires_at > NOW())
+	`, jti, sessionID).Scan(&revoked)
+	return revoked, err
+}
+
+func (a access) ActiveBan(ctx context.Context, userID int) (*repo.Ban, error) {
+	var ban repo.Ban
+	err := a.db.QueryRowContext(ctx, `
+		SELECT reason, expires_at FROM bans
+		WHERE user_id=$1 AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, userID).Scan(&ban.Reason, &ban.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ban, nil
+}
+
+func (a access) SittingActive(ctx context.Context, ownerID, sitterID int) (bool, error) {
+	var active bool
+	err := a.db.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM sitters
+		              WHERE owner_id=$1 AND sitter_id=$2 AND revoked_at IS NULL
+		                AND starts_at <= NOW() AND ends_at > NOW())
+	`, ownerID, sitterID).Scan(&active)
+	return active, err
+}
+
+func (a access) EmailVerified(ctx context.Context, userID int) (bool, error) {
+	var verified bool
+	err := a.db.QueryRowContext(ctx, "SELECT email_verified_at IS NOT NULL FROM users WHERE id=$1", userID).Scan(&verified)
+	return verified, notFound(err)
+}
+
+func (a access) LogSitterAction(ctx context.Context, act repo.SitterAction) error {
+	_, err := a.db.ExecContext(ctx, `
+		INSERT INTO sitter_actions (owner_id, sitter_id, method, path, status, ip)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, act.OwnerID, act.SitterID, act.Method, act.Path, act.Status, act.IP)
+	return err
+}
+
+// ===== Panel admina =====
+
+type admin struct{ db querier }
+
+func (a admin) SearchUsers(ctx context.Context, search string, limit, offset int) ([]repo.UserSummary, int, error) {
+	rows, err := a.db.QueryContext(ctx, `
+		SELECT u.id, u.username, u.email, COALESCE(u.role, 'player'), COALESCE(u.points, 0), u.created_at,
+		       EXISTS(SELECT 1 FROM bans b WHERE b.user_id = u.id AND b.lifted_at IS NULL
+		              AND (b.expires_at IS NULL OR b.expires_at > NOW())) AS banned,
+		       COUNT(*) OVER () AS total
+		FROM users u
+		WHERE $1 = '' OR u.username ILIKE '%' || $1 || '%' OR u.email ILIKE '%' || $1 || '%'
+		ORDER BY u.id
+		LIMIT $2 OFFSET $3
+	`, EscapeLike(search), limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	total := 0
+	list := []repo.UserSummary{}
+	for rows.Next() {
+		var u repo.UserSummary
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.Role, &u.Points, &u.CreatedAt, &u.Banned, &total); err != nil {
+			return nil, 0, err
+		}
+		list = append(list, u)
+	}
+	return list, total, rows.Err()
+}
+
+// wiersz gracza zablokowany do końca transakcji, żeby poprzednia rola w dzienniku była prawdziwa
+func (a admin) SetRole(ctx context.Context, userID int, role string) (string, error) {
+	var oldRole string
+	err := a.db.QueryRowContext(ctx, "SELECT COALESCE(role, 'player') FROM users WHERE id=$1 FOR UPDATE", userID).Scan(&oldRole)
+	if err != nil {
+		return "", notFound(err)
+	}
+	if _, err := a.db.ExecContext(ctx, "UPDATE users SET role=$1 WHERE id=$2", role, userID); err != nil {
+		return "", err
+	}
+	_, err = a.db.ExecContext(ctx, "UPDATE sessions SET revoked_at=NOW() WHERE user_id=$1 AND revoked_at IS NULL", userID)
+	return oldRole, err
+}
+
+func (a admin) Audit(ctx context.Context, e repo.AuditEntry) error {
+	_, err := a.db.ExecContext(ctx, `
+		INSERT INTO audit_log (actor_id, action, target_type, target_id, details, ip)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, e.ActorID, e.Action, e.TargetType, e.TargetID, []byte(e.Details), e.IP)
+	return err
+}
+
+func (a admin) AuditLog(ctx context.Context, limit, offset int) ([]repo.AuditEntry, int, error) {
+	rows, err := a.db.QueryContext(ctx, `
+		SELECT a.id, COALESCE(a.actor_id, 0), COALESCE(u.username, ''), a.action, a.target_type, a.target_id,
+		       a.details, COALESCE(a.ip, ''), a.created_at, COUNT(*) OVER () AS total
+		FROM audit_log a
+		LEFT JOIN users u ON a.actor_id = u.id
+		ORDER BY a.id DESC
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	total := 0
+	list := []repo.AuditEntry{}
+	for rows.Next() {
+		var e repo.AuditEntry
+		var details []byte
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Actor, &e.Action, &e.TargetType, &e.TargetID,
+			&details, &e.IP, &e.CreatedAt, &total); err != nil {
+			return nil, 0, err
+		}
+		e.Details = details
+		list = append(list, e)
+	}
+	return list, total, rows.Err()
+}
+
+// ===== Wioski =====
+
+type villages struct{ db querier }
+
+func (v villages) Create(ctx context.Context, userID int, name string) (repo.Village, error) {
+	village := repo.Village{UserID: userID, Name: name}
+	err := v.db.QueryRowContext(ctx,
+		"INSERT INTO villages (user_id, name) VALUES ($1, $2) RETURNING id, points, created_at",
+		userID, name,
+	).Scan(&village.ID, &village.Points, &village.CreatedAt)
+	return village, err
+}
+
+func (v villages) ByID(ctx context.Context, id int) (repo.Village, error) {
+	var village repo.Village
+	var userID sql.NullInt64
+	err := v.db.QueryRowContext(ctx,
+		"SELECT id, user_id, name, points, created_at FROM villages WHERE id=$1", id,
+	).Scan(&village.ID, &userID, &village.Name, &village.Points, &village.CreatedAt)
+	village.UserID = int(userID.Int64)
+	return village, notFound(err)
+}
+
+func (v villages) ListByUser(ctx context.Context, userID int) ([]repo.Village, error) {
+	rows, err := v.db.QueryContext(ctx,
+		"SELECT id, name, points, created_at FROM villages WHERE user_id=$1 ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []repo.Village
+	for rows.Next() {
+		village := repo.Village{UserID: userID}
+		if err := rows.Scan(&village.ID, &village.Name, &village.Points, &village.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, village)
+	}
+	return list, rows.Err()
+}
+
+func (v villages) CountByUser(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := v.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM villages WHERE user_id=$1", userID).Scan(&count)
+	return count, err
+}
+
+func (v villages) Rename(ctx context.Context, id int, name string) error {
+	return v.affected(v.db.ExecContext(ctx, "UPDATE villages SET name=$1 WHERE id=$2", name, id))
+}
+
+func (v villages) Delete(ctx context.Context, id int) error {
+	return v.affected(v.db.ExecContext(ctx, "DELETE FROM villages WHERE id=$1", id))
+}
+
+func (v villages) SetPoints(ctx context.Context, id, points int) error {
+	return v.affected(v.db.ExecContext(ctx, "UPDATE villages SET points=$1 WHERE id=$2", points, id))
+}
+
+func (v villages) SumPointsByUser(ctx context.Context, userID int) (int, error) {
+	var total int
+	err := v.db.QueryRowContext(ctx, "SELECT COALESCE(SUM(points), 0) FROM villages WHERE user_id=$1", userID).Scan(&total)
+	return total, err
+}
+
+// listy wioski przychodzą jako tablice JSON, żeby całość była jednym zapytaniem
+const snapshotSQL = `
+	SELECT v.id, v.user_id, v.name, v.points, v.created_at,
+	       r.wood, r.clay, r.iron, r.updated_at,
+	       COALESCE((SELECT json_agg(json_build_object('type', b.type, 'level', b.level) ORDER BY b.id)
+	                 FROM buildings b WHERE b.village_id = v.id), '[]'),
+	       COALESCE((SELECT json_agg(json_build_object('type', u.type, 'count', u.count) ORDER BY u.id)
+	                 FROM units u WHERE u.village_id = v.id), '[]'),
+	       COALESCE((SELECT json_agg(json_build_object('unit_type', q.unit_type, 'level', q.level,
+	                                                   'finishes_at', EXTRACT(EPOCH FROM q.finishes_at))
+	                                 ORDER BY q.finishes_at, q.id)
+	                 FROM research_queue q WHERE q.village_id = v.id), '[]'),
+	       vac.starts_at, COALESCE(vac.ended_at, vac.ends_at)
+	FROM villages v
+	JOIN resources r ON r.village_id = v.id
+	LEFT JOIN vacations vac ON vac.user_id = v.user_id AND vac.settled_at IS NULL AND vac.starts_at <= NOW()
+	WHERE v.id = $1`
+
+func (v villages) Snapshot(ctx context.Context, id int) (repo.VillageSnapshot, error) {
+	var snap repo.VillageSnapshot
+	var userID sql.NullInt64
+	var vacationStart, vacationEnd sql.NullTime
+	var buildingsJSON, unitsJSON, researchJSON []byte
+	err := v.db.QueryRowContext(ctx, snapshotSQL, id).Scan(
+		&snap.Village.ID, &userID, &snap.Village.Name, &snap.Village.Points, &snap.Village.CreatedAt,
+		&snap.Resources.Wood, &snap.Resources.Clay, &snap.Resources.Iron, &snap.Resources.UpdatedAt,
+		&buildingsJSON, &unitsJSON, &researchJSON, &vacationStart, &vacationEnd,
+	)
+	if err != nil {
+		return snap, notFound(err)
+	}
+	snap.Village.UserID = int(userID.Int64)
+	snap.Resources.VillageID = snap.Village.ID
+	if vacationStart.Valid {
+		snap.Vacation = &repo.Vacation{StartsAt: vacationStart.Time, EndsAt: vacationEnd.Time}
+	}
+
+	var buildingRows []struct {
+		Type  string `json:"type"`
+		Level int    `json:"level"`
+	}
+	var unitRows []struct {
+		Type  string `json:"type"`
+		Count int    `json:"count"`
+	}
+	var researchRows []struct {
+		UnitType   string  `json:"unit_type"`
+		Level      int     `json:"level"`
+		FinishesAt float64 `json:"finishes_at"` // sekundy od epoki; EXTRACT z TIMESTAMPTZ nie zależy od strefy sesji
+	}
+	for raw, dst := range map[*[]byte]interface{}{&buildingsJSON: &buildingRows, &unitsJSON: &unitRows, &researchJSON: &researchRows} {
+		if err := json.Unmarshal(*raw, dst); err != nil {
+			return snap, err
+		}
+	}
+	for _, b := range buildingRows {
+		snap.Buildings = append(snap.Buildings, repo.Building{Type: b.Type, Level: b.Level})
+	}
+	for _, u := range unitRows {
+		snap.Units = append(snap.Units, repo.Unit{Type: u.Type, Count: u.Count})
+	}
+	for _, q := range researchRows {
+		snap.Research = append(snap.Research, repo.QueuedResearch{
+			UnitType:   q.UnitType,
+			Level:      q.Level,
+			FinishesAt: time.Unix(0, int64(q.FinishesAt*1e9)).UTC(),
+		})
+	}
+	return snap, nil
+}
+
+// brak zmienionego wiersza = brak wioski
+func (v villages) affected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return repo.ErrNotFound
+	}
+	return nil
+}
+
+// ===== Surowce =====
+
+type resources struct{ db querier }
+
+func (r resources) Init(ctx context.Context, villageID, wood, clay, iron int) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO resources (village_id, wood, clay, iron) VALUES ($1, $2, $3, $4)",
+		villageID, wood, clay, iron)
+	return err
+}
+
+func (r resources) Get(ctx context.Context, villageID int) (repo.Resources, error) {
+	res := repo.Resources{VillageID: villageID}
+	err := r.db.QueryRowContext(ctx,
+		"SELECT wood, clay, iron, updated_at FROM resources WHERE village_id=$1", villageID,
+	).Scan(&res.Wood, &res.Clay, &res.Iron, &res.UpdatedAt)
+	return res, notFound(err)
+}
+
+func (r resources) Lock(ctx context.Context, villageID int) (repo.Resources, error) {
+	res := repo.Resources{VillageID: villageID}
+	err := r.db.QueryRowContext(ctx,
+		"SELECT wood, clay, iron, updated_at FROM resources WHERE village_id=$1 FOR UPDATE", villageID,
+	).Scan(&res.Wood, &res.Clay, &res.Iron, &res.UpdatedAt)
+	return res, notFound(err)
+}
+
+func (r resources) Save(ctx context.Context, res repo.Resources) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE resources SET wood=$1, clay=$2, iron=$3, updated_at=$4 WHERE village_id=$5",
+		res.Wood, res.Clay, res.Iron, res.UpdatedAt, res.VillageID)
+	return err
+}
+
+// warunek w WHERE zapobiega wydaniu tych samych surowców przez dwa równoległe żądania
+func (r resources) Spend(ctx context.Context, villageID, wood, clay, iron int) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE resources SET wood=wood-$1, clay=clay-$2, iron=iron-$3
+		WHERE village_id=$4 AND wood>=$1 AND clay>=$2 AND iron>=$3
+	`, wood, clay, iron, villageID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return repo.ErrNotEnoughRes
+	}
+	return nil
+}
+
+// ===== Budynki =====
+
+type buildings struct{ db querier }
+
+func (b buildings) Init(ctx context.Context, villageID int, types []string, level int) error {
+	for _, t := range types {
+		_, err := b.db.ExecContext(ctx,
+			"INSERT INTO buildings (village_id, type, level) VALUES ($1, $2, $3)", villageID, t, level)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b buildings) List(ctx context.Context, villageID int) ([]repo.Building, error) {
+	rows, err := b.db.QueryContext(ctx, "SELECT type, level FROM buildings WHERE village_id=$1 ORDER BY id", villageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []repo.Building{}
+	for rows.Next() {
+		var building repo.Building
+		if err := rows.Scan(&building.Type, &building.Level); err != nil {
+			return nil, err
+		}
+		list = append(list, building)
+	}
+	return list, rows.Err()
+}
+
+func (b buildings) Level(ctx context.Context, villageID int, buildingType string) (int, error) {
+	var level int
+	err := b.db.QueryRowContext(ctx,
+		"SELECT level FROM buildings WHERE village_id=$1 AND type=$2", villageID, buildingType,
+	).Scan(&level)
+	return level, notFound(err)
+}
+
+func (b buildings) Set(ctx context.Context, villageID int, buildingType string, level int) error {
+	res, err := b.db.ExecContext(ctx,
+		"UPDATE buildings SET level=$1 WHERE village_id=$2 AND type=$3", level, villageID, buildingType)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return repo.ErrNotFound
+	}
+	return nil
+}
+
+// poziom zmieniany względnie i tylko z oczekiwanego, żeby równoległa
+// rozbudowa nie nadpisała innej
+func (b buildings) LevelUp(ctx context.Context, villageID int, buildingType string, from int) error {
+	res, err := b.db.ExecContext(ctx,
+		"UPDATE buildings SET level=level+1 WHERE village_id=$1 AND type=$2 AND level=$3", villageID, buildingType, from)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return repo.ErrConflict
+	}
+	return nil
+}
+
+func (b buildings) MaxLevelForUser(ctx context.Context, userID int, buildingType string) (int, error) {
+	var level int
+	err := b.db.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(b.level), 0)
+		FROM buildings b
+		JOIN villages v ON b.village_id = v.id
+		WHERE v.user_id=$1 AND b.type=$2
+	`, userID, buildingType).Scan(&level)
+	return level, err
+}
+
+// ===== Jednostki =====
+
+type units struct{ db querier }
+
+func (u units) Init(ctx context.Context, villageID int, types []string) error {
+	for _, t := range types {
+		_, err := u.db.ExecContext(ctx,
+			"INSERT INTO units (village_id, type, count) VALUES ($1, $2, 0)", villageID, t)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u units) List(ctx context.Context, villageID int) ([]repo.Unit, error) {
+	rows, err := u.db.QueryContext(ctx, "SELECT type, count FROM units WHERE village_id=$1 ORDER BY id", villageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []repo.Unit{}
+	for rows.Next() {
+		var unit repo.Unit
+		if err := rows.Scan(&unit.Type, &unit.Count); err != nil {
+			return nil, err
+		}
+		list = append(list, unit)
+	}
+	return list, rows.Err()
+}
+
+func (u units) Add(ctx context.Context, villageID int, unitType string, delta int) error {
+	_, err := u.db.ExecContext(ctx, `
+		INSERT INTO units (village_id, type, count) VALUES ($1, $2, $3)
+		ON CONFLICT (village_id, type) DO UPDATE SET count = units.count + EXCLUDED.count
+	`, villageID, unitType, delta)
+	return err
+}
+
+// wioski mają wiersze tylko części jednostek - brakujący wiersz jest dokładany
+func (u units) Set(ctx context.Context, villageID int, unitType string, count int) error {
+	res, err := u.db.ExecContext(ctx, `
+		INSERT INTO units (village_id, type, count) SELECT id, $2, $3 FROM villages WHERE id=$1
+		ON CONFLICT (village_id, type) DO UPDATE SET count = EXCLUDED.count
+	`, villageID, unitType, count)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return repo.ErrNotFound
+	}
+	return nil
+}
+
+// ===== Badania =====
+
+type research struct{ db querier }
+
+// badania w kolejce, których urlop jeszcze nie rozliczył, czekają na jego koniec
+const researchPausedSQL = `EXISTS(
+	SELECT 1 FROM vacations vac JOIN villages vil ON vil.user_id = vac.user_id
+	WHERE vil.id = research_queue.village_id AND vac.settled_at IS NULL
+	  AND vac.starts_at <= NOW() AND vac.starts_at < research_queue.finishes_at)`
+
+func (r research) Set(ctx context.Context, villageID int, unitType string, level int) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO research (village_id, unit_type, level) VALUES ($1, $2, $3)
+		ON CONFLICT (village_id, unit_type) DO UPDATE SET level = EXCLUDED.level
+	`, villageID, unitType, level)
+	return err
+}
+
+func (r research) Level(ctx context.Context, villageID int, unitType string) (int, error) {
+	var level int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT level FROM research WHERE village_id=$1 AND unit_type=$2", villageID, unitType,
+	).Scan(&level)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return level, err
+}
+
+func (r research) Levels(ctx context.Context, villageID int) (map[string]int, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT unit_type, level FROM research WHERE village_id=$1", villageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	levels := map[string]int{}
+	for rows.Next() {
+		var unitType string
+		var level int
+		if err := rows.Scan(&unitType, &level); err != nil {
+			return nil, err
+		}
+		levels[unitType] = level
+	}
+	return levels, rows.Err()
+}
+
+func (r research) Queue(ctx context.Context, villageID int) ([]repo.QueuedResearch, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT unit_type, level, finishes_at FROM research_queue WHERE village_id=$1 ORDER BY finishes_at, id", villageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	queue := []repo.QueuedResearch{}
+	for rows.Next() {
+		var q repo.QueuedResearch
+		if err := rows.Scan(&q.UnitType, &q.Level, &q.FinishesAt); err != nil {
+			return nil, err
+		}
+		queue = append(queue, q)
+	}
+	return queue, rows.Err()
+}
+
+func (r research) Enqueue(ctx context.Context, villageID int, q repo.QueuedResearch) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO research_queue (village_id, unit_type, level, finishes_at) VALUES ($1, $2, $3, $4)",
+		villageID, q.UnitType, q.Level, q.FinishesAt)
+	return err
+}
+
+// przeniesienie z kolejki do research w jednym zapytaniu, więc działa też w cudzej transakcji
+func (r research) CompleteDue(ctx context.Context, villageID int) error {
+	_, err := r.db.ExecContext(ctx, `
+		WITH done AS (
+			DELETE FROM research_queue
+			WHERE village_id=$1 AND finishes_at <= NOW() AND NOT `+researchPausedSQL+`
+			RETURNING unit_type, level
+		)
+		INSERT INTO research (village_id, unit_type, level)
+		SELECT DISTINCT ON (unit_type) $1::INT, unit_type, level FROM done ORDER BY unit_type, level DESC
+		ON CONFLICT (village_id, unit_type) DO UPDATE SET level = EXCLUDED.level
+	`, villageID)
+	return err
+}
+
+// ===== Zdarzenia schedulera =====
+
+type jobs struct{ db querier }
+
+func (j jobs) Schedule(ctx context.Context, kind string, payload interface{}, dueAt time.Time) error {
+	return scheduler.Schedule(execer{ctx, j.db}, kind, payload, dueAt)
+}
+
+// scheduler.Execer na połączeniu albo transakcji repozytoriów
+type execer struct {
+	ctx context.Context
+	db  querier
+}
+
+func (e execer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return e.db.ExecContext(e.ctx, query, args...)
+}
@@ -0,0 +1,309 @@
+// Package repo opisuje dostęp do danych gry przez interfejsy, żeby logikę
+// handlerów dało się uruchomić zarówno na Postgresie, jak i w pamięci.
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrNotEnoughRes = errors.New("not enough resources")
+	// wiersz zmienił się między odczytem a zapisem (równoległe żądanie)
+	ErrConflict = errors.New("concurrent update")
+	// zapis naruszyłby unikalność (np. zajęta nazwa gracza)
+	ErrDuplicate = errors.New("already exists")
+)
+
+type User struct {
+	ID        int
+	Username  string
+	Email     string
+	Role      string
+	Points    int
+	CreatedAt time.Time
+}
+
+// NewUser to konto zakładane przy rejestracji
+type NewUser struct {
+	Username       string
+	Email          string
+	PasswordHash   string
+	ProtectedUntil time.Time // koniec ochrony początkujących
+}
+
+type Village struct {
+	ID        int
+	UserID    int // 0 = wioska barbarzyńska
+	Name      string
+	Points    int
+	CreatedAt time.Time
+}
+
+// UserSummary to gracz na liście panelu admina
+type UserSummary struct {
+	User
+	Banned bool // ma aktywną blokadę
+}
+
+// Credentials to dane konta sprawdzane przy logowaniu
+type Credentials struct {
+	UserID       int
+	Username     string
+	Role         string
+	PasswordHash string
+	MFAEnabled   bool
+	LockedUntil  *time.Time // blokada po nieudanych logowaniach
+}
+
+// Lockout to blokada logowania: od Threshold nieudanych prób z rzędu konto
+// blokuje się na Base, a każda kolejna próba podwaja czas, najwyżej do Max
+type Lockout struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+}
+
+// Duration zwraca czas blokady po failures nieudanych próbach (0 = bez blokady)
+func (l Lockout) Duration(failures int) time.Duration {
+	if failures < l.Threshold {
+		return 0
+	}
+	d := time.Duration(float64(l.Base) * math.Pow(2, math.Min(float64(failures-l.Threshold), 30)))
+	if d > l.Max {
+		return l.Max
+	}
+	return d
+}
+
+// NewSession to sesja urządzenia otwierana przy logowaniu
+type NewSession struct {
+	ID          string
+	UserID      int
+	RefreshHash string // skrót sekretu refresh tokenu
+	UserAgent   string
+	IP          string
+	TTL         time.Duration
+}
+
+// AuditEntry to wpis dziennika akcji administracyjnych
+type AuditEntry struct {
+	ID         int
+	ActorID    int
+	Actor      string // nazwa wykonującego (tylko przy odczycie)
+	Action     string
+	TargetType string
+	TargetID   string
+	Details    json.RawMessage
+	IP         string
+	CreatedAt  time.Time
+}
+
+// Vacation to urlop, który się zaczął, a nie został jeszcze rozliczony; do
+// rozliczenia wioski nie produkują między StartsAt a EndsAt
+type Vacation struct {
+	StartsAt time.Time
+	EndsAt   time.Time // planowany albo wcześniejszy koniec
+}
+
+// Active mówi, czy urlop trwa w chwili now
+func (v *Vacation) Active(now time.Time) bool {
+	return v != nil && !v.StartsAt.After(now) && v.EndsAt.After(now)
+}
+
+// Ban to aktywna blokada konta
+type Ban struct {
+	Reason    string
+	ExpiresAt *time.Time // nil = na zawsze
+}
+
+// SitterAction to zmiana wykonana przez zastępcę na koncie właściciela
+type SitterAction struct {
+	OwnerID  int
+	SitterID int
+	Method   string
+	Path     string
+	Status   int
+	IP       string
+}
+
+type Resources struct {
+	VillageID int
+	Wood      int
+	Clay      int
+	Iron      int
+	UpdatedAt time.Time
+}
+
+type Building struct {
+	Type  string
+	Level int
+}
+
+type Unit struct {
+	Type  string
+	Count int
+}
+
+// badanie czekające w kolejce kuźni
+type QueuedResearch struct {
+	UnitType   string
+	Level      int
+	FinishesAt time.Time
+}
+
+// VillageSnapshot to cały stan wioski odczytany naraz (podgląd wioski)
+type VillageSnapshot struct {
+	Village   Village
+	Resources Resources
+	Buildings []Building
+	Units     []Unit
+	Research  []QueuedResearch // kolejka kuźni, od najwcześniej kończącego się
+	// nierozliczony urlop właściciela, nil = brak
+	Vacation *Vacation
+}
+
+type UserRepo interface {
+	// Create zakłada konto; ErrDuplicate, gdy nazwa albo e-mail są zajęte
+	Create(ctx context.Context, u NewUser) (User, error)
+	ByID(ctx context.Context, id int) (User, error)
+	ByUsername(ctx context.Context, username string) (User, error)
+	SetPoints(ctx context.Context, id, points int) error
+	// koniec ochrony początkujących (próg punktów albo własny atak)
+	EndBeginnerProtection(ctx context.Context, id int) error
+	// kiedy kończy się ochrona początkujących (nil = brak albo już minęła)
+	ProtectedUntil(ctx context.Context, id int) (*time.Time, error)
+	// urlop gracza, który się zaczął i nie został rozliczony (nil = brak)
+	Vacation(ctx context.Context, id int) (*Vacation, error)
+}
+
+// AccessRepo odpowiada na pytania zadawane przy każdym żądaniu gracza:
+// czy token jest ważny, czy konto nie jest zablokowane, czy zastępstwo trwa
+type AccessRepo interface {
+	// czy token (jti) albo jego sesja zostały unieważnione lub wygasły
+	TokenRevoked(ctx context.Context, jti, sessionID string) (bool, error)
+	// najnowsza aktywna blokada konta, nil = brak
+	ActiveBan(ctx context.Context, userID int) (*Ban, error)
+	// czy zastępstwo owner -> sitter trwa w tej chwili
+	SittingActive(ctx context.Context, ownerID, sitterID int) (bool, error)
+	EmailVerified(ctx context.Context, userID int) (bool, error)
+	// dziennik zmian wykonanych przez zastępcę
+	LogSitterAction(ctx context.Context, a SitterAction) error
+}
+
+// AuthRepo przechowuje to, czego potrzebuje logowanie: hasła, blokady po
+// nieudanych próbach, sesje urządzeń i jednorazowe tokeny wysyłane mailem
+type AuthRepo interface {
+	// dane logowania gracza o tej nazwie (ErrNotFound = nie ma takiego)
+	Credentials(ctx context.Context, username string) (Credentials, error)
+	RecordLoginFailure(ctx context.Context, userID int, l Lockout) error
+	ResetLoginFailures(ctx context.Context, userID int) error
+	// blokada nieistniejącej nazwy gracza, po skrócie nazwy (nil = brak)
+	UnknownLoginLockedUntil(ctx context.Context, usernameHash string) (*time.Time, error)
+	// nieudane logowanie na nieistniejącą nazwę; nazwy bez prób dłużej niż
+	// retention są zapominane
+	RecordUnknownLoginFailure(ctx context.Context, usernameHash string, l Lockout, retention time.Duration) error
+	CreateSession(ctx context.Context, s NewSession) error
+	// nowy token z maila; poprzednie niewykorzystane tokeny tego typu przestają działać
+	IssueEmailToken(ctx context.Context, userID int, purpose, tokenHash string, ttl time.Duration) error
+}
+
+// AdminRepo to odczyty i zmiany panelu admina, których gra sama nie wykonuje
+type AdminRepo interface {
+	// gracze, których nazwa albo e-mail zawiera search (bez rozróżniania wielkości
+	// liter), po id; zwraca też liczbę wszystkich pasujących
+	SearchUsers(ctx context.Context, search string, limit, offset int) ([]UserSummary, int, error)
+	// SetRole zmienia rolę gracza i zamyka jego sesje (rola siedzi w tokenie);
+	// zwraca poprzednią rolę
+	SetRole(ctx context.Context, userID int, role string) (string, error)
+	Audit(ctx context.Context, e AuditEntry) error
+	// dziennik od najnowszych wpisów i liczba wszystkich wpisów
+	AuditLog(ctx context.Context, limit, offset int) ([]AuditEntry, int, error)
+}
+
+type VillageRepo interface {
+	Create(ctx context.Context, userID int, name string) (Village, error)
+	ByID(ctx context.Context, id int) (Village, error)
+	ListByUser(ctx context.Context, userID int) ([]Village, error)
+	CountByUser(ctx context.Context, userID int) (int, error)
+	Rename(ctx context.Context, id int, name string) error
+	Delete(ctx context.Context, id int) error
+	SetPoints(ctx context.Context, id, points int) error
+	SumPointsByUser(ctx context.Context, userID int) (int, error)
+	// Snapshot odczytuje wioskę z surowcami, budynkami, jednostkami i kolejką jednym zapytaniem
+	Snapshot(ctx context.Context, id int) (VillageSnapshot, error)
+}
+
+type ResourceRepo interface {
+	Init(ctx context.Context, villageID, wood, clay, iron int) error
+	Get(ctx context.Context, villageID int) (Resources, error)
+	// Lock to Get, który w transakcji blokuje wiersz do jej końca
+	Lock(ctx context.Context, villageID int) (Resources, error)
+	Save(ctx context.Context, r Resources) error
+	// Spend odejmuje koszt tylko wtedy, gdy starcza surowców (ErrNotEnoughRes)
+	Spend(ctx context.Context, villageID, wood, clay, iron int) error
+}
+
+type BuildingRepo interface {
+	Init(ctx context.Context, villageID int, types []string, level int) error
+	List(ctx context.Context, villageID int) ([]Building, error)
+	Level(ctx context.Context, villageID int, buildingType string) (int, error)
+	// Set ustawia poziom istniejącego budynku (ErrNotFound, gdy go nie ma)
+	Set(ctx context.Context, villageID int, buildingType string, level int) error
+	// LevelUp podnosi poziom o jeden, o ile nadal wynosi from (inaczej ErrConflict)
+	LevelUp(ctx context.Context, villageID int, buildingType string, from int) error
+	// najwyższy poziom budynku we wszystkich wioskach gracza
+	MaxLevelForUser(ctx context.Context, userID int, buildingType string) (int, error)
+}
+
+type UnitRepo interface {
+	Init(ctx context.Context, villageID int, types []string) error
+	List(ctx context.Context, villageID int) ([]Unit, error)
+	Add(ctx context.Context, villageID int, unitType string, delta int) error
+	// Set ustawia liczbę jednostek, w razie potrzeby zakładając wiersz (ErrNotFound = brak wioski)
+	Set(ctx context.Context, villageID int, unitType string, count int) error
+}
+
+type ResearchRepo interface {
+	Set(ctx context.Context, villageID int, unitType string, level int) error
+	// poziom badania, 0 = niezbadana
+	Level(ctx context.Context, villageID int, unitType string) (int, error)
+	// poziomy wszystkich zbadanych jednostek wioski
+	Levels(ctx context.Context, villageID int) (map[string]int, error)
+	// kolejka kuźni, od najwcześniej kończącego się
+	Queue(ctx context.Context, villageID int) ([]QueuedResearch, error)
+	Enqueue(ctx context.Context, villageID int, q QueuedResearch) error
+	// przenosi zakończone badania z kolejki do research (poza wstrzymanymi urlopem)
+	CompleteDue(ctx context.Context, villageID int) error
+}
+
+// JobRepo planuje zdarzenia schedulera w transakcji polecenia, które je wywołało
+type JobRepo interface {
+	Schedule(ctx context.Context, kind string, payload interface{}, dueAt time.Time) error
+}
+
+// Transactor wykonuje fn na repozytoriach działających w jednej transakcji:
+// błąd z fn wycofuje wszystkie jej zapisy. Wywołanie wewnątrz fn dołącza do
+// trwającej transakcji.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(Store) error) error
+}
+
+// Store zbiera wszystkie repozytoria jednej implementacji
+type Store struct {
+	Users     UserRepo
+	Auth      AuthRepo
+	Access    AccessRepo
+	Admin     AdminRepo
+	Villages  VillageRepo
+	Resources ResourceRepo
+	Buildings BuildingRepo
+	Units     UnitRepo
+	Research  ResearchRepo
+	Jobs      JobRepo
+	Tx        Transactor
+}
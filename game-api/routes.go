@@ -0,0 +1,181 @@
+package main
+
+import (
+	"net/http"
+
+	"PawTribalWars/handlers"
+	"PawTribalWars/openapi"
+	"github.com/gorilla/mux"
+)
+
+// routerDeps to zależności tras: main podaje repozytoria Postgresa, testy repo/memory
+type routerDeps struct {
+	Game     *handlers.Game
+	Accounts *handlers.Accounts
+	Admin    *handlers.Admin
+	Guard    *handlers.Guard
+	Metrics  http.Handler
+}
+
+// newRouter rejestruje wszystkie trasy API; każda musi być opisana w
+// openapi/openapi.json (sprawdza to openapi.CheckRoutes przy starcie)
+func newRouter(d routerDeps) *mux.Router {
+	r := mux.NewRouter()
+	// odpowiedzi i błędy w JSON, ciała JSON czytane jak formularze
+	r.Use(handlers.JSONMiddleware)
+	r.Use(handlers.RateLimitMiddleware)
+	r.NotFoundHandler = http.HandlerFunc(handlers.NotFoundHandler)
+	r.MethodNotAllowedHandler = http.HandlerFunc(handlers.MethodNotAllowedHandler)
+
+	// Poza wersjonowaniem: klucze JWT, metryki i dokumentacja
+	r.HandleFunc("/.well-known/jwks.json", handlers.JWKSHandler).Methods("GET")
+	r.Handle("/metrics", d.Metrics).Methods("GET")
+	r.HandleFunc("/openapi.json", openapi.SpecHandler).Methods("GET")
+	r.HandleFunc("/docs", openapi.DocsHandler).Methods("GET")
+
+	// Aktualne API: zasoby gry zagnieżdżone pod wioską. Pełne ścieżki zamiast
+	// PathPrefix().Subrouter(), bo odziedziczony matcher prefiksu gubi w mux
+	// odpowiedź 405 dla złej metody.
+	sharedRoutes(r, handlers.APIPrefix, d)
+	villageRoutes(r, handlers.APIPrefix, d)
+
+	// Stare trasy bez prefiksu zostają jako przestarzałe aliasy (nagłówek Deprecation)
+	legacy := r.NewRoute().Subrouter()
+	legacy.Use(handlers.Deprecated)
+	sharedRoutes(legacy, "", d)
+	legacyVillageRoutes(legacy, d)
+
+	return r
+}
+
+// trasy o tych samych ścieżkach w /api/v1 i w starym API (prefix "")
+func sharedRoutes(r *mux.Router, prefix string, d routerDeps) {
+	// User authentication
+	r.HandleFunc(prefix+"/register", d.Accounts.RegisterHandler).Methods("POST")
+	r.HandleFunc(prefix+"/login", d.Accounts.LoginHandler).Methods("POST")
+	r.HandleFunc(prefix+"/login/mfa", handlers.LoginMFAHandler).Methods("POST")
+	r.HandleFunc(prefix+"/refresh", handlers.RefreshHandler).Methods("POST")
+	r.HandleFunc(prefix+"/email/verify", handlers.VerifyEmailHandler).Methods("POST")
+	r.Handle(prefix+"/email/resend", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.ResendVerificationHandler)))).Methods("POST")
+	r.HandleFunc(prefix+"/password/forgot", handlers.ForgotPasswordHandler).Methods("POST")
+	r.HandleFunc(prefix+"/password/reset", handlers.ResetPasswordHandler).Methods("POST")
+
+	// Account self-service
+	r.Handle(prefix+"/me", d.Guard.AuthMiddleware(http.HandlerFunc(handlers.GetMeHandler))).Methods("GET")
+	r.Handle(prefix+"/me", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.UpdateMeHandler)))).Methods("PUT")
+	r.Handle(prefix+"/me", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.DeleteMeHandler)))).Methods("DELETE")
+	r.Handle(prefix+"/me/password", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.ChangePasswordHandler)))).Methods("POST")
+	r.Handle(prefix+"/me/restore", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.RestoreMeHandler)))).Methods("POST")
+	r.HandleFunc(prefix+"/me/email/confirm", handlers.ConfirmEmailChangeHandler).Methods("POST")
+
+	// Two-factor authentication (TOTP)
+	r.Handle(prefix+"/2fa/enroll", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.EnrollTOTPHandler)))).Methods("POST")
+	r.Handle(prefix+"/2fa/confirm", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.ConfirmTOTPHandler)))).Methods("POST")
+	r.Handle(prefix+"/2fa/recovery-codes", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.RegenerateRecoveryCodesHandler)))).Methods("POST")
+	r.Handle(prefix+"/2fa/disable", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.DisableTOTPHandler)))).Methods("POST")
+	r.Handle(prefix+"/logout", d.Guard.AuthMiddleware(http.HandlerFunc(handlers.LogoutHandler))).Methods("POST")
+	r.Handle(prefix+"/logout-all", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.LogoutAllHandler)))).Methods("POST")
+	r.Handle(prefix+"/sessions", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.GetSessionsHandler)))).Methods("GET")
+	r.Handle(prefix+"/sessions/{id}", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.RevokeSessionHandler)))).Methods("DELETE")
+
+	// Account sitting (zastępca gra na koncie właściciela z ograniczeniami)
+	r.Handle(prefix+"/sitters", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.GetSittersHandler)))).Methods("GET")
+	r.Handle(prefix+"/sitters", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.AddSitterHandler)))).Methods("POST")
+	r.Handle(prefix+"/sitters/log", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.SitterLogHandler)))).Methods("GET")
+	r.Handle(prefix+"/sitters/{id}", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.RemoveSitterHandler)))).Methods("DELETE")
+	r.Handle(prefix+"/sitting/{id}/login", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.SitterLoginHandler)))).Methods("POST")
+
+	// Vacation mode
+	r.Handle(prefix+"/vacation", d.Guard.AuthMiddleware(http.HandlerFunc(handlers.GetVacationHandler))).Methods("GET")
+	r.Handle(prefix+"/vacation", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(handlers.StartVacationHandler)))).Methods("POST")
+	r.Handle(prefix+"/vacation", d.Guard.AuthMiddleware(handlers.DenySitters(http.HandlerFunc(d.Game.EndVacationHandler)))).Methods("DELETE")
+
+	// Public player profiles
+	r.Handle(prefix+"/players/{id}", d.Guard.AuthMiddleware(http.HandlerFunc(handlers.GetPlayerProfileHandler))).Methods("GET")
+
+	// World map
+	r.Handle(prefix+"/map", d.Guard.AuthMiddleware(http.HandlerFunc(handlers.GetMapHandler))).Methods("GET")
+
+	// Admin API (moderator/admin, każda akcja w audit_log)
+	admin := r.PathPrefix(prefix + "/admin").Subrouter()
+	admin.Use(d.Guard.AuthMiddleware, handlers.RequireRole(handlers.RoleModerator, handlers.RoleAdmin))
+	admin.Handle("/users", handlers.RequirePermission(handlers.PermViewUsers)(http.HandlerFunc(d.Admin.SearchUsersHandler))).Methods("GET")
+	admin.Handle("/users/{id}/role", handlers.RequirePermission(handlers.PermChangeRoles)(http.HandlerFunc(d.Admin.ChangeRoleHandler))).Methods("PUT")
+	admin.Handle("/users/{id}/ban", handlers.RequirePermission(handlers.PermBanUsers)(http.HandlerFunc(handlers.BanUserHandler))).Methods("POST")
+	admin.Handle("/users/{id}/unban", handlers.RequirePermission(handlers.PermBanUsers)(http.HandlerFunc(handlers.UnbanUserHandler))).Methods("POST")
+	admin.Handle("/users/{id}/bans", handlers.RequirePermission(handlers.PermBanUsers)(http.HandlerFunc(handlers.BanHistoryHandler))).Methods("GET")
+	admin.Handle("/reports", handlers.RequirePermission(handlers.PermHandleReports)(http.HandlerFunc(handlers.ListReportsHandler))).Methods("GET")
+	admin.Handle("/reports/{id}/resolve", handlers.RequirePermission(handlers.PermHandleReports)(http.HandlerFunc(handlers.ResolveReportHandler))).Methods("POST")
+	admin.Handle("/villages/{id}", handlers.RequirePermission(handlers.PermViewVillages)(http.HandlerFunc(d.Admin.GetVillageHandler))).Methods("GET")
+	admin.Handle("/villages/{id}/resources", handlers.RequirePermission(handlers.PermEditVillages)(http.HandlerFunc(d.Admin.SetResourcesHandler))).Methods("PUT")
+	admin.Handle("/villages/{id}/buildings/{type}", handlers.RequirePermission(handlers.PermEditVillages)(http.HandlerFunc(d.Admin.SetBuildingHandler))).Methods("PUT")
+	admin.Handle("/villages/{id}/units/{type}", handlers.RequirePermission(handlers.PermEditVillages)(http.HandlerFunc(d.Admin.SetUnitsHandler))).Methods("PUT")
+	admin.Handle("/audit", handlers.RequirePermission(handlers.PermViewAudit)(http.HandlerFunc(d.Admin.AuditLogHandler))).Methods("GET")
+	admin.Handle("/keys/rotate", handlers.RequirePermission(handlers.PermRotateKeys)(http.HandlerFunc(handlers.RotateKeysHandler))).Methods("POST")
+
+	// Zgłoszenia graczy do moderacji
+	r.Handle(prefix+"/reports", d.Guard.AuthMiddleware(d.Guard.RequireVerifiedEmail(http.HandlerFunc(handlers.CreateReportHandler)))).Methods("POST")
+
+	// Combat simulator
+	r.Handle(prefix+"/simulator", d.Guard.AuthMiddleware(http.HandlerFunc(handlers.SimulatorHandler))).Methods("POST")
+
+	// Real-time events
+	r.Handle(prefix+"/ws", d.Guard.AuthMiddleware(http.HandlerFunc(handlers.WebSocketHandler))).Methods("GET")
+	r.Handle(prefix+"/events", d.Guard.AuthMiddleware(http.HandlerFunc(handlers.EventsStreamHandler))).Methods("GET")
+
+	// Rankings
+	r.Handle(prefix+"/rankings/players", d.Guard.AuthMiddleware(http.HandlerFunc(handlers.GetPlayerRankingHandler))).Methods("GET")
+	r.Handle(prefix+"/rankings/tribes", d.Guard.AuthMiddleware(http.HandlerFunc(handlers.GetTribeRankingHandler))).Methods("GET")
+	r.Handle(prefix+"/rankings/oda", d.Guard.AuthMiddleware(http.HandlerFunc(handlers.GetODARankingHandler))).Methods("GET")
+	r.Handle(prefix+"/rankings/odd", d.Guard.AuthMiddleware(http.HandlerFunc(handlers.GetODDRankingHandler))).Methods("GET")
+	r.Handle(prefix+"/rankings/villages", d.Guard.AuthMiddleware(http.HandlerFunc(handlers.GetVillageRankingHandler))).Methods("GET")
+}
+
+// {prefix}/villages/{id}/...
+func villageRoutes(r *mux.Router, prefix string, d routerDeps) {
+	// Villages
+	r.Handle(prefix+"/villages", d.Guard.AuthMiddleware(http.HandlerFunc(d.Game.GetVillagesHandler))).Methods("GET")
+	r.Handle(prefix+"/villages", d.Guard.AuthMiddleware(d.Guard.RequireVerifiedEmail(d.Guard.DenyOnVacation(http.HandlerFunc(d.Game.CreateVillageHandler))))).Methods("POST")
+	r.Handle(prefix+"/villages/{id}", d.Guard.AuthMiddleware(d.Guard.DenyOnVacation(http.HandlerFunc(d.Game.UpdateVillageHandler)))).Methods("PUT")
+	r.Handle(prefix+"/villages/{id}", d.Guard.AuthMiddleware(handlers.DenySitters(d.Guard.DenyOnVacation(http.HandlerFunc(d.Game.DeleteVillageHandler))))).Methods("DELETE")
+	r.Handle(prefix+"/villages/{id}/resources", d.Guard.AuthMiddleware(http.HandlerFunc(d.Game.GetResourcesHandler))).Methods("GET")
+	r.Handle(prefix+"/villages/{id}/overview", d.Guard.AuthMiddleware(http.HandlerFunc(d.Game.GetVillageOverviewHandler))).Methods("GET")
+
+	// Buildings
+	r.Handle(prefix+"/villages/{id}/buildings", d.Guard.AuthMiddleware(http.HandlerFunc(d.Game.GetBuildingsHandler))).Methods("GET")
+	r.Handle(prefix+"/villages/{id}/buildings/{type}/cost", d.Guard.AuthMiddleware(http.HandlerFunc(d.Game.GetBuildingCostHandler))).Methods("GET")
+	r.Handle(prefix+"/villages/{id}/buildings/{type}/upgrade", d.Guard.AuthMiddleware(d.Guard.DenyOnVacation(http.HandlerFunc(d.Game.UpgradeBuildingHandler)))).Methods("POST")
+
+	// Units
+	r.Handle(prefix+"/villages/{id}/units", d.Guard.AuthMiddleware(http.HandlerFunc(d.Game.GetUnitsHandler))).Methods("GET")
+	r.Handle(prefix+"/villages/{id}/units/{type}/recruit", d.Guard.AuthMiddleware(d.Guard.DenyOnVacation(http.HandlerFunc(d.Game.RecruitUnitsHandler)))).Methods("POST")
+
+	// Smithy
+	r.Handle(prefix+"/villages/{id}/smithy", d.Guard.AuthMiddleware(http.HandlerFunc(d.Game.GetSmithyHandler))).Methods("GET")
+	r.Handle(prefix+"/villages/{id}/smithy/{type}/research", d.Guard.AuthMiddleware(d.Guard.DenyOnVacation(http.HandlerFunc(d.Game.StartResearchHandler)))).Methods("POST")
+}
+
+// stare trasy z village_id w parametrach zapytania
+func legacyVillageRoutes(r *mux.Router, d routerDeps) {
+	// Vilages endpoints
+	r.Handle("/villages", d.Guard.AuthMiddleware(http.HandlerFunc(d.Game.GetVillagesHandler))).Methods("GET")
+	r.Handle("/villages", d.Guard.AuthMiddleware(d.Guard.RequireVerifiedEmail(d.Guard.DenyOnVacation(http.HandlerFunc(d.Game.CreateVillageHandler))))).Methods("POST")
+	r.Handle("/villages/{id}", d.Guard.AuthMiddleware(d.Guard.DenyOnVacation(http.HandlerFunc(d.Game.UpdateVillageHandler)))).Methods("PUT")
+	r.Handle("/villages/{id}", d.Guard.AuthMiddleware(handlers.DenySitters(d.Guard.DenyOnVacation(http.HandlerFunc(d.Game.DeleteVillageHandler))))).Methods("DELETE")
+
+	// Resources
+	r.Handle("/resources", d.Guard.AuthMiddleware(http.HandlerFunc(d.Game.GetResourcesHandler))).Methods("GET")
+
+	// Buildings
+	r.Handle("/buildings", d.Guard.AuthMiddleware(http.HandlerFunc(d.Game.GetBuildingsHandler))).Methods("GET")
+	r.Handle("/buildings/upgrade", d.Guard.AuthMiddleware(d.Guard.DenyOnVacation(http.HandlerFunc(d.Game.UpgradeBuildingHandler)))).Methods("PUT")
+	r.Handle("/buildings/cost", d.Guard.AuthMiddleware(http.HandlerFunc(d.Game.GetBuildingCostHandler))).Methods("GET")
+
+	// Units
+	r.Handle("/units", d.Guard.AuthMiddleware(http.HandlerFunc(d.Game.GetUnitsHandler))).Methods("GET")
+	r.Handle("/units/recruit", d.Guard.AuthMiddleware(d.Guard.DenyOnVacation(http.HandlerFunc(d.Game.RecruitUnitsHandler)))).Methods("POST")
+
+	// Smithy
+	r.Handle("/smithy", d.Guard.AuthMiddleware(http.HandlerFunc(d.Game.GetSmithyHandler))).Methods("GET")
+	r.Handle("/smithy/research", d.Guard.AuthMiddleware(d.Guard.DenyOnVacation(http.HandlerFunc(d.Game.StartResearchHandler)))).Methods("POST")
+}
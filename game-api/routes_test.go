@@ -0,0 +1,23 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"PawTribalWars/handlers"
+	"PawTribalWars/openapi"
+	"PawTribalWars/repo/memory"
+)
+
+// każda trasa routera musi być opisana w openapi/openapi.json i odwrotnie
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	store := memory.New().Store()
+	r := newRouter(routerDeps{
+		Game:    handlers.NewGame(nil),
+		Guard:   handlers.NewGuard(store),
+		Metrics: http.NotFoundHandler(),
+	})
+	if err := openapi.CheckRoutes(r); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,231 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Handler wykonuje zdarzenie danego typu w transakcji, która je pobrała: zmiany
+// handlera i oznaczenie zdarzenia jako wykonanego zapisują się razem. Handler
+// nie commituje tx. Po błędzie jego zmiany są wycofywane, a zdarzenie wraca do
+// kolejki, więc handlery nadal muszą być idempotentne.
+type Handler func(ctx context.Context, tx *sql.Tx, payload json.RawMessage) error
+
+// Execer to *sql.DB albo *sql.Tx
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Schedule zapisuje zdarzenie do wykonania o czasie dueAt
+func Schedule(exec Execer, kind string, payload interface{}, dueAt time.Time) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = exec.Exec(
+		"INSERT INTO scheduled_events (kind, payload, due_at) VALUES ($1, $2, $3)",
+		kind, raw, dueAt,
+	)
+	return err
+}
+
+type Scheduler struct {
+	DB           *sql.DB
+	PollInterval time.Duration
+	MaxAttempts  int
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+
+	lagMillis atomic.Int64
+	processed atomic.Int64
+	retried   atomic.Int64
+	failed    atomic.Int64
+}
+
+func New(db *sql.DB) *Scheduler {
+	return &Scheduler{
+		DB:           db,
+		PollInterval: time.Second,
+		MaxAttempts:  10,
+		handlers:     map[string]Handler{},
+	}
+}
+
+func (s *Scheduler) Register(kind string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[kind] = h
+}
+
+// Run przetwarza zaległe zdarzenia aż do anulowania ctx
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+	for {
+		s.updateLag()
+		for {
+			ok, err := s.processNext(ctx)
+			if err != nil {
+				log.Println("scheduler:", err)
+				break
+			}
+			if !ok {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNext bierze najstarsze zaległe zdarzenie; SKIP LOCKED pozwala
+// kilku instancjom serwera pracować na tej samej tabeli
+func (s *Scheduler) processNext(ctx context.Context) (bool, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	var kind string
+	var payload []byte
+	var attempts int
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, kind, payload, attempts FROM scheduled_events
+		WHERE status='pending' AND due_at <= NOW()
+		ORDER BY due_at, id
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`).Scan(&id, &kind, &payload, &attempts)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	h, ok := s.handlers[kind]
+	s.mu.RUnlock()
+
+	// savepoint pozwala wycofać zmiany nieudanego handlera i zapisać samą porażkę
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT job"); err != nil {
+		return false, err
+	}
+	var runErr error
+	if !ok {
+		runErr = fmt.Errorf("no handler for %q", kind)
+	} else {
+		runErr = safeRun(ctx, h, tx, payload)
+	}
+
+	if runErr == nil {
+		_, err = tx.ExecContext(ctx,
+			"UPDATE scheduled_events SET status='done', attempts=attempts+1, processed_at=NOW() WHERE id=$1", id)
+		if err != nil {
+			return false, err
+		}
+		if err := tx.Commit(); err != nil {
+			return false, err
+		}
+		s.processed.Add(1)
+		return true, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT job"); err != nil {
+		return false, err
+	}
+	attempts++
+	counter := &s.retried
+	if s.exhausted(attempts) {
+		log.Printf("scheduler: event %d (%s) failed permanently: %v", id, kind, runErr)
+		_, err = tx.ExecContext(ctx, `
+			UPDATE scheduled_events SET status='failed', attempts=$2, last_error=$3, processed_at=NOW()
+			WHERE id=$1`, id, attempts, runErr.Error())
+		counter = &s.failed
+	} else {
+		log.Printf("scheduler: event %d (%s) attempt %d failed: %v", id, kind, attempts, runErr)
+		_, err = tx.ExecContext(ctx, `
+			UPDATE scheduled_events SET attempts=$2, last_error=$3, due_at=NOW() + $4 * INTERVAL '1 second'
+			WHERE id=$1`, id, attempts, runErr.Error(), backoff(attempts).Seconds())
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	counter.Add(1)
+	return true, nil
+}
+
+// po MaxAttempts nieudanych próbach zdarzenie trafia do 'failed' i nie wraca do kolejki
+func (s *Scheduler) exhausted(attempts int) bool {
+	return attempts >= s.MaxAttempts
+}
+
+// backoff: 5s, 10s, 20s... maksymalnie godzina
+func backoff(attempts int) time.Duration {
+	d := 5 * time.Second * time.Duration(math.Pow(2, float64(attempts-1)))
+	if d > time.Hour || d <= 0 {
+		return time.Hour
+	}
+	return d
+}
+
+// panika w handlerze nie może zabić pętli schedulera
+func safeRun(ctx context.Context, h Handler, tx *sql.Tx, payload json.RawMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h(ctx, tx, payload)
+}
+
+// lag = jak długo najstarsze zaległe zdarzenie czeka po terminie
+func (s *Scheduler) updateLag() {
+	var lag float64
+	err := s.DB.QueryRow(`
+		SELECT COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(due_at)), 0)
+		FROM scheduled_events
+		WHERE status='pending' AND due_at <= NOW()
+	`).Scan(&lag)
+	if err != nil {
+		log.Println("scheduler: cannot compute lag:", err)
+		return
+	}
+	s.lagMillis.Store(int64(lag * 1000))
+}
+
+func (s *Scheduler) Lag() time.Duration {
+	return time.Duration(s.lagMillis.Load()) * time.Millisecond
+}
+
+// MetricsHandler wystawia metryki w formacie tekstowym Prometheusa
+func (s *Scheduler) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprintln(w, "# HELP scheduler_lag_seconds Age of the oldest overdue scheduled event.")
+		fmt.Fprintln(w, "# TYPE scheduler_lag_seconds gauge")
+		fmt.Fprintf(w, "scheduler_lag_seconds %g\n", s.Lag().Seconds())
+		fmt.Fprintln(w, "# HELP scheduler_events_total Scheduled events handled by this instance.")
+		fmt.Fprintln(w, "# TYPE scheduler_events_total counter")
+		fmt.Fprintf(w, "scheduler_events_total{result=\"done\"} %d\n", s.processed.Load())
+		fmt.Fprintf(w, "scheduler_events_total{result=\"retry\"} %d\n", s.retried.Load())
+		fmt.Fprintf(w, "scheduler_events_total{result=\"failed\"} %d\n", s.failed.Load())
+	})
+}
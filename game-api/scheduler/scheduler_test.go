@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoffDoublesUpToAnHour(t *testing.T) {
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{4, 40 * time.Second},
+		{10, 2560 * time.Second},
+		{11, time.Hour}, // 5120s przekracza limit
+		{20, time.Hour},
+		{200, time.Hour}, // przepełnienie Duration nie może dać ujemnego czasu
+	}
+	for _, c := range cases {
+		if got := backoff(c.attempts); got != c.want {
+			t.Errorf("backoff(%d) = %s, want %s", c.attempts, got, c.want)
+		}
+	}
+}
+
+func TestEventFailsAfterMaxAttempts(t *testing.T) {
+	s := New(nil)
+	if s.MaxAttempts != 10 {
+		t.Fatalf("default MaxAttempts = %d, want 10", s.MaxAttempts)
+	}
+
+	s.MaxAttempts = 3
+	cases := []struct {
+		attempts int
+		failed   bool
+	}{
+		{1, false},
+		{2, false},
+		{3, true},
+		{4, true},
+	}
+	for _, c := range cases {
+		if got := s.exhausted(c.attempts); got != c.failed {
+			t.Errorf("MaxAttempts=3, attempt %d: failed=%v, want %v", c.attempts, got, c.failed)
+		}
+	}
+}
+
+func TestSafeRunRecoversPanic(t *testing.T) {
+	h := func(ctx context.Context, tx *sql.Tx, payload json.RawMessage) error {
+		panic("boom")
+	}
+	err := safeRun(context.Background(), h, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("safeRun = %v, want panic error", err)
+	}
+}
@@ -0,0 +1,90 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// sekret z RFC 6238 (dodatek B): ASCII "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeMatchesRFC6238Vectors(t *testing.T) {
+	// wektory SHA-1 z RFC mają 8 cyfr, nasz kod to ich ostatnie 6
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, c := range cases {
+		got, err := Code(rfcSecret, Step(time.Unix(c.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("T=%d: got %s, want %s", c.unix, got, c.want)
+		}
+	}
+}
+
+func TestValidateAcceptsOneStepOfSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	cases := []struct {
+		step int64
+		ok   bool
+	}{
+		{current - 2, false},
+		{current - 1, true},
+		{current, true},
+		{current + 1, true},
+		{current + 2, false},
+	}
+	for _, c := range cases {
+		code, _ := Code(rfcSecret, c.step)
+		step, ok := Validate(rfcSecret, code, now)
+		if ok != c.ok || (ok && step != c.step) {
+			t.Errorf("step %+d: got %d %v, want ok=%v", c.step-current, step, ok, c.ok)
+		}
+	}
+
+	if _, ok := Validate(rfcSecret, "12345", now); ok {
+		t.Error("short code accepted")
+	}
+	code, _ := Code(rfcSecret, current)
+	if _, ok := Validate(rfcSecret, code[:3]+" "+code[3:], now); !ok {
+		t.Error("code with a space rejected")
+	}
+}
+
+func TestValidateAfterRejectsReusedStep(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	code, _ := Code(rfcSecret, current)
+
+	step, ok := ValidateAfter(rfcSecret, code, 0, now)
+	if !ok || step != current {
+		t.Fatalf("first use: got %d %v", step, ok)
+	}
+	// ten sam kod w tym samym oknie i chwilę później, wciąż w oknie skew
+	if _, ok := ValidateAfter(rfcSecret, code, step, now); ok {
+		t.Error("reused code accepted")
+	}
+	if _, ok := ValidateAfter(rfcSecret, code, step, now.Add(Period)); ok {
+		t.Error("reused code accepted in the next step")
+	}
+	// kod z wcześniejszego okna niż ostatnio użyte też nie przechodzi
+	older, _ := Code(rfcSecret, current-1)
+	if _, ok := ValidateAfter(rfcSecret, older, step, now); ok {
+		t.Error("older code accepted after a newer one")
+	}
+	next, _ := Code(rfcSecret, current+1)
+	if got, ok := ValidateAfter(rfcSecret, next, step, now.Add(Period)); !ok || got != current+1 {
+		t.Errorf("next code: got %d %v", got, ok)
+	}
+}